@@ -0,0 +1,82 @@
+package main
+
+import (
+	"ai-notetaking-be/internal/constant"
+	"ai-notetaking-be/internal/dto"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/repository"
+	"ai-notetaking-be/internal/service"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+func runImportCommand(importService service.IImportService, userRepository repository.IUserRepository, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	email := flags.String("email", "", "email of the user that will own the notes")
+	notebook := flags.String("notebook", "", "id of the notebook to import into, defaults to new root notebooks")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: import -email <email> [-notebook <id>] <archive.zip>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *email == "" || flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	user, err := userRepository.GetByEmail(ctx, *email)
+	if err != nil {
+		log.Fatalf("find user %s: %v", *email, err)
+	}
+	ctx = serverutils.ContextWithUserId(ctx, user.Id)
+
+	archivePath := flags.Arg(0)
+	file, err := os.Open(archivePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	req := dto.ImportRequest{
+		File:     file,
+		Size:     info.Size(),
+		FileName: filepath.Base(archivePath),
+	}
+	if *notebook != "" {
+		notebookId, err := uuid.Parse(*notebook)
+		if err != nil {
+			log.Fatalf("invalid notebook id %q", *notebook)
+		}
+		req.NotebookId = &notebookId
+	}
+
+	res, err := importService.Import(ctx, &req)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, result := range res.Files {
+		if result.Status != constant.ImportFileStatusImported {
+			fmt.Printf("%s %s: %s\n", result.Status, result.Path, result.Error)
+		}
+	}
+	fmt.Printf(
+		"%d notebook(s) and %d note(s) imported, %d file(s) skipped, %d failed\n",
+		res.NotebookCount,
+		res.NoteCount,
+		res.SkippedCount,
+		res.FailedCount,
+	)
+}
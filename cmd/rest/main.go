@@ -1,18 +1,24 @@
 package main
 
 import (
+	"ai-notetaking-be/internal/constant"
 	"ai-notetaking-be/internal/controller"
 	"ai-notetaking-be/internal/pkg/serverutils"
 	"ai-notetaking-be/internal/repository"
 	"ai-notetaking-be/internal/service"
+	"ai-notetaking-be/pkg/blobstore"
+	"ai-notetaking-be/pkg/chatbot"
+	"ai-notetaking-be/pkg/chunking"
 	"ai-notetaking-be/pkg/database"
+	"ai-notetaking-be/pkg/embedding"
+	"ai-notetaking-be/pkg/rerank"
 	"context"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
-	"github.com/ThreeDotsLabs/watermill"
-	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/joho/godotenv"
@@ -30,61 +36,355 @@ func main() {
 
 	db := database.ConnectDB(os.Getenv("DB_CONNECTION_STRING"))
 
+	embeddingDimension, _ := strconv.Atoi(os.Getenv("EMBEDDING_DIMENSION"))
+	schemaDimension := embeddingDimension
+	if schemaDimension <= 0 {
+		schemaDimension = constant.EmbeddingDefaultDimension
+	}
+	migrationVars := map[string]string{
+		"EMBEDDING_DIMENSION": strconv.Itoa(schemaDimension),
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(db, migrationVars, os.Args[2:])
+		return
+	}
+	if os.Getenv("AUTO_MIGRATE") == "true" {
+		autoMigrate(db, migrationVars)
+	}
+
 	exampleRepository := repository.NewExampleRepository(db)
 	notebookRepository := repository.NewNotebookRepository(db)
 	noteRepository := repository.NewNoteRepository(db)
-	noteEmbeddingRepository := repository.NewNoteEmbeddingRepository(db)
+	noteEmbeddingRepository := repository.NewNoteEmbeddingRepository(db, schemaDimension)
 	chatSessionRepository := repository.NewChatSessionRepository(db)
 	chatMessageRepository := repository.NewChatMessageRepository(db)
 	chatMessageRawRepository := repository.NewChatMessageRawRepository(db)
+	chatMessageReferenceRepository := repository.NewChatMessageReferenceRepository(db)
+	userRepository := repository.NewUserRepository(db)
+	userSessionRepository := repository.NewUserSessionRepository(db)
+	jobRepository := repository.NewJobRepository(db)
+	noteRevisionRepository := repository.NewNoteRevisionRepository(db)
+	tagRepository := repository.NewTagRepository(db)
+	noteLinkRepository := repository.NewNoteLinkRepository(db)
+	attachmentRepository := repository.NewAttachmentRepository(db)
+
+	columnDimension, err := noteEmbeddingRepository.Dimension(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	if columnDimension != schemaDimension {
+		panic(fmt.Errorf(
+			"note_embedding.embedding_value has %d dimensions but EMBEDDING_DIMENSION is %d, set EMBEDDING_DIMENSION=%d or migrate the column and re-embed the notes",
+			columnDimension, schemaDimension, columnDimension,
+		))
+	}
+
+	// A zero timeout leaves the default of each provider package.
+	providerTimeout, _ := time.ParseDuration(os.Getenv("PROVIDER_TIMEOUT"))
+
+	embeddingApiKey := os.Getenv("EMBEDDING_API_KEY")
+	if embeddingApiKey == "" {
+		embeddingApiKey = os.Getenv("GOOGLE_GEMINI_API_KEY")
+	}
+	embedder, err := embedding.NewEmbedder(embedding.Config{
+		Provider:  os.Getenv("EMBEDDING_PROVIDER"),
+		Model:     os.Getenv("EMBEDDING_MODEL"),
+		BaseUrl:   os.Getenv("EMBEDDING_BASE_URL"),
+		ApiKey:    embeddingApiKey,
+		Dimension: embeddingDimension,
+		Timeout:   providerTimeout,
+	})
+	if err != nil {
+		panic(err)
+	}
+	embedder = embedding.WithDimension(embedder, schemaDimension)
+
+	chatApiKey := os.Getenv("CHAT_API_KEY")
+	if chatApiKey == "" {
+		chatApiKey = os.Getenv("GOOGLE_GEMINI_API_KEY")
+	}
+	chatModel, err := chatbot.NewChatModel(chatbot.Config{
+		Provider: os.Getenv("CHAT_PROVIDER"),
+		Model:    os.Getenv("CHAT_MODEL"),
+		BaseUrl:  os.Getenv("CHAT_BASE_URL"),
+		ApiKey:   chatApiKey,
+		Timeout:  providerTimeout,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	reranker, err := rerank.NewReranker(rerank.Config{
+		Provider: os.Getenv("RERANK_PROVIDER"),
+		Model:    os.Getenv("RERANK_MODEL"),
+		BaseUrl:  os.Getenv("RERANK_BASE_URL"),
+		ApiKey:   os.Getenv("RERANK_API_KEY"),
+		Timeout:  providerTimeout,
+	}, chatModel)
+	if err != nil {
+		panic(err)
+	}
+
+	chunkSize, _ := strconv.Atoi(os.Getenv("CHUNK_SIZE"))
+	chunkOverlap, _ := strconv.Atoi(os.Getenv("CHUNK_OVERLAP"))
+	chunker, err := chunking.NewChunker(chunking.Config{
+		Strategy: os.Getenv("CHUNK_STRATEGY"),
+		Size:     chunkSize,
+		Overlap:  chunkOverlap,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	blobStore, err := blobstore.NewBlobStore(blobstore.Config{
+		Provider:     os.Getenv("BLOB_STORE_PROVIDER"),
+		LocalDir:     os.Getenv("BLOB_STORE_LOCAL_DIR"),
+		Endpoint:     os.Getenv("BLOB_STORE_S3_ENDPOINT"),
+		Region:       os.Getenv("BLOB_STORE_S3_REGION"),
+		Bucket:       os.Getenv("BLOB_STORE_S3_BUCKET"),
+		AccessKey:    os.Getenv("BLOB_STORE_S3_ACCESS_KEY"),
+		SecretKey:    os.Getenv("BLOB_STORE_S3_SECRET_KEY"),
+		UsePathStyle: os.Getenv("BLOB_STORE_S3_PATH_STYLE") == "true",
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	embedJobMaxAttempts, err := strconv.Atoi(os.Getenv("EMBED_JOB_MAX_ATTEMPTS"))
+	if err != nil {
+		embedJobMaxAttempts = 8
+	}
+	embedJobPollInterval, err := time.ParseDuration(os.Getenv("EMBED_JOB_POLL_INTERVAL"))
+	if err != nil {
+		embedJobPollInterval = 2 * time.Second
+	}
+	embedJobBackoffBase, err := time.ParseDuration(os.Getenv("EMBED_JOB_BACKOFF_BASE"))
+	if err != nil {
+		embedJobBackoffBase = 5 * time.Second
+	}
 
-	watermillLogger := watermill.NewStdLogger(false, false)
-	pubSub := gochannel.NewGoChannel(
-		gochannel.Config{},
-		watermillLogger,
-	)
 	publisherService := service.NewPublisherService(
 		os.Getenv("EMBED_NOTE_CONTENT_TOPIC_NAME"),
-		pubSub,
+		embedJobMaxAttempts,
+		jobRepository,
 	)
 	consumerService := service.NewConsumerService(
-		pubSub,
+		jobRepository,
 		os.Getenv("EMBED_NOTE_CONTENT_TOPIC_NAME"),
+		service.ConsumerConfig{
+			PollInterval: embedJobPollInterval,
+			BatchSize:    10,
+			Lease:        5 * time.Minute,
+			BackoffBase:  embedJobBackoffBase,
+			BackoffMax:   1 * time.Hour,
+		},
 		noteRepository,
 		noteEmbeddingRepository,
 		notebookRepository,
+		tagRepository,
+		embedder,
+		chunker,
 		db,
 	)
 
+	attachmentExtractTopicName := os.Getenv("ATTACHMENT_EXTRACT_TOPIC_NAME")
+	if attachmentExtractTopicName == "" {
+		attachmentExtractTopicName = "extract-attachment-text"
+	}
+	attachmentExtractPublisherService := service.NewPublisherService(
+		attachmentExtractTopicName,
+		embedJobMaxAttempts,
+		jobRepository,
+	)
+	attachmentExtractorService := service.NewAttachmentExtractorService(
+		jobRepository,
+		attachmentExtractTopicName,
+		service.ConsumerConfig{
+			PollInterval: embedJobPollInterval,
+			BatchSize:    5,
+			Lease:        10 * time.Minute,
+			BackoffBase:  embedJobBackoffBase,
+			BackoffMax:   1 * time.Hour,
+		},
+		attachmentRepository,
+		noteEmbeddingRepository,
+		blobStore,
+		embedder,
+		chunker,
+		db,
+	)
+
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		panic("JWT_SECRET is required")
+	}
+	accessTokenTtl, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL"))
+	if err != nil {
+		accessTokenTtl = 15 * time.Minute
+	}
+	refreshTokenTtl, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL"))
+	if err != nil {
+		refreshTokenTtl = 30 * 24 * time.Hour
+	}
+
+	trashRetention, err := time.ParseDuration(os.Getenv("TRASH_RETENTION"))
+	if err != nil {
+		trashRetention = 30 * 24 * time.Hour
+	}
+	trashPurgeInterval, err := time.ParseDuration(os.Getenv("TRASH_PURGE_INTERVAL"))
+	if err != nil {
+		trashPurgeInterval = 1 * time.Hour
+	}
+	purgerService := service.NewPurgerService(
+		notebookRepository,
+		noteRepository,
+		noteEmbeddingRepository,
+		noteRevisionRepository,
+		tagRepository,
+		noteLinkRepository,
+		attachmentRepository,
+		blobStore,
+		trashPurgeInterval,
+		trashRetention,
+		db,
+	)
+
+	notebookMaxDepth, err := strconv.Atoi(os.Getenv("NOTEBOOK_MAX_DEPTH"))
+	if err != nil {
+		notebookMaxDepth = 10
+	}
+
 	exampleService := service.NewExampleService(exampleRepository)
+	authService := service.NewAuthService(
+		userRepository,
+		userSessionRepository,
+		[]byte(jwtSecret),
+		accessTokenTtl,
+		refreshTokenTtl,
+	)
 	notebookService := service.NewNotebookService(
 		notebookRepository,
 		noteRepository,
 		db,
 		publisherService,
 		noteEmbeddingRepository,
+		tagRepository,
+		noteLinkRepository,
+		notebookMaxDepth,
+	)
+	noteService := service.NewNoteService(noteRepository, notebookRepository, publisherService, noteEmbeddingRepository, noteRevisionRepository, tagRepository, noteLinkRepository, attachmentRepository, embedder, db)
+	tagService := service.NewTagService(tagRepository, publisherService, db)
+	trashService := service.NewTrashService(
+		notebookRepository,
+		noteRepository,
+		noteEmbeddingRepository,
+		trashRetention,
+		notebookMaxDepth,
+		db,
+	)
+	attachmentService := service.NewAttachmentService(
+		attachmentRepository,
+		noteRepository,
+		noteEmbeddingRepository,
+		blobStore,
+		attachmentExtractPublisherService,
+		db,
+	)
+	exportService := service.NewExportService(notebookRepository, noteRepository)
+	importService := service.NewImportService(
+		notebookRepository,
+		noteRepository,
+		noteRevisionRepository,
+		noteLinkRepository,
+		publisherService,
+		notebookMaxDepth,
+		db,
 	)
-	noteService := service.NewNoteService(noteRepository, publisherService, noteEmbeddingRepository, db)
+	chatContextTokenBudget, err := strconv.Atoi(os.Getenv("CHAT_CONTEXT_TOKEN_BUDGET"))
+	if err != nil {
+		chatContextTokenBudget = 8000
+	}
+	retrievalMinScore, err := strconv.ParseFloat(os.Getenv("RETRIEVAL_MIN_SCORE"), 64)
+	if err != nil {
+		retrievalMinScore = 0.5
+	}
+	retrievalTopK, err := strconv.Atoi(os.Getenv("RETRIEVAL_TOP_K"))
+	if err != nil {
+		retrievalTopK = 5
+	}
+	retrievalCandidateLimit, err := strconv.Atoi(os.Getenv("RETRIEVAL_CANDIDATE_LIMIT"))
+	if err != nil {
+		retrievalCandidateLimit = 30
+	}
+	retrievalMMRLambda, err := strconv.ParseFloat(os.Getenv("RETRIEVAL_MMR_LAMBDA"), 64)
+	if err != nil {
+		retrievalMMRLambda = 0.7
+	}
 	chatbotService := service.NewChatbotService(
 		db,
 		chatSessionRepository,
 		chatMessageRepository,
 		chatMessageRawRepository,
+		chatMessageReferenceRepository,
+		noteRepository,
+		notebookRepository,
+		tagRepository,
 		noteEmbeddingRepository,
+		attachmentRepository,
+		embedder,
+		chatModel,
+		chatContextTokenBudget,
+		service.RetrievalConfig{
+			MinScore:       retrievalMinScore,
+			CandidateLimit: retrievalCandidateLimit,
+			TopK:           retrievalTopK,
+			MMRLambda:      retrievalMMRLambda,
+		},
+		reranker,
 	)
 
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		runImportCommand(importService, userRepository, os.Args[2:])
+		return
+	}
+
 	exampleController := controller.NewExampleController(exampleService)
-	notebookController := controller.NewNotebookController(notebookService)
+	authController := controller.NewAuthController(authService)
+	notebookController := controller.NewNotebookController(notebookService, exportService)
 	noteController := controller.NewNoteController(noteService)
 	chatbotController := controller.NewChatbotController(chatbotService)
+	trashController := controller.NewTrashController(trashService)
+	importController := controller.NewImportController(importService)
+	tagController := controller.NewTagController(tagService)
+	attachmentController := controller.NewAttachmentController(attachmentService)
 
 	api := app.Group("/api")
 	exampleController.RegisterRoutes(api)
+	authController.RegisterRoutes(api)
+
+	// Every route registered after this middleware requires an access token.
+	api.Use(serverutils.AuthMiddleware(authService.Authenticate))
+	authController.RegisterProtectedRoutes(api)
 	notebookController.RegisterRoutes(api)
 	noteController.RegisterRoutes(api)
 	chatbotController.RegisterRoutes(api)
+	trashController.RegisterRoutes(api)
+	importController.RegisterRoutes(api)
+	tagController.RegisterRoutes(api)
+	attachmentController.RegisterRoutes(api)
+
+	err = consumerService.Consume(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	err = attachmentExtractorService.Consume(context.Background())
+	if err != nil {
+		panic(err)
+	}
 
-	err := consumerService.Consume(context.Background())
+	err = purgerService.Start(context.Background())
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"ai-notetaking-be/migrations"
+	"ai-notetaking-be/pkg/database"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func runMigrateCommand(db *pgxpool.Pool, vars map[string]string, args []string) {
+	if len(args) == 0 {
+		log.Fatal("usage: migrate up|down [steps]|status")
+	}
+
+	ctx := context.Background()
+	migrator, err := database.NewMigrator(db, migrations.FS, vars)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, migration := range applied {
+			fmt.Printf("applied %d_%s\n", migration.Version, migration.Name)
+		}
+		fmt.Printf("%d migration(s) applied\n", len(applied))
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("invalid steps %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, migration := range reverted {
+			fmt.Printf("reverted %d_%s\n", migration.Version, migration.Name)
+		}
+		fmt.Printf("%d migration(s) reverted\n", len(reverted))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%06d_%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+	default:
+		log.Fatalf("unknown migrate command %q", args[0])
+	}
+}
+
+func autoMigrate(db *pgxpool.Pool, vars map[string]string) {
+	migrator, err := database.NewMigrator(db, migrations.FS, vars)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(applied) > 0 {
+		fmt.Printf("%d migration(s) applied\n", len(applied))
+	}
+}
@@ -0,0 +1,18 @@
+package constant
+
+const (
+	// AttachmentMaxSize is the largest file accepted as an attachment. The
+	// request body limit of the server has to leave room for it.
+	AttachmentMaxSize = 10 * 1024 * 1024
+
+	AttachmentDefaultContentType = "application/octet-stream"
+
+	AttachmentExtractionStatusPending     = "pending"
+	AttachmentExtractionStatusDone        = "done"
+	AttachmentExtractionStatusFailed      = "failed"
+	AttachmentExtractionStatusUnsupported = "unsupported"
+
+	// AttachmentMaxExtractedLength caps the characters of extracted text
+	// that are stored and embedded per attachment.
+	AttachmentMaxExtractedLength = 500000
+)
@@ -11,4 +11,17 @@ const (
 	DecideUseRAGMessageRawInitialUserPromptV1 = `You are a chatbot assistant that will answer your user question based on references provided. In this session, you will provide true or false data. True if you can answer directly without other information, false otherwise.`
 
 	DecideUseRAGMessageRawInitialModelPromptV1 = `Okay, I understand. I will answer \"True\" if I can definitively answer the user's question based solely on my existing knowledge, and \"False\" if I cannot. I will not attempt to make educated guesses or provide potentially inaccurate information. I will wait for your question.\n`
+
+	// The raw user message wraps the question after the reference blocks,
+	// the markers let older messages be sent without their references.
+	ChatMessageRawQuestionPrefix = "User next question: "
+	ChatMessageRawQuestionSuffix = "\n\nYour answer ?"
+
+	ChatSummaryMessageRawUserPromptV1 = "Here is a summary of our earlier conversation, keep it in mind for my next questions:\n\n"
+
+	ChatSummaryMessageRawModelPromptV1 = "Understood. I will keep our earlier conversation in mind."
+
+	// ChatContextMinRecentMessages is the number of latest raw messages that
+	// are never folded into the summary, even when they exceed the budget.
+	ChatContextMinRecentMessages = 4
 )
@@ -0,0 +1,13 @@
+package constant
+
+const (
+	ImportFileStatusImported = "imported"
+	ImportFileStatusSkipped  = "skipped"
+	ImportFileStatusFailed   = "failed"
+
+	// ImportMaxNoteSize is the largest uncompressed Markdown file accepted.
+	ImportMaxNoteSize = 5 * 1024 * 1024
+	// ImportBatchSize is how many notes are written in one transaction and
+	// have their embed messages enqueued at once.
+	ImportBatchSize = 100
+)
@@ -0,0 +1,8 @@
+package constant
+
+const (
+	JobStatusPending    = "pending"
+	JobStatusProcessing = "processing"
+	JobStatusDone       = "done"
+	JobStatusDead       = "dead"
+)
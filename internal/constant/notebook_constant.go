@@ -0,0 +1,12 @@
+package constant
+
+const (
+	// NoteExcerptLength is the number of characters of a note's content
+	// returned in note summaries.
+	NoteExcerptLength = 160
+	// BacklinkExcerptContext is the number of characters kept before a link
+	// in backlink excerpts.
+	BacklinkExcerptContext = 60
+
+	NotebookNotesDefaultLimit = 20
+)
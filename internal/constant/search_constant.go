@@ -0,0 +1,19 @@
+package constant
+
+const (
+	SearchModeKeyword  = "keyword"
+	SearchModeSemantic = "semantic"
+	SearchModeHybrid   = "hybrid"
+
+	SearchDefaultLimit = 5
+	SearchMaxLimit     = 50
+
+	// EmbeddingDefaultDimension is the size of the note_embedding vectors
+	// when EMBEDDING_DIMENSION is not set, the output size of the default
+	// Gemini model.
+	EmbeddingDefaultDimension = 3072
+
+	// ReciprocalRankFusionK dampens the weight of top ranks when fusing
+	// rankings, 60 is the value used in the original RRF paper.
+	ReciprocalRankFusionK = 60
+)
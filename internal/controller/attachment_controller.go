@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"ai-notetaking-be/internal/dto"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/service"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type IAttachmentController interface {
+	RegisterRoutes(r fiber.Router)
+	Upload(ctx *fiber.Ctx) error
+	GetByNoteId(ctx *fiber.Ctx) error
+	Show(ctx *fiber.Ctx) error
+	Download(ctx *fiber.Ctx) error
+	Extract(ctx *fiber.Ctx) error
+	Delete(ctx *fiber.Ctx) error
+}
+
+type attachmentController struct {
+	attachmentService service.IAttachmentService
+}
+
+func NewAttachmentController(attachmentService service.IAttachmentService) IAttachmentController {
+	return &attachmentController{
+		attachmentService: attachmentService,
+	}
+}
+
+func (c *attachmentController) RegisterRoutes(r fiber.Router) {
+	h := r.Group("/attachment/v1")
+	h.Post("note/:noteId", c.Upload)
+	h.Get("note/:noteId", c.GetByNoteId)
+	h.Get(":id", c.Show)
+	h.Get(":id/download", c.Download)
+	h.Post(":id/extract", c.Extract)
+	h.Delete(":id", c.Delete)
+}
+
+func (c *attachmentController) Upload(ctx *fiber.Ctx) error {
+	noteIdParam := ctx.Params("noteId")
+	noteId, _ := uuid.Parse(noteIdParam)
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "file is required")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	req := dto.UploadAttachmentRequest{
+		NoteId:      noteId,
+		File:        file,
+		FileName:    fileHeader.Filename,
+		ContentType: fileHeader.Header.Get("Content-Type"),
+		Size:        fileHeader.Size,
+	}
+
+	res, err := c.attachmentService.Upload(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success upload attachment", res))
+}
+
+func (c *attachmentController) GetByNoteId(ctx *fiber.Ctx) error {
+	noteIdParam := ctx.Params("noteId")
+	noteId, _ := uuid.Parse(noteIdParam)
+
+	res, err := c.attachmentService.GetByNoteId(ctx.Context(), noteId)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success get note attachments", res))
+}
+
+func (c *attachmentController) Show(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	res, err := c.attachmentService.Show(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success show attachment", res))
+}
+
+func (c *attachmentController) Extract(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	res, err := c.attachmentService.Extract(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success queue attachment extraction", res))
+}
+
+// Download streams the file. A single "Range: bytes=..." range is answered
+// with 206 Partial Content, anything else gets the whole file.
+func (c *attachmentController) Download(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	attachment, err := c.attachmentService.Show(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	start, end, partial, satisfiable := parseByteRange(ctx.Get(fiber.HeaderRange), attachment.Size)
+	if !satisfiable {
+		ctx.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", attachment.Size))
+		return fiber.NewError(fiber.StatusRequestedRangeNotSatisfiable, "range not satisfiable")
+	}
+
+	res, err := c.attachmentService.Download(ctx.Context(), &dto.DownloadAttachmentRequest{
+		Id:     id,
+		Offset: start,
+		Length: end - start + 1,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx.Set(fiber.HeaderContentType, res.Attachment.ContentType)
+	ctx.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": res.Attachment.FileName}))
+	ctx.Set(fiber.HeaderAcceptRanges, "bytes")
+	if partial {
+		ctx.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, res.Attachment.Size))
+		ctx.Status(fiber.StatusPartialContent)
+	}
+	ctx.Response().SetBodyStream(res.Body, int(end-start+1))
+
+	return nil
+}
+
+func (c *attachmentController) Delete(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	err := c.attachmentService.Delete(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse[any]("Success delete attachment", nil))
+}
+
+// parseByteRange resolves a Range header against size into an inclusive
+// start and end. Headers it does not understand, including multiple ranges,
+// select the whole file.
+func parseByteRange(header string, size int64) (start int64, end int64, partial bool, satisfiable bool) {
+	start, end = 0, size-1
+
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return start, end, false, true
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return start, end, false, true
+	}
+
+	if first == "" {
+		// A suffix range, the last n bytes.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return start, end, false, true
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, false, false
+		}
+		if n < size {
+			start = size - n
+		}
+		return start, end, true, true
+	}
+
+	rangeStart, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || rangeStart < 0 {
+		return start, end, false, true
+	}
+	if rangeStart >= size {
+		return 0, 0, false, false
+	}
+	start = rangeStart
+
+	if last != "" {
+		rangeEnd, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || rangeEnd < rangeStart {
+			return 0, size - 1, false, true
+		}
+		if rangeEnd < end {
+			end = rangeEnd
+		}
+	}
+
+	return start, end, true, true
+}
@@ -0,0 +1,48 @@
+package controller
+
+import "testing"
+
+func TestParseByteRange(t *testing.T) {
+	tests := []struct {
+		name            string
+		header          string
+		size            int64
+		wantStart       int64
+		wantEnd         int64
+		wantPartial     bool
+		wantSatisfiable bool
+	}{
+		{name: "no header", header: "", size: 100, wantStart: 0, wantEnd: 99, wantSatisfiable: true},
+		{name: "closed range", header: "bytes=10-19", size: 100, wantStart: 10, wantEnd: 19, wantPartial: true, wantSatisfiable: true},
+		{name: "open range", header: "bytes=90-", size: 100, wantStart: 90, wantEnd: 99, wantPartial: true, wantSatisfiable: true},
+		{name: "single byte", header: "bytes=0-0", size: 100, wantStart: 0, wantEnd: 0, wantPartial: true, wantSatisfiable: true},
+		{name: "end past size", header: "bytes=50-500", size: 100, wantStart: 50, wantEnd: 99, wantPartial: true, wantSatisfiable: true},
+		{name: "suffix range", header: "bytes=-10", size: 100, wantStart: 90, wantEnd: 99, wantPartial: true, wantSatisfiable: true},
+		{name: "suffix longer than size", header: "bytes=-500", size: 100, wantStart: 0, wantEnd: 99, wantPartial: true, wantSatisfiable: true},
+		{name: "empty suffix", header: "bytes=-0", size: 100, wantSatisfiable: false},
+		{name: "start at size", header: "bytes=100-", size: 100, wantSatisfiable: false},
+		{name: "start past size", header: "bytes=150-200", size: 100, wantSatisfiable: false},
+		{name: "end before start", header: "bytes=20-10", size: 100, wantStart: 0, wantEnd: 99, wantSatisfiable: true},
+		{name: "multiple ranges", header: "bytes=0-9,20-29", size: 100, wantStart: 0, wantEnd: 99, wantSatisfiable: true},
+		{name: "other unit", header: "items=0-9", size: 100, wantStart: 0, wantEnd: 99, wantSatisfiable: true},
+		{name: "not a number", header: "bytes=a-b", size: 100, wantStart: 0, wantEnd: 99, wantSatisfiable: true},
+		{name: "zero byte file", header: "", size: 0, wantStart: 0, wantEnd: -1, wantSatisfiable: true},
+		{name: "range of zero byte file", header: "bytes=0-", size: 0, wantSatisfiable: false},
+		{name: "suffix of zero byte file", header: "bytes=-10", size: 0, wantSatisfiable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, partial, satisfiable := parseByteRange(tt.header, tt.size)
+			if satisfiable != tt.wantSatisfiable {
+				t.Fatalf("got satisfiable %v, want %v", satisfiable, tt.wantSatisfiable)
+			}
+			if !satisfiable {
+				return
+			}
+			if start != tt.wantStart || end != tt.wantEnd || partial != tt.wantPartial {
+				t.Fatalf("got %d-%d partial %v, want %d-%d partial %v", start, end, partial, tt.wantStart, tt.wantEnd, tt.wantPartial)
+			}
+		})
+	}
+}
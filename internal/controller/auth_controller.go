@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"ai-notetaking-be/internal/dto"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type IAuthController interface {
+	RegisterRoutes(r fiber.Router)
+	RegisterProtectedRoutes(r fiber.Router)
+	Register(ctx *fiber.Ctx) error
+	Login(ctx *fiber.Ctx) error
+	Refresh(ctx *fiber.Ctx) error
+	Logout(ctx *fiber.Ctx) error
+	Me(ctx *fiber.Ctx) error
+}
+
+type authController struct {
+	authService service.IAuthService
+}
+
+func NewAuthController(authService service.IAuthService) IAuthController {
+	return &authController{
+		authService: authService,
+	}
+}
+
+func (c *authController) RegisterRoutes(r fiber.Router) {
+	h := r.Group("/auth/v1")
+	h.Post("register", c.Register)
+	h.Post("login", c.Login)
+	h.Post("refresh", c.Refresh)
+	h.Post("logout", c.Logout)
+}
+
+func (c *authController) RegisterProtectedRoutes(r fiber.Router) {
+	h := r.Group("/auth/v1")
+	h.Get("me", c.Me)
+}
+
+func (c *authController) Register(ctx *fiber.Ctx) error {
+	var req dto.RegisterRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return err
+	}
+
+	err := serverutils.ValidateRequest(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.authService.Register(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success register", res))
+}
+
+func (c *authController) Login(ctx *fiber.Ctx) error {
+	var req dto.LoginRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return err
+	}
+
+	err := serverutils.ValidateRequest(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.authService.Login(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success login", res))
+}
+
+func (c *authController) Refresh(ctx *fiber.Ctx) error {
+	var req dto.RefreshTokenRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return err
+	}
+
+	err := serverutils.ValidateRequest(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.authService.Refresh(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success refresh token", res))
+}
+
+func (c *authController) Logout(ctx *fiber.Ctx) error {
+	var req dto.LogoutRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return err
+	}
+
+	err := serverutils.ValidateRequest(req)
+	if err != nil {
+		return err
+	}
+
+	err = c.authService.Logout(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse[any]("Success logout", nil))
+}
+
+func (c *authController) Me(ctx *fiber.Ctx) error {
+	res, err := c.authService.Me(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success get current user", res))
+}
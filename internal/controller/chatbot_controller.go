@@ -4,6 +4,10 @@ import (
 	"ai-notetaking-be/internal/dto"
 	"ai-notetaking-be/internal/pkg/serverutils"
 	"ai-notetaking-be/internal/service"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -15,6 +19,12 @@ type IChatbotController interface {
 	GetAllSessions(ctx *fiber.Ctx) error
 	GetChatHistory(ctx *fiber.Ctx) error
 	SendChat(ctx *fiber.Ctx) error
+	SendChatStream(ctx *fiber.Ctx) error
+	RegenerateChat(ctx *fiber.Ctx) error
+	RegenerateChatStream(ctx *fiber.Ctx) error
+	EditChat(ctx *fiber.Ctx) error
+	EditChatStream(ctx *fiber.Ctx) error
+	SelectChatBranch(ctx *fiber.Ctx) error
 	DeleteSession(ctx *fiber.Ctx) error
 }
 
@@ -34,11 +44,27 @@ func (c *chatbotController) RegisterRoutes(r fiber.Router) {
 	h.Get("chat-history", c.GetChatHistory)
 	h.Post("create-session", c.CreateSession)
 	h.Post("send-chat", c.SendChat)
+	h.Post("send-chat-stream", c.SendChatStream)
+	h.Post("regenerate-chat", c.RegenerateChat)
+	h.Post("regenerate-chat-stream", c.RegenerateChatStream)
+	h.Post("edit-chat", c.EditChat)
+	h.Post("edit-chat-stream", c.EditChatStream)
+	h.Post("select-branch", c.SelectChatBranch)
 	h.Delete("delete-session", c.DeleteSession)
 }
 
+// CreateSession accepts an optional body holding the retrieval scope of the
+// session.
 func (c *chatbotController) CreateSession(ctx *fiber.Ctx) error {
-	res, err := c.chatbotService.CreateSession(ctx.Context())
+	var request dto.CreateSessionRequest
+	if len(ctx.Body()) > 0 {
+		err := ctx.BodyParser(&request)
+		if err != nil {
+			return err
+		}
+	}
+
+	res, err := c.chatbotService.CreateSession(ctx.Context(), &request)
 	if err != nil {
 		return err
 	}
@@ -87,6 +113,180 @@ func (c *chatbotController) SendChat(ctx *fiber.Ctx) error {
 	return ctx.JSON(serverutils.SuccessResponse("Success send chat", res))
 }
 
+// SendChatStream replies with Server-Sent Events. Every "delta" event carries
+// a piece of the model reply, and the stream ends with either a "done" event
+// holding the persisted messages or an "error" event. Invalid requests and
+// unknown sessions are answered with a plain JSON error before the stream
+// starts.
+func (c *chatbotController) SendChatStream(ctx *fiber.Ctx) error {
+	var request dto.SendChatRequest
+
+	err := ctx.BodyParser(&request)
+	if err != nil {
+		return err
+	}
+
+	if err = serverutils.ValidateRequest(request); err != nil {
+		return err
+	}
+
+	stream, err := c.chatbotService.SendChatStream(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return streamChat(ctx, "Success send chat", stream)
+}
+
+func (c *chatbotController) RegenerateChat(ctx *fiber.Ctx) error {
+	var request dto.RegenerateChatRequest
+
+	err := ctx.BodyParser(&request)
+	if err != nil {
+		return err
+	}
+
+	if err = serverutils.ValidateRequest(request); err != nil {
+		return err
+	}
+
+	res, err := c.chatbotService.RegenerateChat(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success regenerate chat", res))
+}
+
+// RegenerateChatStream streams the new reply like SendChatStream.
+func (c *chatbotController) RegenerateChatStream(ctx *fiber.Ctx) error {
+	var request dto.RegenerateChatRequest
+
+	err := ctx.BodyParser(&request)
+	if err != nil {
+		return err
+	}
+
+	if err = serverutils.ValidateRequest(request); err != nil {
+		return err
+	}
+
+	stream, err := c.chatbotService.RegenerateChatStream(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return streamChat(ctx, "Success regenerate chat", stream)
+}
+
+func (c *chatbotController) EditChat(ctx *fiber.Ctx) error {
+	var request dto.EditChatRequest
+
+	err := ctx.BodyParser(&request)
+	if err != nil {
+		return err
+	}
+
+	if err = serverutils.ValidateRequest(request); err != nil {
+		return err
+	}
+
+	res, err := c.chatbotService.EditChat(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success edit chat", res))
+}
+
+// EditChatStream streams the new reply like SendChatStream.
+func (c *chatbotController) EditChatStream(ctx *fiber.Ctx) error {
+	var request dto.EditChatRequest
+
+	err := ctx.BodyParser(&request)
+	if err != nil {
+		return err
+	}
+
+	if err = serverutils.ValidateRequest(request); err != nil {
+		return err
+	}
+
+	stream, err := c.chatbotService.EditChatStream(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return streamChat(ctx, "Success edit chat", stream)
+}
+
+func (c *chatbotController) SelectChatBranch(ctx *fiber.Ctx) error {
+	var request dto.SelectChatBranchRequest
+
+	err := ctx.BodyParser(&request)
+	if err != nil {
+		return err
+	}
+
+	if err = serverutils.ValidateRequest(request); err != nil {
+		return err
+	}
+
+	res, err := c.chatbotService.SelectChatBranch(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success select branch", res))
+}
+
+// streamChat runs stream after the response is handed to the body stream
+// writer, so it gets a context of its own carrying the user. Failures from
+// then on are sent as an "error" event with the status the error maps to.
+func streamChat(ctx *fiber.Ctx, successMessage string, stream service.ChatStream) error {
+	streamCtx := serverutils.ContextWithUserId(
+		context.Background(),
+		serverutils.UserIdFromContext(ctx.Context()),
+	)
+
+	ctx.Set("Content-Type", "text/event-stream")
+	ctx.Set("Cache-Control", "no-cache")
+	ctx.Set("Connection", "keep-alive")
+	ctx.Set("X-Accel-Buffering", "no")
+
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		res, err := stream(
+			streamCtx,
+			func(delta string) error {
+				return writeServerSentEvent(w, "delta", dto.SendChatStreamDelta{Text: delta})
+			},
+		)
+		if err != nil {
+			_, errRes := serverutils.ErrorToResponse(err)
+			_ = writeServerSentEvent(w, "error", errRes)
+			return
+		}
+
+		_ = writeServerSentEvent(w, "done", serverutils.SuccessResponse(successMessage, res))
+	})
+
+	return nil
+}
+
+func writeServerSentEvent(w *bufio.Writer, event string, data any) error {
+	dataJson, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, dataJson)
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
 func (c *chatbotController) DeleteSession(ctx *fiber.Ctx) error {
 	var request dto.DeleteSessionRequest
 
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"ai-notetaking-be/internal/dto"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type IImportController interface {
+	RegisterRoutes(r fiber.Router)
+	Import(ctx *fiber.Ctx) error
+}
+
+type importController struct {
+	importService service.IImportService
+}
+
+func NewImportController(importService service.IImportService) IImportController {
+	return &importController{
+		importService: importService,
+	}
+}
+
+func (c *importController) RegisterRoutes(r fiber.Router) {
+	h := r.Group("/import/v1")
+	h.Post("", c.Import)
+}
+
+func (c *importController) Import(ctx *fiber.Ctx) error {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "file is required")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	req := dto.ImportRequest{
+		File:     file,
+		Size:     fileHeader.Size,
+		FileName: fileHeader.Filename,
+	}
+	if notebookIdStr := ctx.FormValue("notebook_id"); notebookIdStr != "" {
+		notebookId, err := uuid.Parse(notebookIdStr)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid notebook_id")
+		}
+		req.NotebookId = &notebookId
+	}
+
+	res, err := c.importService.Import(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success import notes", res))
+}
@@ -17,6 +17,12 @@ type INoteController interface {
 	Delete(ctx *fiber.Ctx) error
 	MoveNote(ctx *fiber.Ctx) error
 	SemanticSearch(ctx *fiber.Ctx) error
+	GetRevisions(ctx *fiber.Ctx) error
+	ShowRevision(ctx *fiber.Ctx) error
+	DiffRevisions(ctx *fiber.Ctx) error
+	RestoreRevision(ctx *fiber.Ctx) error
+	SetTags(ctx *fiber.Ctx) error
+	GetBacklinks(ctx *fiber.Ctx) error
 }
 
 type noteController struct {
@@ -36,7 +42,13 @@ func (c *noteController) RegisterRoutes(r fiber.Router) {
 	h.Get(":id", c.Show)
 	h.Put(":id", c.Update)
 	h.Put(":id/move", c.MoveNote)
+	h.Put(":id/tags", c.SetTags)
+	h.Get(":id/backlinks", c.GetBacklinks)
 	h.Delete(":id", c.Delete)
+	h.Get(":id/revisions", c.GetRevisions)
+	h.Get(":id/revisions/diff", c.DiffRevisions)
+	h.Get(":id/revisions/:revisionId", c.ShowRevision)
+	h.Post(":id/revisions/:revisionId/restore", c.RestoreRevision)
 }
 
 func (c *noteController) Create(ctx *fiber.Ctx) error {
@@ -127,13 +139,134 @@ func (c *noteController) MoveNote(ctx *fiber.Ctx) error {
 	return ctx.JSON(serverutils.SuccessResponse("Success move note", res))
 }
 
+func (c *noteController) SetTags(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	var req dto.SetNoteTagsRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return err
+	}
+	req.Id = id
+
+	err := serverutils.ValidateRequest(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.noteService.SetTags(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success set note tags", res))
+}
+
+func (c *noteController) GetBacklinks(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	res, err := c.noteService.GetBacklinks(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success get note backlinks", res))
+}
+
 func (c *noteController) SemanticSearch(ctx *fiber.Ctx) error {
-	q := ctx.Query("q", "")
+	req := dto.SemanticSearchRequest{
+		Query:  ctx.Query("q", ""),
+		Mode:   ctx.Query("mode", ""),
+		Limit:  ctx.QueryInt("limit", 0),
+		Offset: ctx.QueryInt("offset", 0),
+		Tags:   splitTags(ctx.Query("tags")),
+	}
+	if notebookIdStr := ctx.Query("notebook_id"); notebookIdStr != "" {
+		notebookId, err := uuid.Parse(notebookIdStr)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid notebook_id")
+		}
+		req.NotebookId = &notebookId
+	}
 
-	res, err := c.noteService.SemanticSearch(ctx.Context(), q)
+	err := serverutils.ValidateRequest(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.noteService.SemanticSearch(ctx.Context(), &req)
 	if err != nil {
 		return err
 	}
 
 	return ctx.JSON(serverutils.SuccessResponse("Success semantic search notes", res))
 }
+
+func (c *noteController) GetRevisions(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	res, err := c.noteService.GetRevisions(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success get note revisions", res))
+}
+
+func (c *noteController) ShowRevision(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+	revisionIdParam := ctx.Params("revisionId")
+	revisionId, _ := uuid.Parse(revisionIdParam)
+
+	res, err := c.noteService.ShowRevision(ctx.Context(), id, revisionId)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success show note revision", res))
+}
+
+func (c *noteController) DiffRevisions(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+	fromRevisionId, _ := uuid.Parse(ctx.Query("from"))
+	toRevisionId, _ := uuid.Parse(ctx.Query("to"))
+
+	req := dto.DiffNoteRevisionRequest{
+		NoteId:         id,
+		FromRevisionId: fromRevisionId,
+		ToRevisionId:   toRevisionId,
+	}
+
+	err := serverutils.ValidateRequest(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.noteService.DiffRevisions(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success diff note revisions", res))
+}
+
+func (c *noteController) RestoreRevision(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+	revisionIdParam := ctx.Params("revisionId")
+	revisionId, _ := uuid.Parse(revisionIdParam)
+
+	res, err := c.noteService.RestoreRevision(ctx.Context(), &dto.RestoreNoteRevisionRequest{
+		NoteId:     id,
+		RevisionId: revisionId,
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success restore note revision", res))
+}
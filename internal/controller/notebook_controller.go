@@ -4,6 +4,10 @@ import (
 	"ai-notetaking-be/internal/dto"
 	"ai-notetaking-be/internal/pkg/serverutils"
 	"ai-notetaking-be/internal/service"
+	"bufio"
+	"context"
+	"log"
+	"mime"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -15,28 +19,45 @@ type INotebookController interface {
 	Show(ctx *fiber.Ctx) error
 	Update(ctx *fiber.Ctx) error
 	Delete(ctx *fiber.Ctx) error
+	GetTree(ctx *fiber.Ctx) error
+	GetAllTree(ctx *fiber.Ctx) error
+	GetNotes(ctx *fiber.Ctx) error
+	GetGraph(ctx *fiber.Ctx) error
+	Export(ctx *fiber.Ctx) error
+	ExportWorkspace(ctx *fiber.Ctx) error
 }
 
 type notebookController struct {
-	service service.INotebookService
+	service       service.INotebookService
+	exportService service.IExportService
 }
 
-func NewNotebookController(service service.INotebookService) INotebookController {
-	return &notebookController{service: service}
+func NewNotebookController(service service.INotebookService, exportService service.IExportService) INotebookController {
+	return &notebookController{service: service, exportService: exportService}
 }
 
 func (c *notebookController) RegisterRoutes(r fiber.Router) {
 	h := r.Group("/notebook/v1")
 	h.Get("", c.GetAll)
+	h.Get("tree", c.GetAllTree)
+	h.Get("export", c.ExportWorkspace)
 	h.Post("", c.Create)
 	h.Get(":id", c.Show)
 	h.Put(":id", c.Update)
 	h.Delete(":id", c.Delete)
 	h.Put(":id/move", c.MoveNotebook)
+	h.Get(":id/tree", c.GetTree)
+	h.Get(":id/notes", c.GetNotes)
+	h.Get(":id/graph", c.GetGraph)
+	h.Get(":id/export", c.Export)
 }
 
 func (c *notebookController) GetAll(ctx *fiber.Ctx) error {
-	res, err := c.service.GetAll(ctx.Context())
+	req := dto.GetAllNotebookRequest{
+		Tags: splitTags(ctx.Query("tags")),
+	}
+
+	res, err := c.service.GetAll(ctx.Context(), &req)
 	if err != nil {
 		return err
 	}
@@ -97,7 +118,10 @@ func (c *notebookController) Delete(ctx *fiber.Ctx) error {
 	idParam := ctx.Params("id")
 	id, _ := uuid.Parse(idParam)
 
-	err := c.service.Delete(ctx.Context(), id)
+	err := c.service.Delete(ctx.Context(), &dto.DeleteNotebookRequest{
+		Id:      id,
+		Cascade: ctx.QueryBool("cascade", false),
+	})
 	if err != nil {
 		return err
 	}
@@ -122,3 +146,108 @@ func (c *notebookController) MoveNotebook(ctx *fiber.Ctx) error {
 
 	return ctx.JSON(serverutils.SuccessResponse("Success move notebook", res))
 }
+
+func (c *notebookController) GetTree(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	res, err := c.service.GetTree(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success get notebook tree", res))
+}
+
+func (c *notebookController) GetGraph(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	res, err := c.service.GetGraph(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success get notebook graph", res))
+}
+
+func (c *notebookController) GetAllTree(ctx *fiber.Ctx) error {
+	req := dto.GetNotebookTreeRequest{
+		NotesLimit: ctx.QueryInt("notes_limit", 0),
+	}
+
+	err := serverutils.ValidateRequest(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.service.GetAllTree(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success get notebook tree", res))
+}
+
+func (c *notebookController) GetNotes(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	req := dto.GetNotebookNotesRequest{
+		Id:     id,
+		Limit:  ctx.QueryInt("limit", 0),
+		Offset: ctx.QueryInt("offset", 0),
+	}
+
+	err := serverutils.ValidateRequest(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.service.GetNotes(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success get notebook notes", res))
+}
+
+func (c *notebookController) Export(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	export, err := c.exportService.ExportNotebook(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return streamExport(ctx, export)
+}
+
+func (c *notebookController) ExportWorkspace(ctx *fiber.Ctx) error {
+	export, err := c.exportService.ExportWorkspace(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	return streamExport(ctx, export)
+}
+
+func streamExport(ctx *fiber.Ctx, export *service.Export) error {
+	streamCtx := serverutils.ContextWithUserId(
+		context.Background(),
+		serverutils.UserIdFromContext(ctx.Context()),
+	)
+
+	ctx.Set(fiber.HeaderContentType, "application/zip")
+	ctx.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": export.FileName}))
+
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		err := export.WriteTo(streamCtx, w)
+		if err != nil {
+			log.Printf("[ERROR] %v", err)
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"ai-notetaking-be/internal/dto"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/service"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ITagController interface {
+	RegisterRoutes(r fiber.Router)
+	GetAll(ctx *fiber.Ctx) error
+	Create(ctx *fiber.Ctx) error
+	Update(ctx *fiber.Ctx) error
+	Delete(ctx *fiber.Ctx) error
+	Merge(ctx *fiber.Ctx) error
+}
+
+type tagController struct {
+	tagService service.ITagService
+}
+
+func NewTagController(tagService service.ITagService) ITagController {
+	return &tagController{
+		tagService: tagService,
+	}
+}
+
+func (c *tagController) RegisterRoutes(r fiber.Router) {
+	h := r.Group("/tag/v1")
+	h.Get("", c.GetAll)
+	h.Post("", c.Create)
+	h.Put(":id", c.Update)
+	h.Delete(":id", c.Delete)
+	h.Post(":id/merge", c.Merge)
+}
+
+func (c *tagController) GetAll(ctx *fiber.Ctx) error {
+	res, err := c.tagService.GetAll(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success get all tag", res))
+}
+
+func (c *tagController) Create(ctx *fiber.Ctx) error {
+	var req dto.CreateTagRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return err
+	}
+
+	err := serverutils.ValidateRequest(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.tagService.Create(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success create tag", res))
+}
+
+func (c *tagController) Update(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	var req dto.UpdateTagRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return err
+	}
+	req.Id = id
+
+	err := serverutils.ValidateRequest(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.tagService.Update(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success update tag", res))
+}
+
+func (c *tagController) Delete(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	err := c.tagService.Delete(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse[any]("Success delete tag", nil))
+}
+
+func (c *tagController) Merge(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	var req dto.MergeTagRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return err
+	}
+	req.Id = id
+
+	err := serverutils.ValidateRequest(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.tagService.Merge(ctx.Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success merge tag", res))
+}
+
+// splitTags reads a comma separated tags query parameter.
+func splitTags(value string) []string {
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
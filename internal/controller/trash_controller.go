@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ITrashController interface {
+	RegisterRoutes(r fiber.Router)
+	GetAll(ctx *fiber.Ctx) error
+	RestoreNotebook(ctx *fiber.Ctx) error
+	RestoreNote(ctx *fiber.Ctx) error
+}
+
+type trashController struct {
+	trashService service.ITrashService
+}
+
+func NewTrashController(trashService service.ITrashService) ITrashController {
+	return &trashController{
+		trashService: trashService,
+	}
+}
+
+func (c *trashController) RegisterRoutes(r fiber.Router) {
+	h := r.Group("/trash/v1")
+	h.Get("", c.GetAll)
+	h.Post("notebook/:id/restore", c.RestoreNotebook)
+	h.Post("note/:id/restore", c.RestoreNote)
+}
+
+func (c *trashController) GetAll(ctx *fiber.Ctx) error {
+	res, err := c.trashService.GetAll(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success get trash", res))
+}
+
+func (c *trashController) RestoreNotebook(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	res, err := c.trashService.RestoreNotebook(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success restore notebook", res))
+}
+
+func (c *trashController) RestoreNote(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, _ := uuid.Parse(idParam)
+
+	res, err := c.trashService.RestoreNote(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(serverutils.SuccessResponse("Success restore note", res))
+}
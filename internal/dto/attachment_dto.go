@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UploadAttachmentRequest struct {
+	NoteId      uuid.UUID
+	File        io.Reader
+	FileName    string
+	ContentType string
+	Size        int64
+}
+
+type AttachmentResponse struct {
+	Id               uuid.UUID `json:"id"`
+	NoteId           uuid.UUID `json:"note_id"`
+	FileName         string    `json:"file_name"`
+	ContentType      string    `json:"content_type"`
+	Size             int64     `json:"size"`
+	ExtractionStatus string    `json:"extraction_status"`
+	ExtractionError  *string   `json:"extraction_error"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// DownloadAttachmentRequest reads Length bytes from Offset, a negative
+// Length reads to the end of the file.
+type DownloadAttachmentRequest struct {
+	Id     uuid.UUID
+	Offset int64
+	Length int64
+}
+
+type DownloadAttachmentResponse struct {
+	Attachment *AttachmentResponse
+	Body       io.ReadCloser
+}
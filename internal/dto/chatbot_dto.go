@@ -6,34 +6,74 @@ import (
 	"github.com/google/uuid"
 )
 
+// ChatScopeRequest limits chat references to a notebook and its
+// descendants, to notes carrying all of Tags and to the notes in NoteIds.
+type ChatScopeRequest struct {
+	NotebookId *uuid.UUID  `json:"notebook_id"`
+	Tags       []string    `json:"tags"`
+	NoteIds    []uuid.UUID `json:"note_ids"`
+}
+
+type ChatScopeResponse struct {
+	NotebookId *uuid.UUID  `json:"notebook_id"`
+	TagIds     []uuid.UUID `json:"tag_ids"`
+	NoteIds    []uuid.UUID `json:"note_ids"`
+}
+
+type CreateSessionRequest struct {
+	Scope *ChatScopeRequest `json:"scope"`
+}
+
 type CreateSessionResponse struct {
-	Id uuid.UUID `json:"id"`
+	Id    uuid.UUID          `json:"id"`
+	Scope *ChatScopeResponse `json:"scope"`
 }
 
 type GetAllSessionsResponse struct {
-	Id        uuid.UUID  `json:"id"`
-	Title     string     `json:"title"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
+	Id        uuid.UUID          `json:"id"`
+	Title     string             `json:"title"`
+	Scope     *ChatScopeResponse `json:"scope"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt *time.Time         `json:"updated_at"`
+}
+
+type ChatMessageReferenceResponse struct {
+	ReferenceNumber    int        `json:"reference_number"`
+	NoteId             uuid.UUID  `json:"note_id"`
+	NoteTitle          string     `json:"note_title"`
+	ChunkIndex         int        `json:"chunk_index"`
+	AttachmentId       *uuid.UUID `json:"attachment_id"`
+	AttachmentFileName *string    `json:"attachment_file_name"`
+	Snippet            string     `json:"snippet"`
+	Score              float64    `json:"score"`
 }
 
 type GetChatHistoryResponse struct {
-	Id        uuid.UUID `json:"id"`
-	Role      string    `json:"role"`
-	Chat      string    `json:"chat"`
-	CreatedAt time.Time `json:"created_at"`
+	Id       uuid.UUID  `json:"id"`
+	ParentId *uuid.UUID `json:"parent_id"`
+	// SiblingIds are the alternative branches at this message, itself
+	// included, in creation order.
+	SiblingIds []uuid.UUID                     `json:"sibling_ids"`
+	Role       string                          `json:"role"`
+	Chat       string                          `json:"chat"`
+	CreatedAt  time.Time                       `json:"created_at"`
+	References []*ChatMessageReferenceResponse `json:"references"`
 }
 
 type SendChatRequest struct {
 	ChatSessionId uuid.UUID `json:"chat_session_id" validate:"required"`
 	Chat          string    `json:"chat" validate:"required"`
+	// Scope replaces the session's scope for this message only.
+	Scope *ChatScopeRequest `json:"scope"`
 }
 
 type SendChatResponseChat struct {
-	Id        uuid.UUID `json:"id"`
-	Chat      string    `json:"chat"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
+	Id         uuid.UUID                       `json:"id"`
+	ParentId   *uuid.UUID                      `json:"parent_id"`
+	Chat       string                          `json:"chat"`
+	Role       string                          `json:"role"`
+	CreatedAt  time.Time                       `json:"created_at"`
+	References []*ChatMessageReferenceResponse `json:"references"`
 }
 
 type SendChatResponse struct {
@@ -43,6 +83,28 @@ type SendChatResponse struct {
 	Reply            *SendChatResponseChat `json:"reply"`
 }
 
+type RegenerateChatRequest struct {
+	ChatSessionId uuid.UUID `json:"chat_session_id" validate:"required"`
+}
+
+// EditChatRequest replaces an earlier user message with Chat on a new branch
+// and answers it again.
+type EditChatRequest struct {
+	ChatSessionId uuid.UUID         `json:"chat_session_id" validate:"required"`
+	ChatMessageId uuid.UUID         `json:"chat_message_id" validate:"required"`
+	Chat          string            `json:"chat" validate:"required"`
+	Scope         *ChatScopeRequest `json:"scope"`
+}
+
+type SelectChatBranchRequest struct {
+	ChatSessionId uuid.UUID `json:"chat_session_id" validate:"required"`
+	ChatMessageId uuid.UUID `json:"chat_message_id" validate:"required"`
+}
+
+type SendChatStreamDelta struct {
+	Text string `json:"text"`
+}
+
 type DeleteSessionRequest struct {
 	ChatSessionId uuid.UUID `json:"chat_session_id"`
 }
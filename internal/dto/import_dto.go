@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"io"
+
+	"github.com/google/uuid"
+)
+
+type ImportRequest struct {
+	File     io.ReaderAt
+	Size     int64
+	FileName string
+	// NotebookId is the notebook the archive is imported into. Without it
+	// top level folders become root notebooks.
+	NotebookId *uuid.UUID
+}
+
+type ImportFileResult struct {
+	Path   string     `json:"path"`
+	Status string     `json:"status"`
+	NoteId *uuid.UUID `json:"note_id,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+type ImportResponse struct {
+	NotebookCount int                 `json:"notebook_count"`
+	NoteCount     int                 `json:"note_count"`
+	SkippedCount  int                 `json:"skipped_count"`
+	FailedCount   int                 `json:"failed_count"`
+	Files         []*ImportFileResult `json:"files"`
+}
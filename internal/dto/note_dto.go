@@ -10,6 +10,7 @@ type CreateNoteRequest struct {
 	Title      string    `json:"title" validate:"required"`
 	Content    string    `json:"content"`
 	NotebookId uuid.UUID `json:"notebook_id" validate:"required"`
+	Tags       []string  `json:"tags" validate:"max=50,dive,max=50"`
 }
 
 type CreateNoteResponse struct {
@@ -17,18 +18,22 @@ type CreateNoteResponse struct {
 }
 
 type ShowNoteResponse struct {
-	Id         uuid.UUID  `json:"id"`
-	Title      string     `json:"title"`
-	Content    string     `json:"content"`
-	NotebookId uuid.UUID  `json:"notebook_id"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  *time.Time `json:"updated_at"`
+	Id         uuid.UUID      `json:"id"`
+	Title      string         `json:"title"`
+	Content    string         `json:"content"`
+	NotebookId uuid.UUID      `json:"notebook_id"`
+	Tags       []*TagResponse `json:"tags"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  *time.Time     `json:"updated_at"`
 }
 
+// UpdateNoteRequest leaves the tags of the note untouched when Tags is
+// omitted.
 type UpdateNoteRequest struct {
 	Id      uuid.UUID
-	Title   string `json:"title" validate:"required"`
-	Content string `json:"content"`
+	Title   string   `json:"title" validate:"required"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags" validate:"max=50,dive,max=50"`
 }
 
 type UpdateNoteResponse struct {
@@ -44,11 +49,70 @@ type MoveNoteResponse struct {
 	Id uuid.UUID `json:"id"`
 }
 
+type SemanticSearchRequest struct {
+	Query      string
+	Mode       string `validate:"omitempty,oneof=keyword semantic hybrid"`
+	Limit      int    `validate:"min=0,max=50"`
+	Offset     int    `validate:"min=0,max=1000"`
+	NotebookId *uuid.UUID
+	Tags       []string
+}
+
 type SemanticSearchResponse struct {
-	Id         uuid.UUID  `json:"id"`
-	Title      string     `json:"title"`
-	Content    string     `json:"content"`
-	NotebookId uuid.UUID  `json:"notebook_id"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  *time.Time `json:"updated_at"`
+	Id                 uuid.UUID      `json:"id"`
+	Title              string         `json:"title"`
+	Content            string         `json:"content"`
+	Snippet            string         `json:"snippet"`
+	ChunkIndex         int            `json:"chunk_index"`
+	AttachmentId       *uuid.UUID     `json:"attachment_id"`
+	AttachmentFileName *string        `json:"attachment_file_name"`
+	Score              float64        `json:"score"`
+	NotebookId         uuid.UUID      `json:"notebook_id"`
+	Tags               []*TagResponse `json:"tags"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          *time.Time     `json:"updated_at"`
+}
+
+type NoteRevisionResponse struct {
+	Id             uuid.UUID `json:"id"`
+	NoteId         uuid.UUID `json:"note_id"`
+	RevisionNumber int       `json:"revision_number"`
+	Title          string    `json:"title"`
+	Content        string    `json:"content,omitempty"`
+	NotebookId     uuid.UUID `json:"notebook_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type DiffNoteRevisionRequest struct {
+	NoteId         uuid.UUID
+	FromRevisionId uuid.UUID `validate:"required"`
+	ToRevisionId   uuid.UUID `validate:"required"`
+}
+
+type DiffNoteRevisionResponse struct {
+	FromRevisionNumber int    `json:"from_revision_number"`
+	ToRevisionNumber   int    `json:"to_revision_number"`
+	FromTitle          string `json:"from_title"`
+	ToTitle            string `json:"to_title"`
+	Diff               string `json:"diff"`
+}
+
+type RestoreNoteRevisionRequest struct {
+	NoteId     uuid.UUID
+	RevisionId uuid.UUID
+}
+
+type RestoreNoteRevisionResponse struct {
+	Id             uuid.UUID `json:"id"`
+	RevisionNumber int       `json:"revision_number"`
+}
+
+type BacklinkResponse struct {
+	Id         uuid.UUID `json:"id"`
+	Title      string    `json:"title"`
+	NotebookId uuid.UUID `json:"notebook_id"`
+	// Excerpt is the text around the first link to the note.
+	Excerpt   string     `json:"excerpt"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
 }
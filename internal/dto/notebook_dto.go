@@ -32,6 +32,11 @@ type UpdateNotebookResponse struct {
 	Id uuid.UUID `json:"id"`
 }
 
+type DeleteNotebookRequest struct {
+	Id      uuid.UUID
+	Cascade bool
+}
+
 type MoveNotebookRequest struct {
 	Id       uuid.UUID
 	ParentId *uuid.UUID `json:"parent_id"`
@@ -41,20 +46,85 @@ type MoveNotebookResponse struct {
 	Id uuid.UUID `json:"id"`
 }
 
+// GetAllNotebookRequest keeps only the notes carrying every tag in Tags.
+type GetAllNotebookRequest struct {
+	Tags []string
+}
+
 type GetAllNotebookResponseNote struct {
+	Id        uuid.UUID      `json:"id"`
+	Title     string         `json:"title"`
+	Content   string         `json:"content"`
+	Tags      []*TagResponse `json:"tags"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt *time.Time     `json:"updated_at"`
+}
+
+type GetAllNotebookResponse struct {
 	Id        uuid.UUID  `json:"id"`
-	Title     string     `json:"title"`
-	Content   string     `json:"content"`
+	Name      string     `json:"name"`
+	ParentId  *uuid.UUID `json:"parent_id"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at"`
+
+	Notes []*GetAllNotebookResponseNote `json:"notes"`
 }
 
-type GetAllNotebookResponse struct {
+type NotebookTreeResponse struct {
 	Id        uuid.UUID  `json:"id"`
 	Name      string     `json:"name"`
 	ParentId  *uuid.UUID `json:"parent_id"`
+	NoteCount int        `json:"note_count"`
+	// TotalNoteCount includes the notes of every descendant.
+	TotalNoteCount int        `json:"total_note_count"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+
+	// Notes and HasMoreNotes are only set by the full tree listing, which
+	// returns the first page of each notebook's notes.
+	Notes        []*NoteSummaryResponse `json:"notes,omitempty"`
+	HasMoreNotes bool                   `json:"has_more_notes,omitempty"`
+
+	Children []*NotebookTreeResponse `json:"children"`
+}
+
+type NoteSummaryResponse struct {
+	Id        uuid.UUID  `json:"id"`
+	Title     string     `json:"title"`
+	Excerpt   string     `json:"excerpt"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at"`
+}
 
-	Notes []*GetAllNotebookResponseNote `json:"notes"`
+type GetNotebookTreeRequest struct {
+	NotesLimit int `validate:"min=0,max=100"`
+}
+
+type GetNotebookNotesRequest struct {
+	Id     uuid.UUID
+	Limit  int `validate:"min=0,max=100"`
+	Offset int `validate:"min=0"`
+}
+
+type GetNotebookNotesResponse struct {
+	Notes  []*NoteSummaryResponse `json:"notes"`
+	Total  int                    `json:"total"`
+	Limit  int                    `json:"limit"`
+	Offset int                    `json:"offset"`
+}
+
+type NotebookGraphNode struct {
+	Id         uuid.UUID `json:"id"`
+	Title      string    `json:"title"`
+	NotebookId uuid.UUID `json:"notebook_id"`
+}
+
+type NotebookGraphEdge struct {
+	SourceId uuid.UUID `json:"source_id"`
+	TargetId uuid.UUID `json:"target_id"`
+}
+
+type NotebookGraphResponse struct {
+	Nodes []*NotebookGraphNode `json:"nodes"`
+	Edges []*NotebookGraphEdge `json:"edges"`
 }
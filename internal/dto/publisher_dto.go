@@ -3,5 +3,11 @@ package dto
 import "github.com/google/uuid"
 
 type PublishEmbedNoteMessage struct {
-	NoteId uuid.UUID `json:"note_id"`
+	NoteId  uuid.UUID `json:"note_id"`
+	OwnerId uuid.UUID `json:"owner_id"`
+}
+
+type PublishExtractAttachmentMessage struct {
+	AttachmentId uuid.UUID `json:"attachment_id"`
+	OwnerId      uuid.UUID `json:"owner_id"`
 }
@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TagResponse struct {
+	Id   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+type GetAllTagResponse struct {
+	Id        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	NoteCount int        `json:"note_count"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+type CreateTagRequest struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+type CreateTagResponse struct {
+	Id uuid.UUID `json:"id"`
+}
+
+type UpdateTagRequest struct {
+	Id   uuid.UUID
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+type UpdateTagResponse struct {
+	Id uuid.UUID `json:"id"`
+}
+
+type MergeTagRequest struct {
+	Id       uuid.UUID
+	TargetId uuid.UUID `json:"target_id" validate:"required"`
+}
+
+type MergeTagResponse struct {
+	Id uuid.UUID `json:"id"`
+}
+
+type SetNoteTagsRequest struct {
+	Id   uuid.UUID
+	Tags []string `json:"tags" validate:"max=50,dive,max=50"`
+}
+
+type SetNoteTagsResponse struct {
+	Id   uuid.UUID      `json:"id"`
+	Tags []*TagResponse `json:"tags"`
+}
@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type GetTrashResponseNotebook struct {
+	Id        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	ParentId  *uuid.UUID `json:"parent_id"`
+	NoteCount int        `json:"note_count"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   time.Time  `json:"purge_at"`
+}
+
+type GetTrashResponseNote struct {
+	Id         uuid.UUID `json:"id"`
+	Title      string    `json:"title"`
+	NotebookId uuid.UUID `json:"notebook_id"`
+	DeletedAt  time.Time `json:"deleted_at"`
+	PurgeAt    time.Time `json:"purge_at"`
+}
+
+type GetTrashResponse struct {
+	Notebooks []*GetTrashResponseNotebook `json:"notebooks"`
+	Notes     []*GetTrashResponseNote     `json:"notes"`
+}
+
+type RestoreNotebookResponse struct {
+	Id       uuid.UUID  `json:"id"`
+	ParentId *uuid.UUID `json:"parent_id"`
+}
+
+type RestoreNoteResponse struct {
+	Id uuid.UUID `json:"id"`
+}
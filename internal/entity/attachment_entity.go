@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Attachment struct {
+	Id               uuid.UUID
+	NoteId           uuid.UUID
+	FileName         string
+	ContentType      string
+	Size             int64
+	StorageKey       string
+	OwnerId          uuid.UUID
+	ExtractionStatus string
+	ExtractionError  *string
+	ExtractedAt      *time.Time
+	CreatedAt        time.Time
+}
+
+// AttachmentText is the text extracted from an attachment, which chunk
+// offsets of attachment embeddings point into.
+type AttachmentText struct {
+	AttachmentId uuid.UUID
+	FileName     string
+	Text         string
+}
@@ -11,8 +11,11 @@ type ChatMessage struct {
 	Chat          string
 	Role          string
 	ChatSessionId uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     *time.Time
-	DeletedAt     *time.Time
-	IsDeleted     bool
+	// ParentId is the message this one follows, nil for the greeting that
+	// starts a session. Messages sharing a parent are alternative branches.
+	ParentId  *uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+	DeletedAt *time.Time
+	IsDeleted bool
 }
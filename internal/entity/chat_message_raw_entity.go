@@ -11,8 +11,14 @@ type ChatMessageRaw struct {
 	Chat          string
 	Role          string
 	ChatSessionId uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     *time.Time
-	DeletedAt     *time.Time
-	IsDeleted     bool
+	// ChatMessageId is the message the raw message was sent as, nil for the
+	// instructions that start a session.
+	ChatMessageId *uuid.UUID
+	// RetrievalQuery is the standalone rewrite of a user message that was
+	// embedded to retrieve its references.
+	RetrievalQuery *string
+	CreatedAt      time.Time
+	UpdatedAt      *time.Time
+	DeletedAt      *time.Time
+	IsDeleted      bool
 }
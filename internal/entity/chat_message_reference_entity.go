@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ChatMessageReference struct {
+	Id                 uuid.UUID
+	ChatMessageId      uuid.UUID
+	ReferenceNumber    int
+	NoteId             uuid.UUID
+	NoteTitle          string
+	ChunkIndex         int
+	AttachmentId       *uuid.UUID
+	AttachmentFileName *string
+	Snippet            string
+	Score              float64
+	CreatedAt          time.Time
+	UpdatedAt          *time.Time
+	DeletedAt          *time.Time
+	IsDeleted          bool
+}
@@ -7,10 +7,28 @@ import (
 )
 
 type ChatSession struct {
-	Id        uuid.UUID
-	Title     string
-	CreatedAt time.Time
-	UpdatedAt *time.Time
-	DeletedAt *time.Time
-	IsDeleted bool
+	Id    uuid.UUID
+	Title string
+	Scope ChatScope
+	// ActiveMessageId is the last message of the branch the session
+	// continues from.
+	ActiveMessageId *uuid.UUID
+	// Summary condenses the raw messages of the branch up to
+	// SummarizedMessageId, which are no longer sent to the chat model.
+	Summary             string
+	SummarizedMessageId *uuid.UUID
+	OwnerId             uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           *time.Time
+	DeletedAt           *time.Time
+	IsDeleted           bool
+}
+
+// ChatScope limits the notes a chat retrieves references from to a notebook
+// and its descendants, notes carrying all of TagIds and the notes in NoteIds.
+// Empty fields do not limit anything.
+type ChatScope struct {
+	NotebookId *uuid.UUID
+	TagIds     []uuid.UUID
+	NoteIds    []uuid.UUID
 }
@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Job struct {
+	Id          uuid.UUID
+	Topic       string
+	DedupKey    string
+	Payload     []byte
+	Status      string
+	Attempts    int
+	MaxAttempts int
+	Version     int
+	RunAt       time.Time
+	LockedUntil *time.Time
+	LastError   *string
+	CreatedAt   time.Time
+	UpdatedAt   *time.Time
+}
@@ -11,6 +11,11 @@ type NoteEmbedding struct {
 	Document       string
 	EmbeddingValue []float32
 	NoteId         uuid.UUID
+	AttachmentId   *uuid.UUID
+	ChunkIndex     int
+	StartOffset    int
+	EndOffset      int
+	Score          float64
 	CreatedAt      time.Time
 	UpdatedAt      *time.Time
 	DeletedAt      *time.Time
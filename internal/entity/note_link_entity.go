@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NoteLink struct {
+	Id           uuid.UUID
+	SourceNoteId uuid.UUID
+	TargetTitle  string
+	TargetNoteId *uuid.UUID
+	OwnerId      uuid.UUID
+	CreatedAt    time.Time
+}
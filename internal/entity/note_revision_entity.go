@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NoteRevision struct {
+	Id             uuid.UUID
+	NoteId         uuid.UUID
+	RevisionNumber int
+	Title          string
+	Content        string
+	NotebookId     uuid.UUID
+	OwnerId        uuid.UUID
+	CreatedAt      time.Time
+}
@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NoteSummary is a note without its full content, used for listings.
+type NoteSummary struct {
+	Id         uuid.UUID
+	Title      string
+	Excerpt    string
+	NotebookId uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  *time.Time
+}
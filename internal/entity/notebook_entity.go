@@ -7,11 +7,13 @@ import (
 )
 
 type Notebook struct {
-	Id        uuid.UUID
-	Name      string
-	ParentId  *uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt *time.Time
-	DeletedAt *time.Time
-	IsDeleted bool
+	Id             uuid.UUID
+	Name           string
+	ParentId       *uuid.UUID
+	DetachedFromId *uuid.UUID
+	OwnerId        uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      *time.Time
+	DeletedAt      *time.Time
+	IsDeleted      bool
 }
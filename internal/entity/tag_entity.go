@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Tag struct {
+	Id        uuid.UUID
+	Name      string
+	OwnerId   uuid.UUID
+	NoteCount int
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
@@ -0,0 +1,24 @@
+package serverutils
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type userIdContextKey struct{}
+
+func ContextWithUserId(ctx context.Context, userId uuid.UUID) context.Context {
+	return context.WithValue(ctx, userIdContextKey{}, userId)
+}
+
+// UserIdFromContext returns the authenticated user id, or uuid.Nil when the
+// context carries none so that owner scoped queries match nothing.
+func UserIdFromContext(ctx context.Context) uuid.UUID {
+	userId, ok := ctx.Value(userIdContextKey{}).(uuid.UUID)
+	if !ok {
+		return uuid.Nil
+	}
+
+	return userId
+}
@@ -0,0 +1,33 @@
+package serverutils
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AuthenticateFunc func(ctx context.Context, accessToken string) (uuid.UUID, error)
+
+// AuthMiddleware validates the bearer access token and stores the user id in
+// the request context, readable with UserIdFromContext(ctx.Context()).
+func AuthMiddleware(authenticate AuthenticateFunc) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authorization := c.Get(fiber.HeaderAuthorization)
+		accessToken, ok := strings.CutPrefix(authorization, "Bearer ")
+		if !ok || accessToken == "" {
+			return ErrUnauthorized
+		}
+
+		userId, err := authenticate(c.Context(), accessToken)
+		if err != nil {
+			return err
+		}
+
+		c.Context().SetUserValue(userIdContextKey{}, userId)
+		c.SetUserContext(ContextWithUserId(c.UserContext(), userId))
+
+		return c.Next()
+	}
+}
@@ -26,25 +26,34 @@ func ErrorHandlerMiddleware() fiber.Handler {
 			return nil
 		}
 
-		if errors.Is(err, ErrNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse(
-				fiber.StatusNotFound, "Entity not found",
-			))
-		}
+		status, res := ErrorToResponse(err)
+		return c.Status(status).JSON(res)
+	}
+}
 
-		if fiberErr, ok := err.(*fiber.Error); ok {
-			return c.Status(fiberErr.Code).JSON(ErrorResponse(
-				fiberErr.Code, fiberErr.Message,
-			))
-		}
+// ErrorToResponse maps err to its status code and response body. Errors
+// without a mapping are logged and reported as internal server errors.
+func ErrorToResponse(err error) (int, BaseResponse[any]) {
+	if errors.Is(err, ErrNotFound) {
+		return fiber.StatusNotFound, ErrorResponse(fiber.StatusNotFound, "Entity not found")
+	}
 
-		if ve, ok := err.(*ValidationError); ok {
-			return c.Status(fiber.StatusBadRequest).JSON(ValidationErrorResponse(ve.ToErrorDetails()))
-		}
+	if errors.Is(err, ErrUnauthorized) {
+		return fiber.StatusUnauthorized, ErrorResponse(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	if errors.Is(err, ErrConflict) {
+		return fiber.StatusConflict, ErrorResponse(fiber.StatusConflict, err.Error())
+	}
 
-		log.Printf("[ERROR] %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse(
-			fiber.StatusInternalServerError, err.Error(),
-		))
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return fiberErr.Code, ErrorResponse(fiberErr.Code, fiberErr.Message)
 	}
+
+	if ve, ok := err.(*ValidationError); ok {
+		return fiber.StatusBadRequest, ValidationErrorResponse(ve.ToErrorDetails())
+	}
+
+	log.Printf("[ERROR] %v", err)
+	return fiber.StatusInternalServerError, ErrorResponse(fiber.StatusInternalServerError, err.Error())
 }
@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/pkg/database"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IAttachmentRepository interface {
+	UsingTx(ctx context.Context, tx database.DatabaseQueryer) IAttachmentRepository
+	Create(ctx context.Context, attachment *entity.Attachment) error
+	GetById(ctx context.Context, id uuid.UUID) (*entity.Attachment, error)
+	GetByNoteId(ctx context.Context, noteId uuid.UUID) ([]*entity.Attachment, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	UpdateExtraction(ctx context.Context, attachment *entity.Attachment, extractedText *string) error
+	GetTextsByIds(ctx context.Context, ids []uuid.UUID) ([]*entity.AttachmentText, error)
+	GetStorageKeysByNoteIds(ctx context.Context, noteIds []uuid.UUID) ([]string, error)
+	DeleteByNoteIds(ctx context.Context, noteIds []uuid.UUID) error
+}
+
+type attachmentRepository struct {
+	db database.DatabaseQueryer
+}
+
+func (a *attachmentRepository) UsingTx(ctx context.Context, tx database.DatabaseQueryer) IAttachmentRepository {
+	return &attachmentRepository{
+		db: tx,
+	}
+}
+
+func (a *attachmentRepository) Create(ctx context.Context, attachment *entity.Attachment) error {
+	_, err := a.db.Exec(
+		ctx,
+		`INSERT INTO attachment (id, note_id, file_name, content_type, size, storage_key, owner_id, extraction_status, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		attachment.Id,
+		attachment.NoteId,
+		attachment.FileName,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.StorageKey,
+		attachment.OwnerId,
+		attachment.ExtractionStatus,
+		attachment.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetById only returns attachments of notes that are not in the trash.
+func (a *attachmentRepository) GetById(ctx context.Context, id uuid.UUID) (*entity.Attachment, error) {
+	row := a.db.QueryRow(
+		ctx,
+		`
+		SELECT a.id, a.note_id, a.file_name, a.content_type, a.size, a.storage_key, a.owner_id, a.extraction_status, a.extraction_error, a.extracted_at, a.created_at
+		FROM attachment a
+		JOIN note n ON n.id = a.note_id
+		WHERE a.id = $1 AND a.owner_id = $2 AND n.is_deleted = false
+		`,
+		id,
+		serverutils.UserIdFromContext(ctx),
+	)
+
+	var attachment entity.Attachment
+	err := row.Scan(
+		&attachment.Id,
+		&attachment.NoteId,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.StorageKey,
+		&attachment.OwnerId,
+		&attachment.ExtractionStatus,
+		&attachment.ExtractionError,
+		&attachment.ExtractedAt,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, serverutils.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+func (a *attachmentRepository) GetByNoteId(ctx context.Context, noteId uuid.UUID) ([]*entity.Attachment, error) {
+	rows, err := a.db.Query(
+		ctx,
+		`
+		SELECT id, note_id, file_name, content_type, size, storage_key, owner_id, extraction_status, extraction_error, extracted_at, created_at
+		FROM attachment
+		WHERE note_id = $1 AND owner_id = $2
+		ORDER BY created_at ASC
+		`,
+		noteId,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entity.Attachment, 0)
+	for rows.Next() {
+		var attachment entity.Attachment
+		err = rows.Scan(
+			&attachment.Id,
+			&attachment.NoteId,
+			&attachment.FileName,
+			&attachment.ContentType,
+			&attachment.Size,
+			&attachment.StorageKey,
+			&attachment.OwnerId,
+			&attachment.ExtractionStatus,
+			&attachment.ExtractionError,
+			&attachment.ExtractedAt,
+			&attachment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &attachment)
+	}
+
+	return result, nil
+}
+
+func (a *attachmentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := a.db.Exec(
+		ctx,
+		`DELETE FROM attachment WHERE id = $1 AND owner_id = $2`,
+		id,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateExtraction stores the extraction result of the attachment. The
+// extracted text is left unchanged when extractedText is nil.
+func (a *attachmentRepository) UpdateExtraction(ctx context.Context, attachment *entity.Attachment, extractedText *string) error {
+	_, err := a.db.Exec(
+		ctx,
+		`UPDATE attachment SET extraction_status = $1, extraction_error = $2, extracted_at = $3, extracted_text = COALESCE($4, extracted_text) WHERE id = $5`,
+		attachment.ExtractionStatus,
+		attachment.ExtractionError,
+		attachment.ExtractedAt,
+		extractedText,
+		attachment.Id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *attachmentRepository) GetTextsByIds(ctx context.Context, ids []uuid.UUID) ([]*entity.AttachmentText, error) {
+	result := make([]*entity.AttachmentText, 0)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range ids {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	rows, err := a.db.Query(
+		ctx,
+		fmt.Sprintf(`SELECT id, file_name, COALESCE(extracted_text, '') FROM attachment WHERE id IN (%s) AND owner_id = $1`, idSqlFormat),
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var attachmentText entity.AttachmentText
+		err = rows.Scan(
+			&attachmentText.AttachmentId,
+			&attachmentText.FileName,
+			&attachmentText.Text,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &attachmentText)
+	}
+
+	return result, nil
+}
+
+func (a *attachmentRepository) GetStorageKeysByNoteIds(ctx context.Context, noteIds []uuid.UUID) ([]string, error) {
+	result := make([]string, 0)
+	if len(noteIds) == 0 {
+		return result, nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range noteIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	rows, err := a.db.Query(
+		ctx,
+		fmt.Sprintf(`SELECT storage_key FROM attachment WHERE note_id IN (%s)`, idSqlFormat),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var storageKey string
+		err = rows.Scan(&storageKey)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, storageKey)
+	}
+
+	return result, nil
+}
+
+func (a *attachmentRepository) DeleteByNoteIds(ctx context.Context, noteIds []uuid.UUID) error {
+	if len(noteIds) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range noteIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	_, err := a.db.Exec(
+		ctx,
+		fmt.Sprintf(`DELETE FROM attachment WHERE note_id IN (%s)`, idSqlFormat),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func NewAttachmentRepository(db *pgxpool.Pool) IAttachmentRepository {
+	return &attachmentRepository{
+		db: db,
+	}
+}
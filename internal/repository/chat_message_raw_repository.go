@@ -14,6 +14,7 @@ type IChatMessageRawRepository interface {
 	UsingTx(ctx context.Context, tx database.DatabaseQueryer) IChatMessageRawRepository
 	Create(ctx context.Context, chatMessageRaw *entity.ChatMessageRaw) error
 	GetByChatSessionId(ctx context.Context, chatSessionId uuid.UUID) ([]*entity.ChatMessageRaw, error)
+	Update(ctx context.Context, chatMessageRaw *entity.ChatMessageRaw) error
 	DeleteByChatSessionId(ctx context.Context, chatSessionId uuid.UUID) error
 }
 
@@ -30,11 +31,13 @@ func (n *chatMessageRawRepository) UsingTx(ctx context.Context, tx database.Data
 func (cs *chatMessageRawRepository) Create(ctx context.Context, chatMessageRaw *entity.ChatMessageRaw) error {
 	_, err := cs.db.Exec(
 		ctx,
-		`INSERT INTO chat_message_raw (id, role, chat, chat_session_id, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		`INSERT INTO chat_message_raw (id, role, chat, chat_session_id, chat_message_id, retrieval_query, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
 		chatMessageRaw.Id,
 		chatMessageRaw.Role,
 		chatMessageRaw.Chat,
 		chatMessageRaw.ChatSessionId,
+		chatMessageRaw.ChatMessageId,
+		chatMessageRaw.RetrievalQuery,
 		chatMessageRaw.CreatedAt,
 		chatMessageRaw.UpdatedAt,
 		chatMessageRaw.DeletedAt,
@@ -50,7 +53,7 @@ func (cs *chatMessageRawRepository) Create(ctx context.Context, chatMessageRaw *
 func (cs *chatMessageRawRepository) GetByChatSessionId(ctx context.Context, chatSessionId uuid.UUID) ([]*entity.ChatMessageRaw, error) {
 	rows, err := cs.db.Query(
 		ctx,
-		`SELECT id, role, chat, chat_session_id, created_at, updated_at, deleted_at, is_deleted FROM chat_message_raw WHERE chat_session_id = $1 AND is_deleted = false ORDER BY created_at ASC`,
+		`SELECT id, role, chat, chat_session_id, chat_message_id, retrieval_query, created_at, updated_at, deleted_at, is_deleted FROM chat_message_raw WHERE chat_session_id = $1 AND is_deleted = false ORDER BY created_at ASC`,
 		chatSessionId,
 	)
 	if err != nil {
@@ -66,6 +69,8 @@ func (cs *chatMessageRawRepository) GetByChatSessionId(ctx context.Context, chat
 			&chatMessageRaw.Role,
 			&chatMessageRaw.Chat,
 			&chatMessageRaw.ChatSessionId,
+			&chatMessageRaw.ChatMessageId,
+			&chatMessageRaw.RetrievalQuery,
 			&chatMessageRaw.CreatedAt,
 			&chatMessageRaw.UpdatedAt,
 			&chatMessageRaw.DeletedAt,
@@ -81,6 +86,22 @@ func (cs *chatMessageRawRepository) GetByChatSessionId(ctx context.Context, chat
 	return res, nil
 }
 
+func (cs *chatMessageRawRepository) Update(ctx context.Context, chatMessageRaw *entity.ChatMessageRaw) error {
+	_, err := cs.db.Exec(
+		ctx,
+		`UPDATE chat_message_raw SET chat = $1, retrieval_query = $2, updated_at = $3 WHERE id = $4`,
+		chatMessageRaw.Chat,
+		chatMessageRaw.RetrievalQuery,
+		chatMessageRaw.UpdatedAt,
+		chatMessageRaw.Id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (cs *chatMessageRawRepository) DeleteByChatSessionId(ctx context.Context, chatSessionId uuid.UUID) error {
 	_, err := cs.db.Exec(
 		ctx,
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/pkg/database"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IChatMessageReferenceRepository interface {
+	UsingTx(ctx context.Context, tx database.DatabaseQueryer) IChatMessageReferenceRepository
+	Create(ctx context.Context, chatMessageReference *entity.ChatMessageReference) error
+	GetByChatMessageIds(ctx context.Context, chatMessageIds []uuid.UUID) ([]*entity.ChatMessageReference, error)
+	DeleteByChatSessionId(ctx context.Context, chatSessionId uuid.UUID) error
+}
+
+type chatMessageReferenceRepository struct {
+	db database.DatabaseQueryer
+}
+
+func (n *chatMessageReferenceRepository) UsingTx(ctx context.Context, tx database.DatabaseQueryer) IChatMessageReferenceRepository {
+	return &chatMessageReferenceRepository{
+		db: tx,
+	}
+}
+
+func (cs *chatMessageReferenceRepository) Create(ctx context.Context, chatMessageReference *entity.ChatMessageReference) error {
+	_, err := cs.db.Exec(
+		ctx,
+		`INSERT INTO chat_message_reference (id, chat_message_id, reference_number, note_id, note_title, chunk_index, attachment_id, attachment_file_name, snippet, score, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		chatMessageReference.Id,
+		chatMessageReference.ChatMessageId,
+		chatMessageReference.ReferenceNumber,
+		chatMessageReference.NoteId,
+		chatMessageReference.NoteTitle,
+		chatMessageReference.ChunkIndex,
+		chatMessageReference.AttachmentId,
+		chatMessageReference.AttachmentFileName,
+		chatMessageReference.Snippet,
+		chatMessageReference.Score,
+		chatMessageReference.CreatedAt,
+		chatMessageReference.UpdatedAt,
+		chatMessageReference.DeletedAt,
+		chatMessageReference.IsDeleted,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (cs *chatMessageReferenceRepository) GetByChatMessageIds(ctx context.Context, chatMessageIds []uuid.UUID) ([]*entity.ChatMessageReference, error) {
+	if len(chatMessageIds) == 0 {
+		return make([]*entity.ChatMessageReference, 0), nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range chatMessageIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	rows, err := cs.db.Query(
+		ctx,
+		fmt.Sprintf(`SELECT id, chat_message_id, reference_number, note_id, note_title, chunk_index, attachment_id, attachment_file_name, snippet, score, created_at, updated_at, deleted_at, is_deleted FROM chat_message_reference WHERE chat_message_id IN (%s) AND is_deleted = false ORDER BY reference_number ASC`, idSqlFormat),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*entity.ChatMessageReference, 0)
+	for rows.Next() {
+		var chatMessageReference entity.ChatMessageReference
+
+		err = rows.Scan(
+			&chatMessageReference.Id,
+			&chatMessageReference.ChatMessageId,
+			&chatMessageReference.ReferenceNumber,
+			&chatMessageReference.NoteId,
+			&chatMessageReference.NoteTitle,
+			&chatMessageReference.ChunkIndex,
+			&chatMessageReference.AttachmentId,
+			&chatMessageReference.AttachmentFileName,
+			&chatMessageReference.Snippet,
+			&chatMessageReference.Score,
+			&chatMessageReference.CreatedAt,
+			&chatMessageReference.UpdatedAt,
+			&chatMessageReference.DeletedAt,
+			&chatMessageReference.IsDeleted,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &chatMessageReference)
+	}
+
+	return res, nil
+}
+
+func (cs *chatMessageReferenceRepository) DeleteByChatSessionId(ctx context.Context, chatSessionId uuid.UUID) error {
+	_, err := cs.db.Exec(
+		ctx,
+		`UPDATE chat_message_reference SET is_deleted = true, deleted_at = $1 WHERE chat_message_id IN (SELECT id FROM chat_message WHERE chat_session_id = $2)`,
+		time.Now(),
+		chatSessionId,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func NewChatMessageReferenceRepository(db *pgxpool.Pool) IChatMessageReferenceRepository {
+	return &chatMessageReferenceRepository{
+		db: db,
+	}
+}
@@ -30,11 +30,12 @@ func (n *chatMessageRepository) UsingTx(ctx context.Context, tx database.Databas
 func (cs *chatMessageRepository) Create(ctx context.Context, chatMessage *entity.ChatMessage) error {
 	_, err := cs.db.Exec(
 		ctx,
-		`INSERT INTO chat_message (id, role, chat, chat_session_id, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		`INSERT INTO chat_message (id, role, chat, chat_session_id, parent_id, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
 		chatMessage.Id,
 		chatMessage.Role,
 		chatMessage.Chat,
 		chatMessage.ChatSessionId,
+		chatMessage.ParentId,
 		chatMessage.CreatedAt,
 		chatMessage.UpdatedAt,
 		chatMessage.DeletedAt,
@@ -50,7 +51,7 @@ func (cs *chatMessageRepository) Create(ctx context.Context, chatMessage *entity
 func (cs *chatMessageRepository) GetByChatSessionId(ctx context.Context, chatSessionId uuid.UUID) ([]*entity.ChatMessage, error) {
 	rows, err := cs.db.Query(
 		ctx,
-		`SELECT id, role, chat, chat_session_id, created_at, updated_at, deleted_at, is_deleted FROM chat_message WHERE chat_session_id = $1 AND is_deleted = false ORDER BY created_at ASC`,
+		`SELECT id, role, chat, chat_session_id, parent_id, created_at, updated_at, deleted_at, is_deleted FROM chat_message WHERE chat_session_id = $1 AND is_deleted = false ORDER BY created_at ASC`,
 		chatSessionId,
 	)
 	if err != nil {
@@ -66,6 +67,7 @@ func (cs *chatMessageRepository) GetByChatSessionId(ctx context.Context, chatSes
 			&chatMessage.Role,
 			&chatMessage.Chat,
 			&chatMessage.ChatSessionId,
+			&chatMessage.ParentId,
 			&chatMessage.CreatedAt,
 			&chatMessage.UpdatedAt,
 			&chatMessage.DeletedAt,
@@ -2,11 +2,14 @@ package repository
 
 import (
 	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
 	"ai-notetaking-be/pkg/database"
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -16,6 +19,8 @@ type IChatSessionRepository interface {
 	GetAll(ctx context.Context) ([]*entity.ChatSession, error)
 	GetById(ctx context.Context, id uuid.UUID) (*entity.ChatSession, error)
 	Update(ctx context.Context, chatSession *entity.ChatSession) error
+	UpdateSummary(ctx context.Context, chatSession *entity.ChatSession) error
+	UpdateActiveMessage(ctx context.Context, chatSession *entity.ChatSession) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
@@ -32,9 +37,13 @@ func (n *chatSessionRepository) UsingTx(ctx context.Context, tx database.Databas
 func (cs *chatSessionRepository) Create(ctx context.Context, chatSession *entity.ChatSession) error {
 	_, err := cs.db.Exec(
 		ctx,
-		`INSERT INTO chat_session (id, title, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6)`,
+		`INSERT INTO chat_session (id, title, scope_notebook_id, scope_tag_ids, scope_note_ids, owner_id, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
 		chatSession.Id,
 		chatSession.Title,
+		chatSession.Scope.NotebookId,
+		chatSession.Scope.TagIds,
+		chatSession.Scope.NoteIds,
+		chatSession.OwnerId,
 		chatSession.CreatedAt,
 		chatSession.UpdatedAt,
 		chatSession.DeletedAt,
@@ -50,7 +59,8 @@ func (cs *chatSessionRepository) Create(ctx context.Context, chatSession *entity
 func (cs *chatSessionRepository) GetAll(ctx context.Context) ([]*entity.ChatSession, error) {
 	rows, err := cs.db.Query(
 		ctx,
-		`SELECT id, title, created_at, updated_at, deleted_at, is_deleted FROM chat_session WHERE is_deleted = false ORDER BY created_at DESC`,
+		`SELECT id, title, scope_notebook_id, scope_tag_ids, scope_note_ids, active_message_id, summary, summarized_message_id, owner_id, created_at, updated_at, deleted_at, is_deleted FROM chat_session WHERE is_deleted = false AND owner_id = $1 ORDER BY created_at DESC`,
+		serverutils.UserIdFromContext(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -63,6 +73,13 @@ func (cs *chatSessionRepository) GetAll(ctx context.Context) ([]*entity.ChatSess
 		err = rows.Scan(
 			&chatSession.Id,
 			&chatSession.Title,
+			&chatSession.Scope.NotebookId,
+			&chatSession.Scope.TagIds,
+			&chatSession.Scope.NoteIds,
+			&chatSession.ActiveMessageId,
+			&chatSession.Summary,
+			&chatSession.SummarizedMessageId,
+			&chatSession.OwnerId,
 			&chatSession.CreatedAt,
 			&chatSession.UpdatedAt,
 			&chatSession.DeletedAt,
@@ -81,20 +98,31 @@ func (cs *chatSessionRepository) GetAll(ctx context.Context) ([]*entity.ChatSess
 func (cs *chatSessionRepository) GetById(ctx context.Context, id uuid.UUID) (*entity.ChatSession, error) {
 	row := cs.db.QueryRow(
 		ctx,
-		`SELECT id, title, created_at, updated_at, deleted_at, is_deleted FROM chat_session WHERE id = $1 AND is_deleted = false`,
+		`SELECT id, title, scope_notebook_id, scope_tag_ids, scope_note_ids, active_message_id, summary, summarized_message_id, owner_id, created_at, updated_at, deleted_at, is_deleted FROM chat_session WHERE id = $1 AND is_deleted = false AND owner_id = $2`,
 		id,
+		serverutils.UserIdFromContext(ctx),
 	)
 
 	var result entity.ChatSession
 	err := row.Scan(
 		&result.Id,
 		&result.Title,
+		&result.Scope.NotebookId,
+		&result.Scope.TagIds,
+		&result.Scope.NoteIds,
+		&result.ActiveMessageId,
+		&result.Summary,
+		&result.SummarizedMessageId,
+		&result.OwnerId,
 		&result.CreatedAt,
 		&result.UpdatedAt,
 		&result.DeletedAt,
 		&result.IsDeleted,
 	)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, serverutils.ErrNotFound
+		}
 		return nil, err
 	}
 
@@ -104,10 +132,11 @@ func (cs *chatSessionRepository) GetById(ctx context.Context, id uuid.UUID) (*en
 func (cs *chatSessionRepository) Update(ctx context.Context, chatSession *entity.ChatSession) error {
 	_, err := cs.db.Exec(
 		ctx,
-		`UPDATE chat_session SET title = $1, updated_at = $2 WHERE id = $3`,
+		`UPDATE chat_session SET title = $1, updated_at = $2 WHERE id = $3 AND owner_id = $4`,
 		chatSession.Title,
 		chatSession.UpdatedAt,
 		chatSession.Id,
+		serverutils.UserIdFromContext(ctx),
 	)
 	if err != nil {
 		return nil
@@ -116,12 +145,44 @@ func (cs *chatSessionRepository) Update(ctx context.Context, chatSession *entity
 	return nil
 }
 
+func (cs *chatSessionRepository) UpdateSummary(ctx context.Context, chatSession *entity.ChatSession) error {
+	_, err := cs.db.Exec(
+		ctx,
+		`UPDATE chat_session SET summary = $1, summarized_message_id = $2 WHERE id = $3 AND owner_id = $4`,
+		chatSession.Summary,
+		chatSession.SummarizedMessageId,
+		chatSession.Id,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (cs *chatSessionRepository) UpdateActiveMessage(ctx context.Context, chatSession *entity.ChatSession) error {
+	_, err := cs.db.Exec(
+		ctx,
+		`UPDATE chat_session SET active_message_id = $1 WHERE id = $2 AND owner_id = $3`,
+		chatSession.ActiveMessageId,
+		chatSession.Id,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (cs *chatSessionRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	_, err := cs.db.Exec(
 		ctx,
-		`UPDATE chat_session SET deleted_at = $1, is_deleted = true WHERE id = $2`,
+		`UPDATE chat_session SET deleted_at = $1, is_deleted = true WHERE id = $2 AND owner_id = $3`,
 		time.Now(),
 		id,
+		serverutils.UserIdFromContext(ctx),
 	)
 	if err != nil {
 		return nil
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"ai-notetaking-be/internal/constant"
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/pkg/database"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// jobEnqueueBatchSize bounds the rows of one insert in EnqueueBatch, each row
+// taking 7 of the 65535 parameters a statement can have.
+const jobEnqueueBatchSize = 1000
+
+type IJobRepository interface {
+	UsingTx(ctx context.Context, tx database.DatabaseQueryer) IJobRepository
+	Enqueue(ctx context.Context, job *entity.Job) error
+	EnqueueBatch(ctx context.Context, jobs []*entity.Job) error
+	ClaimDue(ctx context.Context, topic string, limit int, lease time.Duration) ([]*entity.Job, error)
+	MarkDone(ctx context.Context, id uuid.UUID, version int) error
+	MarkFailed(ctx context.Context, id uuid.UUID, version int, lastError string, runAt time.Time) error
+	MarkDead(ctx context.Context, id uuid.UUID, version int, lastError string) error
+}
+
+type jobRepository struct {
+	db database.DatabaseQueryer
+}
+
+func (j *jobRepository) UsingTx(ctx context.Context, tx database.DatabaseQueryer) IJobRepository {
+	return &jobRepository{
+		db: tx,
+	}
+}
+
+// Enqueue inserts a job, or resets the existing job with the same topic and
+// dedup key back to pending. The version is bumped so a worker that is still
+// processing the previous payload does not mark the new one as done.
+func (j *jobRepository) Enqueue(ctx context.Context, job *entity.Job) error {
+	_, err := j.db.Exec(
+		ctx,
+		`
+		INSERT INTO job_queue (id, topic, dedup_key, payload, status, attempts, max_attempts, version, run_at, locked_until, last_error, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 0, $6, 1, $7, null, null, $8, null)
+		ON CONFLICT (topic, dedup_key) DO UPDATE SET
+			payload = EXCLUDED.payload,
+			status = EXCLUDED.status,
+			attempts = 0,
+			max_attempts = EXCLUDED.max_attempts,
+			version = job_queue.version + 1,
+			run_at = EXCLUDED.run_at,
+			last_error = null,
+			updated_at = EXCLUDED.created_at
+		`,
+		job.Id,
+		job.Topic,
+		job.DedupKey,
+		job.Payload,
+		constant.JobStatusPending,
+		job.MaxAttempts,
+		job.RunAt,
+		job.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EnqueueBatch enqueues jobs with one statement per jobEnqueueBatchSize jobs,
+// with the same upsert semantics as Enqueue. Dedup keys must be unique within
+// jobs. Use it in a transaction for the jobs to be enqueued all or none.
+func (j *jobRepository) EnqueueBatch(ctx context.Context, jobs []*entity.Job) error {
+	for start := 0; start < len(jobs); start += jobEnqueueBatchSize {
+		end := min(start+jobEnqueueBatchSize, len(jobs))
+		err := j.enqueueBatch(ctx, jobs[start:end])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *jobRepository) enqueueBatch(ctx context.Context, jobs []*entity.Job) error {
+	values := make([]string, 0)
+	args := []any{constant.JobStatusPending}
+	for _, job := range jobs {
+		n := len(args)
+		values = append(values, fmt.Sprintf(
+			"($%d, $%d, $%d, $%d, $1, 0, $%d, 1, $%d, null, null, $%d, null)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7,
+		))
+		args = append(args, job.Id, job.Topic, job.DedupKey, job.Payload, job.MaxAttempts, job.RunAt, job.CreatedAt)
+	}
+
+	_, err := j.db.Exec(
+		ctx,
+		fmt.Sprintf(`
+		INSERT INTO job_queue (id, topic, dedup_key, payload, status, attempts, max_attempts, version, run_at, locked_until, last_error, created_at, updated_at)
+		VALUES %s
+		ON CONFLICT (topic, dedup_key) DO UPDATE SET
+			payload = EXCLUDED.payload,
+			status = EXCLUDED.status,
+			attempts = 0,
+			max_attempts = EXCLUDED.max_attempts,
+			version = job_queue.version + 1,
+			run_at = EXCLUDED.run_at,
+			last_error = null,
+			updated_at = EXCLUDED.created_at
+		`, strings.Join(values, ", ")),
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ClaimDue locks up to limit due jobs for lease. Jobs whose lease expired,
+// for example because the worker crashed, are claimed again.
+func (j *jobRepository) ClaimDue(ctx context.Context, topic string, limit int, lease time.Duration) ([]*entity.Job, error) {
+	now := time.Now()
+	rows, err := j.db.Query(
+		ctx,
+		`
+		UPDATE job_queue SET
+			status = $1,
+			attempts = attempts + 1,
+			locked_until = $2,
+			updated_at = $3
+		WHERE id IN (
+			SELECT id FROM job_queue
+			WHERE topic = $4
+				AND (
+					(status = $5 AND run_at <= $3)
+					OR (status = $1 AND locked_until < $3)
+				)
+			ORDER BY run_at ASC
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, topic, dedup_key, payload, status, attempts, max_attempts, version, run_at, locked_until, last_error, created_at, updated_at
+		`,
+		constant.JobStatusProcessing,
+		now.Add(lease),
+		now,
+		topic,
+		constant.JobStatusPending,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entity.Job, 0)
+	for rows.Next() {
+		var job entity.Job
+		err = rows.Scan(
+			&job.Id,
+			&job.Topic,
+			&job.DedupKey,
+			&job.Payload,
+			&job.Status,
+			&job.Attempts,
+			&job.MaxAttempts,
+			&job.Version,
+			&job.RunAt,
+			&job.LockedUntil,
+			&job.LastError,
+			&job.CreatedAt,
+			&job.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &job)
+	}
+
+	return result, nil
+}
+
+func (j *jobRepository) MarkDone(ctx context.Context, id uuid.UUID, version int) error {
+	_, err := j.db.Exec(
+		ctx,
+		`UPDATE job_queue SET status = $1, locked_until = null, last_error = null, updated_at = $2 WHERE id = $3 AND version = $4 AND status = $5`,
+		constant.JobStatusDone,
+		time.Now(),
+		id,
+		version,
+		constant.JobStatusProcessing,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (j *jobRepository) MarkFailed(ctx context.Context, id uuid.UUID, version int, lastError string, runAt time.Time) error {
+	_, err := j.db.Exec(
+		ctx,
+		`UPDATE job_queue SET status = $1, locked_until = null, last_error = $2, run_at = $3, updated_at = $4 WHERE id = $5 AND version = $6 AND status = $7`,
+		constant.JobStatusPending,
+		lastError,
+		runAt,
+		time.Now(),
+		id,
+		version,
+		constant.JobStatusProcessing,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (j *jobRepository) MarkDead(ctx context.Context, id uuid.UUID, version int, lastError string) error {
+	_, err := j.db.Exec(
+		ctx,
+		`UPDATE job_queue SET status = $1, locked_until = null, last_error = $2, updated_at = $3 WHERE id = $4 AND version = $5 AND status = $6`,
+		constant.JobStatusDead,
+		lastError,
+		time.Now(),
+		id,
+		version,
+		constant.JobStatusProcessing,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func NewJobRepository(db *pgxpool.Pool) IJobRepository {
+	return &jobRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"ai-notetaking-be/internal/entity"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// recordingQueryer keeps the arguments of every Exec.
+type recordingQueryer struct {
+	execArgs [][]any
+}
+
+func (r *recordingQueryer) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	r.execArgs = append(r.execArgs, arguments)
+	return pgconn.CommandTag{}, nil
+}
+
+func (r *recordingQueryer) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return nil, fmt.Errorf("unexpected query")
+}
+
+func (r *recordingQueryer) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return nil
+}
+
+func TestEnqueueBatchSplitsStatements(t *testing.T) {
+	tests := []struct {
+		jobs           int
+		wantStatements int
+	}{
+		{jobs: 0, wantStatements: 0},
+		{jobs: 1, wantStatements: 1},
+		{jobs: jobEnqueueBatchSize, wantStatements: 1},
+		{jobs: 10*jobEnqueueBatchSize + 1, wantStatements: 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.jobs), func(t *testing.T) {
+			jobs := make([]*entity.Job, 0)
+			for i := 0; i < tt.jobs; i++ {
+				jobs = append(jobs, &entity.Job{Id: uuid.New(), DedupKey: fmt.Sprint(i), RunAt: time.Now()})
+			}
+
+			db := &recordingQueryer{}
+			err := (&jobRepository{db: db}).EnqueueBatch(context.Background(), jobs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(db.execArgs) != tt.wantStatements {
+				t.Fatalf("got %d statements, want %d", len(db.execArgs), tt.wantStatements)
+			}
+
+			enqueued := 0
+			for _, args := range db.execArgs {
+				if len(args) > 65535 {
+					t.Fatalf("statement has %d parameters", len(args))
+				}
+				enqueued += (len(args) - 1) / 7
+			}
+			if enqueued != tt.jobs {
+				t.Fatalf("enqueued %d jobs, want %d", enqueued, tt.jobs)
+			}
+		})
+	}
+}
@@ -2,8 +2,11 @@ package repository
 
 import (
 	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
 	"ai-notetaking-be/pkg/database"
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,33 +14,88 @@ import (
 	"github.com/pgvector/pgvector-go"
 )
 
+// similarityCandidateLimit is how many nearest chunks are fetched through the
+// HNSW index before keeping the best chunk of each note.
+const similarityCandidateLimit = 100
+
+// filteredScanQuery makes the HNSW index keep scanning until a filtered query
+// has its rows, rather than filtering only the ef_search nearest chunks of
+// every owner. pgvector before 0.8 cannot, so there a scoped query ($1) skips
+// the index for an exact scan and any other widens ef_search to its maximum.
+const filteredScanQuery = `
+SELECT CASE
+	WHEN string_to_array(extversion, '.')::int[] >= '{0,8}' THEN set_config('hnsw.iterative_scan', 'relaxed_order', true)
+	WHEN $1 THEN set_config('enable_indexscan', 'off', true)
+	ELSE set_config('hnsw.ef_search', '1000', true)
+END
+FROM pg_extension WHERE extname = 'vector'
+`
+
+// RetrievalScope narrows chat retrieval to notes in one of NotebookIds that
+// carry all of TagIds and are listed in NoteIds. Nil fields do not filter.
+type RetrievalScope struct {
+	NotebookIds []uuid.UUID
+	TagIds      []uuid.UUID
+	NoteIds     []uuid.UUID
+}
+
 type INoteEmbeddingRepository interface {
 	UsingTx(ctx context.Context, tx database.DatabaseQueryer) INoteEmbeddingRepository
 	Create(ctx context.Context, noteEmbedding *entity.NoteEmbedding) error
-	DeleteByNoteId(ctx context.Context, noteId uuid.UUID) error
-	SemanticSearch(ctx context.Context, embeddingValues []float32) ([]*entity.NoteEmbedding, error)
-	DeleteByNotebookId(ctx context.Context, notebookId uuid.UUID) error
-	SearchSimilarity(ctx context.Context, embeddingValues []float32) ([]*entity.NoteEmbedding, error)
+	DeleteByNoteId(ctx context.Context, noteId uuid.UUID, deletedAt time.Time) error
+	DeleteContentByNoteId(ctx context.Context, noteId uuid.UUID, deletedAt time.Time) error
+	DeleteByAttachmentId(ctx context.Context, attachmentId uuid.UUID, deletedAt time.Time) error
+	HardDeleteByAttachmentId(ctx context.Context, attachmentId uuid.UUID) error
+	SemanticSearch(ctx context.Context, embeddingValues []float32, filter NoteSearchFilter, limit int) ([]*entity.NoteEmbedding, error)
+	DeleteByNotebookIds(ctx context.Context, notebookIds []uuid.UUID, deletedAt time.Time) error
+	SearchSimilarity(ctx context.Context, embeddingValues []float32, scope RetrievalScope, minScore float64, limit int) ([]*entity.NoteEmbedding, error)
+	RestoreByNoteId(ctx context.Context, noteId uuid.UUID, deletedAt time.Time) error
+	Dimension(ctx context.Context) (int, error)
+	RestoreByNotebookIds(ctx context.Context, notebookIds []uuid.UUID, deletedAt time.Time) error
+	PurgeDeletedBefore(ctx context.Context, deletedBefore time.Time) error
+	HardDeleteByNoteIds(ctx context.Context, noteIds []uuid.UUID) error
 }
 
 type noteEmbeddingRepository struct {
 	db database.DatabaseQueryer
+	// halfvecType is the half precision type the HNSW index is built on, the
+	// query has to cast to the same type for the index to be used.
+	halfvecType string
 }
 
 func (n *noteEmbeddingRepository) UsingTx(ctx context.Context, tx database.DatabaseQueryer) INoteEmbeddingRepository {
 	return &noteEmbeddingRepository{
-		db: tx,
+		db:          tx,
+		halfvecType: n.halfvecType,
 	}
 }
 
+// Dimension returns the number of dimensions of the embedding_value column.
+func (n *noteEmbeddingRepository) Dimension(ctx context.Context) (int, error) {
+	var dimension int
+	err := n.db.QueryRow(
+		ctx,
+		`SELECT atttypmod FROM pg_attribute WHERE attrelid = 'note_embedding'::regclass AND attname = 'embedding_value'`,
+	).Scan(&dimension)
+	if err != nil {
+		return 0, err
+	}
+
+	return dimension, nil
+}
+
 func (n *noteEmbeddingRepository) Create(ctx context.Context, noteEmbedding *entity.NoteEmbedding) error {
 	_, err := n.db.Exec(
 		ctx,
-		`INSERT INTO note_embedding (id, document, embedding_value, note_id, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		`INSERT INTO note_embedding (id, document, embedding_value, note_id, attachment_id, chunk_index, start_offset, end_offset, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
 		noteEmbedding.Id,
 		noteEmbedding.Document,
 		pgvector.NewVector(noteEmbedding.EmbeddingValue),
 		noteEmbedding.NoteId,
+		noteEmbedding.AttachmentId,
+		noteEmbedding.ChunkIndex,
+		noteEmbedding.StartOffset,
+		noteEmbedding.EndOffset,
 		noteEmbedding.CreatedAt,
 		noteEmbedding.UpdatedAt,
 		noteEmbedding.DeletedAt,
@@ -50,11 +108,11 @@ func (n *noteEmbeddingRepository) Create(ctx context.Context, noteEmbedding *ent
 	return nil
 }
 
-func (n *noteEmbeddingRepository) DeleteByNoteId(ctx context.Context, noteId uuid.UUID) error {
+func (n *noteEmbeddingRepository) DeleteByNoteId(ctx context.Context, noteId uuid.UUID, deletedAt time.Time) error {
 	_, err := n.db.Exec(
 		ctx,
-		`UPDATE note_embedding SET deleted_at = $1, is_deleted = true WHERE note_id = $2`,
-		time.Now(),
+		`UPDATE note_embedding SET deleted_at = $1, is_deleted = true WHERE note_id = $2 AND is_deleted = false`,
+		deletedAt,
 		noteId,
 	)
 	if err != nil {
@@ -64,66 +122,296 @@ func (n *noteEmbeddingRepository) DeleteByNoteId(ctx context.Context, noteId uui
 	return nil
 }
 
-func (n *noteEmbeddingRepository) SemanticSearch(ctx context.Context, embeddingValues []float32) ([]*entity.NoteEmbedding, error) {
-	rows, err := n.db.Query(
+// DeleteContentByNoteId removes the embeddings of the note's own content and
+// keeps those of its attachments.
+func (n *noteEmbeddingRepository) DeleteContentByNoteId(ctx context.Context, noteId uuid.UUID, deletedAt time.Time) error {
+	_, err := n.db.Exec(
 		ctx,
-		`SELECT id, note_id FROM note_embedding WHERE is_deleted = false ORDER BY 1 - (embedding_value <=> $1) DESC LIMIT 5`,
-		pgvector.NewVector(embeddingValues),
+		`UPDATE note_embedding SET deleted_at = $1, is_deleted = true WHERE note_id = $2 AND attachment_id IS NULL AND is_deleted = false`,
+		deletedAt,
+		noteId,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	res := make([]*entity.NoteEmbedding, 0)
-	for rows.Next() {
-		var noteEmbedding entity.NoteEmbedding
-		err = rows.Scan(
-			&noteEmbedding.Id,
-			&noteEmbedding.NoteId,
-		)
-		if err != nil {
-			return nil, err
-		}
+	return nil
+}
 
-		res = append(res, &noteEmbedding)
+func (n *noteEmbeddingRepository) DeleteByAttachmentId(ctx context.Context, attachmentId uuid.UUID, deletedAt time.Time) error {
+	_, err := n.db.Exec(
+		ctx,
+		`UPDATE note_embedding SET deleted_at = $1, is_deleted = true WHERE attachment_id = $2 AND is_deleted = false`,
+		deletedAt,
+		attachmentId,
+	)
+	if err != nil {
+		return err
 	}
 
-	return res, nil
+	return nil
 }
 
-func (n *noteEmbeddingRepository) SearchSimilarity(ctx context.Context, embeddingValues []float32) ([]*entity.NoteEmbedding, error) {
-	rows, err := n.db.Query(
+func (n *noteEmbeddingRepository) HardDeleteByAttachmentId(ctx context.Context, attachmentId uuid.UUID) error {
+	_, err := n.db.Exec(
 		ctx,
-		`SELECT id, document FROM note_embedding WHERE is_deleted = false ORDER BY 1 - (embedding_value <=> $1) DESC LIMIT 5`,
-		pgvector.NewVector(embeddingValues),
+		`DELETE FROM note_embedding WHERE attachment_id = $1`,
+		attachmentId,
 	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// withFilteredScan runs search with filteredScanQuery applied in a
+// transaction of its own, or a savepoint when n is already in one. Nothing is
+// written, so it is rolled back to reset the settings.
+func (n *noteEmbeddingRepository) withFilteredScan(ctx context.Context, scoped bool, search func(db database.DatabaseQueryer) error) error {
+	beginner, ok := n.db.(database.DatabaseBeginner)
+	if !ok {
+		return fmt.Errorf("note embedding search needs a database that can begin a transaction")
+	}
+
+	tx, err := beginner.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, filteredScanQuery, scoped)
+	if err != nil {
+		return err
+	}
+
+	return search(tx)
+}
+
+func (n *noteEmbeddingRepository) SemanticSearch(ctx context.Context, embeddingValues []float32, filter NoteSearchFilter, limit int) ([]*entity.NoteEmbedding, error) {
+	candidateLimit := similarityCandidateLimit
+	if limit*4 > candidateLimit {
+		candidateLimit = limit * 4
+	}
+
+	res := make([]*entity.NoteEmbedding, 0)
+	scoped := filter.NotebookId != nil || len(filter.TagIds) > 0
+	err := n.withFilteredScan(ctx, scoped, func(db database.DatabaseQueryer) error {
+		rows, err := db.Query(
+			ctx,
+			`
+			SELECT id, note_id, attachment_id, chunk_index, start_offset, end_offset, 1 - distance AS score FROM (
+				SELECT DISTINCT ON (note_id) id, note_id, attachment_id, chunk_index, start_offset, end_offset, distance FROM (
+					SELECT ne.id, ne.note_id, ne.attachment_id, ne.chunk_index, ne.start_offset, ne.end_offset, ne.embedding_value::`+n.halfvecType+` <=> $1::`+n.halfvecType+` AS distance
+					FROM note_embedding ne
+					JOIN note n ON n.id = ne.note_id
+					WHERE ne.is_deleted = false AND n.is_deleted = false AND n.owner_id = $2
+						AND ($4::uuid IS NULL OR n.notebook_id = $4)
+						AND `+noteHasAllTagsCondition+`
+					ORDER BY distance ASC
+					LIMIT $3
+				) candidate
+				ORDER BY note_id, distance ASC
+			) best_chunk
+			ORDER BY distance ASC
+			LIMIT $6
+			`,
+			pgvector.NewVector(embeddingValues),
+			serverutils.UserIdFromContext(ctx),
+			candidateLimit,
+			filter.NotebookId,
+			filter.TagIds,
+			limit,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var noteEmbedding entity.NoteEmbedding
+			err = rows.Scan(
+				&noteEmbedding.Id,
+				&noteEmbedding.NoteId,
+				&noteEmbedding.AttachmentId,
+				&noteEmbedding.ChunkIndex,
+				&noteEmbedding.StartOffset,
+				&noteEmbedding.EndOffset,
+				&noteEmbedding.Score,
+			)
+			if err != nil {
+				return err
+			}
+
+			res = append(res, &noteEmbedding)
+		}
+
+		return rows.Err()
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return res, nil
+}
+
+// SearchSimilarity returns up to limit chunks scoring at least minScore,
+// best first, with their embedding values. Several chunks of the same note
+// may be returned.
+func (n *noteEmbeddingRepository) SearchSimilarity(ctx context.Context, embeddingValues []float32, scope RetrievalScope, minScore float64, limit int) ([]*entity.NoteEmbedding, error) {
 	res := make([]*entity.NoteEmbedding, 0)
-	for rows.Next() {
-		var noteEmbedding entity.NoteEmbedding
-		err = rows.Scan(
-			&noteEmbedding.Id,
-			&noteEmbedding.Document,
+	scoped := scope.NotebookIds != nil || len(scope.TagIds) > 0 || scope.NoteIds != nil
+	err := n.withFilteredScan(ctx, scoped, func(db database.DatabaseQueryer) error {
+		rows, err := db.Query(
+			ctx,
+			`
+			SELECT id, note_id, attachment_id, document, embedding_value, chunk_index, start_offset, end_offset, 1 - distance AS score FROM (
+				SELECT ne.id, ne.note_id, ne.attachment_id, ne.document, ne.embedding_value, ne.chunk_index, ne.start_offset, ne.end_offset, ne.embedding_value::`+n.halfvecType+` <=> $1::`+n.halfvecType+` AS distance
+				FROM note_embedding ne
+				JOIN note n ON n.id = ne.note_id
+				WHERE ne.is_deleted = false AND n.is_deleted = false AND n.owner_id = $2
+					AND ($4::uuid[] IS NULL OR n.notebook_id = ANY($4))
+					AND `+noteHasAllTagsCondition+`
+					AND ($6::uuid[] IS NULL OR n.id = ANY($6))
+				ORDER BY distance ASC
+				LIMIT $3
+			) candidate
+			WHERE 1 - distance >= $7
+			ORDER BY distance ASC
+			`,
+			pgvector.NewVector(embeddingValues),
+			serverutils.UserIdFromContext(ctx),
+			limit,
+			scope.NotebookIds,
+			scope.TagIds,
+			scope.NoteIds,
+			minScore,
 		)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var noteEmbedding entity.NoteEmbedding
+			var embeddingValue pgvector.Vector
+			err = rows.Scan(
+				&noteEmbedding.Id,
+				&noteEmbedding.NoteId,
+				&noteEmbedding.AttachmentId,
+				&noteEmbedding.Document,
+				&embeddingValue,
+				&noteEmbedding.ChunkIndex,
+				&noteEmbedding.StartOffset,
+				&noteEmbedding.EndOffset,
+				&noteEmbedding.Score,
+			)
+			if err != nil {
+				return err
+			}
+			noteEmbedding.EmbeddingValue = embeddingValue.Slice()
+
+			res = append(res, &noteEmbedding)
 		}
 
-		res = append(res, &noteEmbedding)
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return res, nil
 }
 
-func (n *noteEmbeddingRepository) DeleteByNotebookId(ctx context.Context, notebookId uuid.UUID) error {
+func (n *noteEmbeddingRepository) DeleteByNotebookIds(ctx context.Context, notebookIds []uuid.UUID, deletedAt time.Time) error {
+	if len(notebookIds) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range notebookIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	_, err := n.db.Exec(
+		ctx,
+		fmt.Sprintf(`UPDATE note_embedding SET is_deleted = true, deleted_at = $1 WHERE is_deleted = false AND note_id IN (SELECT id FROM note WHERE notebook_id IN (%s) AND is_deleted = false)`, idSqlFormat),
+		deletedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RestoreByNoteId brings back the embeddings that were removed when the
+// note was trashed. Older generations replaced by re-embedding stay deleted.
+func (n *noteEmbeddingRepository) RestoreByNoteId(ctx context.Context, noteId uuid.UUID, deletedAt time.Time) error {
+	_, err := n.db.Exec(
+		ctx,
+		`UPDATE note_embedding SET deleted_at = null, is_deleted = false WHERE note_id = $1 AND deleted_at = $2 AND is_deleted = true`,
+		noteId,
+		deletedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (n *noteEmbeddingRepository) RestoreByNotebookIds(ctx context.Context, notebookIds []uuid.UUID, deletedAt time.Time) error {
+	if len(notebookIds) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range notebookIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	_, err := n.db.Exec(
+		ctx,
+		fmt.Sprintf(`UPDATE note_embedding SET deleted_at = null, is_deleted = false WHERE deleted_at = $1 AND is_deleted = true AND note_id IN (SELECT id FROM note WHERE notebook_id IN (%s))`, idSqlFormat),
+		deletedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (n *noteEmbeddingRepository) PurgeDeletedBefore(ctx context.Context, deletedBefore time.Time) error {
+	_, err := n.db.Exec(
+		ctx,
+		`DELETE FROM note_embedding WHERE is_deleted = true AND deleted_at < $1`,
+		deletedBefore,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (n *noteEmbeddingRepository) HardDeleteByNoteIds(ctx context.Context, noteIds []uuid.UUID) error {
+	if len(noteIds) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range noteIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
 	_, err := n.db.Exec(
 		ctx,
-		`UPDATE note_embedding SET is_deleted = true, deleted_at = $1 WHERE note_id IN (SELECT id FROM note WHERE notebook_id = $2 AND is_deleted = false)`,
-		time.Now(),
-		notebookId,
+		fmt.Sprintf(`DELETE FROM note_embedding WHERE note_id IN (%s)`, idSqlFormat),
 	)
 	if err != nil {
 		return err
@@ -132,8 +420,11 @@ func (n *noteEmbeddingRepository) DeleteByNotebookId(ctx context.Context, notebo
 	return nil
 }
 
-func NewNoteEmbeddingRepository(db *pgxpool.Pool) INoteEmbeddingRepository {
+// NewNoteEmbeddingRepository expects embedding_value to have dimension
+// dimensions, see Dimension.
+func NewNoteEmbeddingRepository(db *pgxpool.Pool, dimension int) INoteEmbeddingRepository {
 	return &noteEmbeddingRepository{
-		db: db,
+		db:          db,
+		halfvecType: fmt.Sprintf("halfvec(%d)", dimension),
 	}
 }
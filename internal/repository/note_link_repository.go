@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/pkg/database"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type INoteLinkRepository interface {
+	UsingTx(ctx context.Context, tx database.DatabaseQueryer) INoteLinkRepository
+	ReplaceBySourceId(ctx context.Context, sourceNoteId uuid.UUID, targetTitles []string) error
+	ResolveTitle(ctx context.Context, targetNoteId uuid.UUID, title string) error
+	RetargetTitle(ctx context.Context, targetNoteId uuid.UUID, oldTitle string, title string) error
+	GetByTargetId(ctx context.Context, targetNoteId uuid.UUID) ([]*entity.NoteLink, error)
+	GetBetweenNoteIds(ctx context.Context, noteIds []uuid.UUID) ([]*entity.NoteLink, error)
+	DeleteByNoteIds(ctx context.Context, noteIds []uuid.UUID) error
+}
+
+type noteLinkRepository struct {
+	db database.DatabaseQueryer
+}
+
+func (n *noteLinkRepository) UsingTx(ctx context.Context, tx database.DatabaseQueryer) INoteLinkRepository {
+	return &noteLinkRepository{
+		db: tx,
+	}
+}
+
+// ReplaceBySourceId makes targetTitles the links of a note. Links that are
+// kept also keep their resolved target, new ones resolve to the oldest live
+// note with that title.
+func (n *noteLinkRepository) ReplaceBySourceId(ctx context.Context, sourceNoteId uuid.UUID, targetTitles []string) error {
+	lowerTitles := make([]string, 0)
+	for _, title := range targetTitles {
+		lowerTitles = append(lowerTitles, strings.ToLower(title))
+	}
+
+	_, err := n.db.Exec(
+		ctx,
+		`DELETE FROM note_link WHERE source_note_id = $1 AND NOT (lower(target_title) = ANY($2))`,
+		sourceNoteId,
+		lowerTitles,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, title := range targetTitles {
+		_, err = n.db.Exec(
+			ctx,
+			`
+			INSERT INTO note_link (id, source_note_id, target_title, target_note_id, owner_id, created_at)
+			VALUES ($1, $2, $3, (
+				SELECT id FROM note
+				WHERE owner_id = $4 AND is_deleted = false AND lower(title) = lower($3)
+				ORDER BY created_at ASC
+				LIMIT 1
+			), $4, $5)
+			ON CONFLICT (source_note_id, lower(target_title)) DO NOTHING
+			`,
+			uuid.New(),
+			sourceNoteId,
+			title,
+			serverutils.UserIdFromContext(ctx),
+			time.Now(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ResolveTitle points the dangling links written as title at targetNoteId.
+func (n *noteLinkRepository) ResolveTitle(ctx context.Context, targetNoteId uuid.UUID, title string) error {
+	_, err := n.db.Exec(
+		ctx,
+		`UPDATE note_link SET target_note_id = $1 WHERE owner_id = $2 AND target_note_id IS NULL AND lower(target_title) = lower($3)`,
+		targetNoteId,
+		serverutils.UserIdFromContext(ctx),
+		title,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RetargetTitle renames the oldTitle links of live notes pointing at
+// targetNoteId. A source that already links to the new title keeps its old
+// row, which goes away once its content is re-synced.
+func (n *noteLinkRepository) RetargetTitle(ctx context.Context, targetNoteId uuid.UUID, oldTitle string, title string) error {
+	_, err := n.db.Exec(
+		ctx,
+		`
+		UPDATE note_link l SET target_title = $2
+		WHERE l.target_note_id = $1 AND l.owner_id = $3 AND lower(l.target_title) = lower($4)
+			AND l.source_note_id IN (SELECT id FROM note WHERE is_deleted = false)
+			AND NOT EXISTS (
+				SELECT 1 FROM note_link o
+				WHERE o.source_note_id = l.source_note_id AND lower(o.target_title) = lower($2)
+			)
+		`,
+		targetNoteId,
+		title,
+		serverutils.UserIdFromContext(ctx),
+		oldTitle,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetByTargetId returns the links from live notes to targetNoteId.
+func (n *noteLinkRepository) GetByTargetId(ctx context.Context, targetNoteId uuid.UUID) ([]*entity.NoteLink, error) {
+	rows, err := n.db.Query(
+		ctx,
+		`
+		SELECT l.id, l.source_note_id, l.target_title, l.target_note_id, l.owner_id, l.created_at
+		FROM note_link l
+		JOIN note n ON n.id = l.source_note_id
+		WHERE l.target_note_id = $1 AND l.owner_id = $2 AND n.is_deleted = false
+		ORDER BY coalesce(n.updated_at, n.created_at) DESC
+		`,
+		targetNoteId,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanNoteLinks(rows)
+}
+
+// GetBetweenNoteIds returns the resolved links whose source and target are
+// both in noteIds.
+func (n *noteLinkRepository) GetBetweenNoteIds(ctx context.Context, noteIds []uuid.UUID) ([]*entity.NoteLink, error) {
+	if len(noteIds) == 0 {
+		return make([]*entity.NoteLink, 0), nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range noteIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	rows, err := n.db.Query(
+		ctx,
+		fmt.Sprintf(`
+		SELECT id, source_note_id, target_title, target_note_id, owner_id, created_at
+		FROM note_link
+		WHERE source_note_id IN (%s) AND target_note_id IN (%s) AND owner_id = $1
+		`, idSqlFormat, idSqlFormat),
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanNoteLinks(rows)
+}
+
+// DeleteByNoteIds drops the links written in noteIds and leaves the links to
+// them dangling, before the notes are purged.
+func (n *noteLinkRepository) DeleteByNoteIds(ctx context.Context, noteIds []uuid.UUID) error {
+	if len(noteIds) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range noteIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	_, err := n.db.Exec(
+		ctx,
+		fmt.Sprintf(`DELETE FROM note_link WHERE source_note_id IN (%s)`, idSqlFormat),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.db.Exec(
+		ctx,
+		fmt.Sprintf(`UPDATE note_link SET target_note_id = null WHERE target_note_id IN (%s)`, idSqlFormat),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func scanNoteLinks(rows pgx.Rows) ([]*entity.NoteLink, error) {
+	result := make([]*entity.NoteLink, 0)
+	for rows.Next() {
+		var noteLink entity.NoteLink
+		err := rows.Scan(
+			&noteLink.Id,
+			&noteLink.SourceNoteId,
+			&noteLink.TargetTitle,
+			&noteLink.TargetNoteId,
+			&noteLink.OwnerId,
+			&noteLink.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &noteLink)
+	}
+
+	return result, nil
+}
+
+func NewNoteLinkRepository(db *pgxpool.Pool) INoteLinkRepository {
+	return &noteLinkRepository{
+		db: db,
+	}
+}
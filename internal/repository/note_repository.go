@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"ai-notetaking-be/internal/constant"
 	"ai-notetaking-be/internal/entity"
 	"ai-notetaking-be/internal/pkg/serverutils"
 	"ai-notetaking-be/pkg/database"
@@ -15,15 +16,42 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// noteHasAllTagsCondition filters the note aliased n on the tag ids bound to
+// $5, which is skipped when they are NULL.
+const noteHasAllTagsCondition = `($5::uuid[] IS NULL OR (SELECT count(*) FROM note_tag nt WHERE nt.note_id = n.id AND nt.tag_id = ANY($5)) = cardinality($5::uuid[]))`
+
+// noteExcerptQueryLength is how much content is loaded for note summaries.
+// It leaves room for the whitespace collapsed when building the excerpt.
+const noteExcerptQueryLength = 2 * constant.NoteExcerptLength
+
+// NoteSearchFilter narrows searches to a notebook and to notes carrying all
+// of TagIds. Zero values do not filter.
+type NoteSearchFilter struct {
+	NotebookId *uuid.UUID
+	TagIds     []uuid.UUID
+}
+
 type INoteRepository interface {
 	UsingTx(ctx context.Context, tx database.DatabaseQueryer) INoteRepository
 	Create(ctx context.Context, note *entity.Note) error
 	GetById(ctx context.Context, id uuid.UUID) (*entity.Note, error)
+	LockById(ctx context.Context, id uuid.UUID) error
 	GetByNotebookIds(ctx context.Context, ids []uuid.UUID) ([]*entity.Note, error)
 	Update(ctx context.Context, note *entity.Note) error
-	Delete(ctx context.Context, id uuid.UUID) error
-	DeleteByNotebookId(ctx context.Context, notebookId uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID, deletedAt time.Time) error
+	DeleteByNotebookIds(ctx context.Context, notebookIds []uuid.UUID, deletedAt time.Time) error
+	CountByNotebookIds(ctx context.Context, notebookIds []uuid.UUID) (map[uuid.UUID]int, error)
+	GetSummariesByNotebookIds(ctx context.Context, notebookIds []uuid.UUID, limitPerNotebook int) ([]*entity.NoteSummary, error)
+	GetSummariesByNotebookId(ctx context.Context, notebookId uuid.UUID, limit int, offset int) ([]*entity.NoteSummary, error)
 	GetByIds(ctx context.Context, ids []uuid.UUID) ([]*entity.Note, error)
+	GetByIdsForUpdate(ctx context.Context, ids []uuid.UUID) ([]*entity.Note, error)
+	KeywordSearch(ctx context.Context, query string, filter NoteSearchFilter, limit int) ([]*entity.Note, error)
+	GetDeleted(ctx context.Context) ([]*entity.Note, error)
+	GetDeletedById(ctx context.Context, id uuid.UUID) (*entity.Note, error)
+	Restore(ctx context.Context, id uuid.UUID) error
+	RestoreByNotebookIds(ctx context.Context, notebookIds []uuid.UUID, deletedAt time.Time) error
+	GetPurgeableIds(ctx context.Context, deletedBefore time.Time) ([]uuid.UUID, error)
+	HardDeleteByIds(ctx context.Context, ids []uuid.UUID) error
 }
 
 type noteRepository struct {
@@ -39,11 +67,12 @@ func (n *noteRepository) UsingTx(ctx context.Context, tx database.DatabaseQuerye
 func (n *noteRepository) Create(ctx context.Context, note *entity.Note) error {
 	_, err := n.db.Exec(
 		ctx,
-		`INSERT INTO note (id, title, content, notebook_id, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		`INSERT INTO note (id, title, content, notebook_id, owner_id, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
 		note.Id,
 		note.Title,
 		note.Content,
 		note.NotebookId,
+		note.OwnerId,
 		note.CreatedAt,
 		note.UpdatedAt,
 		note.DeletedAt,
@@ -59,8 +88,9 @@ func (n *noteRepository) Create(ctx context.Context, note *entity.Note) error {
 func (n *noteRepository) GetById(ctx context.Context, id uuid.UUID) (*entity.Note, error) {
 	row := n.db.QueryRow(
 		ctx,
-		`SELECT id, title, content, notebook_id, created_at, updated_at FROM note WHERE id = $1 AND is_deleted = false`,
+		`SELECT id, title, content, notebook_id, owner_id, created_at, updated_at FROM note WHERE id = $1 AND is_deleted = false AND owner_id = $2`,
 		id,
+		serverutils.UserIdFromContext(ctx),
 	)
 
 	var note entity.Note
@@ -69,6 +99,7 @@ func (n *noteRepository) GetById(ctx context.Context, id uuid.UUID) (*entity.Not
 		&note.Title,
 		&note.Content,
 		&note.NotebookId,
+		&note.OwnerId,
 		&note.CreatedAt,
 		&note.UpdatedAt,
 	)
@@ -82,6 +113,26 @@ func (n *noteRepository) GetById(ctx context.Context, id uuid.UUID) (*entity.Not
 	return &note, nil
 }
 
+// LockById locks the note row until the end of the transaction, so that
+// writes depending on the note's current state are serialized.
+func (n *noteRepository) LockById(ctx context.Context, id uuid.UUID) error {
+	var lockedId uuid.UUID
+	err := n.db.QueryRow(
+		ctx,
+		`SELECT id FROM note WHERE id = $1 AND owner_id = $2 FOR UPDATE`,
+		id,
+		serverutils.UserIdFromContext(ctx),
+	).Scan(&lockedId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return serverutils.ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (n *noteRepository) Update(ctx context.Context, note *entity.Note) error {
 	_, err := n.db.Exec(
 		ctx,
@@ -91,13 +142,14 @@ func (n *noteRepository) Update(ctx context.Context, note *entity.Note) error {
 			content = $2,
 			notebook_id = $3,
 			updated_at = $4
-		WHERE id = $5
+		WHERE id = $5 AND owner_id = $6
 		`,
 		note.Title,
 		note.Content,
 		note.NotebookId,
 		note.UpdatedAt,
 		note.Id,
+		serverutils.UserIdFromContext(ctx),
 	)
 	if err != nil {
 		return err
@@ -106,17 +158,18 @@ func (n *noteRepository) Update(ctx context.Context, note *entity.Note) error {
 	return nil
 }
 
-func (n *noteRepository) Delete(ctx context.Context, id uuid.UUID) error {
+func (n *noteRepository) Delete(ctx context.Context, id uuid.UUID, deletedAt time.Time) error {
 	_, err := n.db.Exec(
 		ctx,
 		`
 		UPDATE note SET
 			deleted_at = $1,
 			is_deleted = true
-		WHERE id = $2
+		WHERE id = $2 AND owner_id = $3
 		`,
-		time.Now(),
+		deletedAt,
 		id,
+		serverutils.UserIdFromContext(ctx),
 	)
 	if err != nil {
 		return err
@@ -125,12 +178,22 @@ func (n *noteRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-func (n *noteRepository) DeleteByNotebookId(ctx context.Context, notebookId uuid.UUID) error {
+func (n *noteRepository) DeleteByNotebookIds(ctx context.Context, notebookIds []uuid.UUID, deletedAt time.Time) error {
+	if len(notebookIds) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range notebookIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
 	_, err := n.db.Exec(
 		ctx,
-		`UPDATE note SET deleted_at = $1, is_deleted = true WHERE notebook_id = $2`,
-		time.Now(),
-		notebookId,
+		fmt.Sprintf(`UPDATE note SET deleted_at = $1, is_deleted = true WHERE notebook_id IN (%s) AND owner_id = $2 AND is_deleted = false`, idSqlFormat),
+		deletedAt,
+		serverutils.UserIdFromContext(ctx),
 	)
 	if err != nil {
 		return err
@@ -139,6 +202,41 @@ func (n *noteRepository) DeleteByNotebookId(ctx context.Context, notebookId uuid
 	return nil
 }
 
+func (n *noteRepository) CountByNotebookIds(ctx context.Context, notebookIds []uuid.UUID) (map[uuid.UUID]int, error) {
+	result := make(map[uuid.UUID]int)
+	if len(notebookIds) == 0 {
+		return result, nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range notebookIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	rows, err := n.db.Query(
+		ctx,
+		fmt.Sprintf(`SELECT notebook_id, count(*) FROM note WHERE notebook_id IN (%s) AND is_deleted = false AND owner_id = $1 GROUP BY notebook_id`, idSqlFormat),
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var notebookId uuid.UUID
+		var count int
+		err = rows.Scan(&notebookId, &count)
+		if err != nil {
+			return nil, err
+		}
+
+		result[notebookId] = count
+	}
+
+	return result, nil
+}
+
 func (n *noteRepository) GetByNotebookIds(ctx context.Context, ids []uuid.UUID) ([]*entity.Note, error) {
 	if len(ids) == 0 {
 		return make([]*entity.Note, 0), nil
@@ -152,7 +250,8 @@ func (n *noteRepository) GetByNotebookIds(ctx context.Context, ids []uuid.UUID)
 
 	rows, err := n.db.Query(
 		ctx,
-		fmt.Sprintf(`SELECT id, title, content, notebook_id, created_at, updated_at FROM note WHERE notebook_id IN (%s) AND is_deleted = false`, idSqlFormat),
+		fmt.Sprintf(`SELECT id, title, content, notebook_id, owner_id, created_at, updated_at FROM note WHERE notebook_id IN (%s) AND is_deleted = false AND owner_id = $1`, idSqlFormat),
+		serverutils.UserIdFromContext(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -167,6 +266,7 @@ func (n *noteRepository) GetByNotebookIds(ctx context.Context, ids []uuid.UUID)
 			&note.Title,
 			&note.Content,
 			&note.NotebookId,
+			&note.OwnerId,
 			&note.CreatedAt,
 			&note.UpdatedAt,
 		)
@@ -180,7 +280,100 @@ func (n *noteRepository) GetByNotebookIds(ctx context.Context, ids []uuid.UUID)
 	return result, nil
 }
 
+// GetSummariesByNotebookIds returns up to limitPerNotebook of the most
+// recently updated notes of each notebook.
+func (n *noteRepository) GetSummariesByNotebookIds(ctx context.Context, notebookIds []uuid.UUID, limitPerNotebook int) ([]*entity.NoteSummary, error) {
+	if len(notebookIds) == 0 {
+		return make([]*entity.NoteSummary, 0), nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range notebookIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	rows, err := n.db.Query(
+		ctx,
+		fmt.Sprintf(`
+		SELECT id, title, excerpt, notebook_id, created_at, updated_at FROM (
+			SELECT id, title, left(content, $2) AS excerpt, notebook_id, created_at, updated_at,
+				row_number() OVER (PARTITION BY notebook_id ORDER BY coalesce(updated_at, created_at) DESC, id) AS rank
+			FROM note
+			WHERE notebook_id IN (%s) AND is_deleted = false AND owner_id = $1
+		) ranked
+		WHERE rank <= $3
+		ORDER BY notebook_id, rank
+		`, idSqlFormat),
+		serverutils.UserIdFromContext(ctx),
+		noteExcerptQueryLength,
+		limitPerNotebook,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanNoteSummaries(rows)
+}
+
+func (n *noteRepository) GetSummariesByNotebookId(ctx context.Context, notebookId uuid.UUID, limit int, offset int) ([]*entity.NoteSummary, error) {
+	rows, err := n.db.Query(
+		ctx,
+		`
+		SELECT id, title, left(content, $3), notebook_id, created_at, updated_at
+		FROM note
+		WHERE notebook_id = $1 AND is_deleted = false AND owner_id = $2
+		ORDER BY coalesce(updated_at, created_at) DESC, id
+		LIMIT $4 OFFSET $5
+		`,
+		notebookId,
+		serverutils.UserIdFromContext(ctx),
+		noteExcerptQueryLength,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanNoteSummaries(rows)
+}
+
+func scanNoteSummaries(rows pgx.Rows) ([]*entity.NoteSummary, error) {
+	result := make([]*entity.NoteSummary, 0)
+	for rows.Next() {
+		var noteSummary entity.NoteSummary
+
+		err := rows.Scan(
+			&noteSummary.Id,
+			&noteSummary.Title,
+			&noteSummary.Excerpt,
+			&noteSummary.NotebookId,
+			&noteSummary.CreatedAt,
+			&noteSummary.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &noteSummary)
+	}
+
+	return result, nil
+}
+
 func (n *noteRepository) GetByIds(ctx context.Context, ids []uuid.UUID) ([]*entity.Note, error) {
+	return n.getByIds(ctx, ids, "")
+}
+
+// GetByIdsForUpdate is GetByIds locking the returned rows until the end of the
+// transaction. Rows are locked in id order so that concurrent callers do not
+// deadlock on each other.
+func (n *noteRepository) GetByIdsForUpdate(ctx context.Context, ids []uuid.UUID) ([]*entity.Note, error) {
+	return n.getByIds(ctx, ids, "ORDER BY id FOR UPDATE")
+}
+
+func (n *noteRepository) getByIds(ctx context.Context, ids []uuid.UUID, suffix string) ([]*entity.Note, error) {
 	if len(ids) == 0 {
 		return make([]*entity.Note, 0), nil
 	}
@@ -193,7 +386,8 @@ func (n *noteRepository) GetByIds(ctx context.Context, ids []uuid.UUID) ([]*enti
 
 	rows, err := n.db.Query(
 		ctx,
-		fmt.Sprintf(`SELECT id, title, content, notebook_id, created_at, updated_at FROM note WHERE id IN (%s) AND is_deleted = false`, idSqlFormat),
+		fmt.Sprintf(`SELECT id, title, content, notebook_id, owner_id, created_at, updated_at FROM note WHERE id IN (%s) AND is_deleted = false AND owner_id = $1 %s`, idSqlFormat, suffix),
+		serverutils.UserIdFromContext(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -208,6 +402,7 @@ func (n *noteRepository) GetByIds(ctx context.Context, ids []uuid.UUID) ([]*enti
 			&note.Title,
 			&note.Content,
 			&note.NotebookId,
+			&note.OwnerId,
 			&note.CreatedAt,
 			&note.UpdatedAt,
 		)
@@ -221,6 +416,213 @@ func (n *noteRepository) GetByIds(ctx context.Context, ids []uuid.UUID) ([]*enti
 	return result, nil
 }
 
+// KeywordSearch runs a full-text search over title and content, best match
+// first. The query accepts web search syntax such as quotes and "-word".
+func (n *noteRepository) KeywordSearch(ctx context.Context, query string, filter NoteSearchFilter, limit int) ([]*entity.Note, error) {
+	rows, err := n.db.Query(
+		ctx,
+		`
+		SELECT id, title, content, notebook_id, owner_id, created_at, updated_at
+		FROM note n
+		WHERE is_deleted = false
+			AND owner_id = $1
+			AND search_vector @@ websearch_to_tsquery('simple', $2)
+			AND ($3::uuid IS NULL OR notebook_id = $3)
+			AND `+noteHasAllTagsCondition+`
+		ORDER BY ts_rank_cd(search_vector, websearch_to_tsquery('simple', $2)) DESC, updated_at DESC NULLS LAST
+		LIMIT $4
+		`,
+		serverutils.UserIdFromContext(ctx),
+		query,
+		filter.NotebookId,
+		limit,
+		filter.TagIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entity.Note, 0)
+	for rows.Next() {
+		var note entity.Note
+
+		err = rows.Scan(
+			&note.Id,
+			&note.Title,
+			&note.Content,
+			&note.NotebookId,
+			&note.OwnerId,
+			&note.CreatedAt,
+			&note.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &note)
+	}
+
+	return result, nil
+}
+
+func (n *noteRepository) GetDeleted(ctx context.Context) ([]*entity.Note, error) {
+	rows, err := n.db.Query(
+		ctx,
+		`SELECT id, title, content, notebook_id, owner_id, created_at, updated_at, deleted_at, is_deleted FROM note WHERE is_deleted = true AND owner_id = $1 ORDER BY deleted_at DESC`,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entity.Note, 0)
+	for rows.Next() {
+		var note entity.Note
+
+		err = rows.Scan(
+			&note.Id,
+			&note.Title,
+			&note.Content,
+			&note.NotebookId,
+			&note.OwnerId,
+			&note.CreatedAt,
+			&note.UpdatedAt,
+			&note.DeletedAt,
+			&note.IsDeleted,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &note)
+	}
+
+	return result, nil
+}
+
+func (n *noteRepository) GetDeletedById(ctx context.Context, id uuid.UUID) (*entity.Note, error) {
+	row := n.db.QueryRow(
+		ctx,
+		`SELECT id, title, content, notebook_id, owner_id, created_at, updated_at, deleted_at, is_deleted FROM note WHERE id = $1 AND is_deleted = true AND owner_id = $2`,
+		id,
+		serverutils.UserIdFromContext(ctx),
+	)
+
+	var note entity.Note
+	err := row.Scan(
+		&note.Id,
+		&note.Title,
+		&note.Content,
+		&note.NotebookId,
+		&note.OwnerId,
+		&note.CreatedAt,
+		&note.UpdatedAt,
+		&note.DeletedAt,
+		&note.IsDeleted,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, serverutils.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &note, nil
+}
+
+func (n *noteRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	_, err := n.db.Exec(
+		ctx,
+		`UPDATE note SET deleted_at = null, is_deleted = false WHERE id = $1 AND owner_id = $2`,
+		id,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RestoreByNotebookIds restores the notes trashed together with their
+// notebooks, which share the notebooks' deletedAt.
+func (n *noteRepository) RestoreByNotebookIds(ctx context.Context, notebookIds []uuid.UUID, deletedAt time.Time) error {
+	if len(notebookIds) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range notebookIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	_, err := n.db.Exec(
+		ctx,
+		fmt.Sprintf(`UPDATE note SET deleted_at = null, is_deleted = false WHERE notebook_id IN (%s) AND deleted_at = $1 AND is_deleted = true AND owner_id = $2`, idSqlFormat),
+		deletedAt,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetPurgeableIds returns notes of every owner that have been in the trash
+// since before deletedBefore, including notes of such notebooks.
+func (n *noteRepository) GetPurgeableIds(ctx context.Context, deletedBefore time.Time) ([]uuid.UUID, error) {
+	rows, err := n.db.Query(
+		ctx,
+		`
+		SELECT n.id FROM note n
+		JOIN notebook nb ON nb.id = n.notebook_id
+		WHERE (n.is_deleted = true AND n.deleted_at < $1)
+			OR (nb.is_deleted = true AND nb.deleted_at < $1)
+		`,
+		deletedBefore,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, id)
+	}
+
+	return result, nil
+}
+
+func (n *noteRepository) HardDeleteByIds(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range ids {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	_, err := n.db.Exec(
+		ctx,
+		fmt.Sprintf(`DELETE FROM note WHERE id IN (%s)`, idSqlFormat),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func NewNoteRepository(db *pgxpool.Pool) INoteRepository {
 	return &noteRepository{
 		db: db,
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/pkg/database"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type INoteRevisionRepository interface {
+	UsingTx(ctx context.Context, tx database.DatabaseQueryer) INoteRevisionRepository
+	Create(ctx context.Context, noteRevision *entity.NoteRevision) error
+	GetByNoteId(ctx context.Context, noteId uuid.UUID) ([]*entity.NoteRevision, error)
+	GetById(ctx context.Context, noteId uuid.UUID, id uuid.UUID) (*entity.NoteRevision, error)
+	DeleteByNoteIds(ctx context.Context, noteIds []uuid.UUID) error
+}
+
+type noteRevisionRepository struct {
+	db database.DatabaseQueryer
+}
+
+func (n *noteRevisionRepository) UsingTx(ctx context.Context, tx database.DatabaseQueryer) INoteRevisionRepository {
+	return &noteRevisionRepository{
+		db: tx,
+	}
+}
+
+// Create stores the revision as the next number in the note's history and
+// writes the assigned number back to noteRevision.RevisionNumber.
+func (n *noteRevisionRepository) Create(ctx context.Context, noteRevision *entity.NoteRevision) error {
+	row := n.db.QueryRow(
+		ctx,
+		`
+		INSERT INTO note_revision (id, note_id, revision_number, title, content, notebook_id, owner_id, created_at)
+		SELECT $1, $2, coalesce(max(revision_number), 0) + 1, $3, $4, $5, $6, $7
+		FROM note_revision WHERE note_id = $2
+		RETURNING revision_number
+		`,
+		noteRevision.Id,
+		noteRevision.NoteId,
+		noteRevision.Title,
+		noteRevision.Content,
+		noteRevision.NotebookId,
+		noteRevision.OwnerId,
+		noteRevision.CreatedAt,
+	)
+
+	err := row.Scan(&noteRevision.RevisionNumber)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (n *noteRevisionRepository) GetByNoteId(ctx context.Context, noteId uuid.UUID) ([]*entity.NoteRevision, error) {
+	rows, err := n.db.Query(
+		ctx,
+		`SELECT id, note_id, revision_number, title, content, notebook_id, owner_id, created_at FROM note_revision WHERE note_id = $1 AND owner_id = $2 ORDER BY revision_number DESC`,
+		noteId,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*entity.NoteRevision, 0)
+	for rows.Next() {
+		var noteRevision entity.NoteRevision
+		err = rows.Scan(
+			&noteRevision.Id,
+			&noteRevision.NoteId,
+			&noteRevision.RevisionNumber,
+			&noteRevision.Title,
+			&noteRevision.Content,
+			&noteRevision.NotebookId,
+			&noteRevision.OwnerId,
+			&noteRevision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &noteRevision)
+	}
+
+	return res, nil
+}
+
+func (n *noteRevisionRepository) GetById(ctx context.Context, noteId uuid.UUID, id uuid.UUID) (*entity.NoteRevision, error) {
+	row := n.db.QueryRow(
+		ctx,
+		`SELECT id, note_id, revision_number, title, content, notebook_id, owner_id, created_at FROM note_revision WHERE id = $1 AND note_id = $2 AND owner_id = $3`,
+		id,
+		noteId,
+		serverutils.UserIdFromContext(ctx),
+	)
+
+	var noteRevision entity.NoteRevision
+	err := row.Scan(
+		&noteRevision.Id,
+		&noteRevision.NoteId,
+		&noteRevision.RevisionNumber,
+		&noteRevision.Title,
+		&noteRevision.Content,
+		&noteRevision.NotebookId,
+		&noteRevision.OwnerId,
+		&noteRevision.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, serverutils.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &noteRevision, nil
+}
+
+func (n *noteRevisionRepository) DeleteByNoteIds(ctx context.Context, noteIds []uuid.UUID) error {
+	if len(noteIds) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range noteIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	_, err := n.db.Exec(
+		ctx,
+		fmt.Sprintf(`DELETE FROM note_revision WHERE note_id IN (%s)`, idSqlFormat),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func NewNoteRevisionRepository(db *pgxpool.Pool) INoteRevisionRepository {
+	return &noteRevisionRepository{
+		db: db,
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"ai-notetaking-be/pkg/database"
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,9 +21,20 @@ type INotebookRepository interface {
 	Create(ctx context.Context, notebook *entity.Notebook) error
 	GetById(ctx context.Context, id uuid.UUID) (*entity.Notebook, error)
 	Update(ctx context.Context, notebook *entity.Notebook) error
-	DeleteById(ctx context.Context, id uuid.UUID) error
-	NullifyParentById(ctx context.Context, parentId uuid.UUID) error
+	DeleteByIds(ctx context.Context, ids []uuid.UUID, deletedAt time.Time) error
+	GetSubtree(ctx context.Context, rootId uuid.UUID) ([]*entity.Notebook, error)
+	GetAncestorIds(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error)
+	LockTree(ctx context.Context) error
+	GetDeletedDescendantIds(ctx context.Context, rootId uuid.UUID, deletedAt time.Time) ([]uuid.UUID, error)
+	RestoreByIds(ctx context.Context, ids []uuid.UUID) error
+	DetachChildren(ctx context.Context, parentId uuid.UUID) error
+	GetDetachedChildIds(ctx context.Context, parentId uuid.UUID) ([]uuid.UUID, error)
 	UpdateParentId(ctx context.Context, id uuid.UUID, parentId *uuid.UUID) error
+	GetDeleted(ctx context.Context) ([]*entity.Notebook, error)
+	GetDeletedById(ctx context.Context, id uuid.UUID) (*entity.Notebook, error)
+	Restore(ctx context.Context, notebook *entity.Notebook) error
+	GetPurgeableIds(ctx context.Context, deletedBefore time.Time) ([]uuid.UUID, error)
+	HardDeleteByIds(ctx context.Context, ids []uuid.UUID) error
 }
 
 type notebookRepository struct {
@@ -37,7 +50,8 @@ func (n *notebookRepository) UsingTx(ctx context.Context, tx database.DatabaseQu
 func (n *notebookRepository) GetAll(ctx context.Context) ([]*entity.Notebook, error) {
 	rows, err := n.db.Query(
 		ctx,
-		`SELECT id, name, parent_id, created_at, updated_at FROM notebook WHERE is_deleted = false ORDER BY name ASC`,
+		`SELECT id, name, parent_id, owner_id, created_at, updated_at FROM notebook WHERE is_deleted = false AND owner_id = $1 ORDER BY name ASC`,
+		serverutils.UserIdFromContext(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -50,6 +64,7 @@ func (n *notebookRepository) GetAll(ctx context.Context) ([]*entity.Notebook, er
 			&notebook.Id,
 			&notebook.Name,
 			&notebook.ParentId,
+			&notebook.OwnerId,
 			&notebook.CreatedAt,
 			&notebook.UpdatedAt,
 		)
@@ -66,10 +81,11 @@ func (n *notebookRepository) GetAll(ctx context.Context) ([]*entity.Notebook, er
 func (n *notebookRepository) Create(ctx context.Context, notebook *entity.Notebook) error {
 	_, err := n.db.Exec(
 		ctx,
-		`INSERT INTO notebook (id, name, parent_id, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		`INSERT INTO notebook (id, name, parent_id, owner_id, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
 		notebook.Id,
 		notebook.Name,
 		notebook.ParentId,
+		notebook.OwnerId,
 		notebook.CreatedAt,
 		notebook.UpdatedAt,
 		notebook.DeletedAt,
@@ -85,8 +101,9 @@ func (n *notebookRepository) Create(ctx context.Context, notebook *entity.Notebo
 func (n *notebookRepository) GetById(ctx context.Context, id uuid.UUID) (*entity.Notebook, error) {
 	row := n.db.QueryRow(
 		ctx,
-		`SELECT id, name, parent_id, created_at, updated_at, deleted_at, is_deleted FROM notebook n WHERE n.is_deleted = false AND n.id = $1`,
+		`SELECT id, name, parent_id, owner_id, created_at, updated_at, deleted_at, is_deleted FROM notebook n WHERE n.is_deleted = false AND n.id = $1 AND n.owner_id = $2`,
 		id,
+		serverutils.UserIdFromContext(ctx),
 	)
 	var notebook entity.Notebook
 
@@ -94,6 +111,7 @@ func (n *notebookRepository) GetById(ctx context.Context, id uuid.UUID) (*entity
 		&notebook.Id,
 		&notebook.Name,
 		&notebook.ParentId,
+		&notebook.OwnerId,
 		&notebook.CreatedAt,
 		&notebook.UpdatedAt,
 		&notebook.DeletedAt,
@@ -109,20 +127,21 @@ func (n *notebookRepository) GetById(ctx context.Context, id uuid.UUID) (*entity
 	return &notebook, nil
 }
 
+// Update writes the name of notebook. Its parent is only changed through
+// UpdateParentId, under LockTree.
 func (n *notebookRepository) Update(ctx context.Context, notebook *entity.Notebook) error {
 	_, err := n.db.Exec(
 		ctx,
 		`
 		UPDATE notebook SET
 			name = $1,
-			parent_id = $2,
-			updated_at = $3
-		WHERE id = $4
+			updated_at = $2
+		WHERE id = $3 AND owner_id = $4
 		`,
 		notebook.Name,
-		notebook.ParentId,
 		notebook.UpdatedAt,
 		notebook.Id,
+		serverutils.UserIdFromContext(ctx),
 	)
 	if err != nil {
 		return err
@@ -131,14 +150,128 @@ func (n *notebookRepository) Update(ctx context.Context, notebook *entity.Notebo
 	return nil
 }
 
-func (n *notebookRepository) DeleteById(ctx context.Context, id uuid.UUID) error {
+func (n *notebookRepository) DeleteByIds(ctx context.Context, ids []uuid.UUID, deletedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range ids {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
 	_, err := n.db.Exec(
+		ctx,
+		fmt.Sprintf(`UPDATE notebook SET is_deleted = true, deleted_at = $1 WHERE id IN (%s) AND owner_id = $2`, idSqlFormat),
+		deletedAt,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetSubtree returns the live notebook rootId followed by all of its live
+// descendants, ordered by depth and name.
+func (n *notebookRepository) GetSubtree(ctx context.Context, rootId uuid.UUID) ([]*entity.Notebook, error) {
+	rows, err := n.db.Query(
 		ctx,
 		`
-		UPDATE notebook SET is_deleted = true, deleted_at = $1 WHERE id = $2
+		WITH RECURSIVE subtree AS (
+			SELECT id, name, parent_id, owner_id, created_at, updated_at, 0 AS depth, ARRAY[id] AS path
+			FROM notebook
+			WHERE id = $1 AND owner_id = $2 AND is_deleted = false
+			UNION ALL
+			SELECT nb.id, nb.name, nb.parent_id, nb.owner_id, nb.created_at, nb.updated_at, s.depth + 1, s.path || nb.id
+			FROM notebook nb
+			JOIN subtree s ON nb.parent_id = s.id
+			WHERE nb.owner_id = $2 AND nb.is_deleted = false AND NOT nb.id = ANY(s.path)
+		)
+		SELECT id, name, parent_id, owner_id, created_at, updated_at FROM subtree ORDER BY depth ASC, name ASC
+		`,
+		rootId,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entity.Notebook, 0)
+	for rows.Next() {
+		var notebook entity.Notebook
+		err = rows.Scan(
+			&notebook.Id,
+			&notebook.Name,
+			&notebook.ParentId,
+			&notebook.OwnerId,
+			&notebook.CreatedAt,
+			&notebook.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &notebook)
+	}
+	if len(result) == 0 {
+		return nil, serverutils.ErrNotFound
+	}
+
+	return result, nil
+}
+
+// GetAncestorIds returns id followed by its parent, grandparent and so on up
+// to the root.
+func (n *notebookRepository) GetAncestorIds(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := n.db.Query(
+		ctx,
+		`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, ARRAY[id] AS path
+			FROM notebook
+			WHERE id = $1 AND owner_id = $2 AND is_deleted = false
+			UNION ALL
+			SELECT nb.id, nb.parent_id, a.path || nb.id
+			FROM notebook nb
+			JOIN ancestors a ON nb.id = a.parent_id
+			WHERE nb.owner_id = $2 AND nb.is_deleted = false AND NOT nb.id = ANY(a.path)
+		)
+		SELECT id FROM ancestors ORDER BY array_length(path, 1) ASC
 		`,
-		time.Now(),
 		id,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var ancestorId uuid.UUID
+		err = rows.Scan(&ancestorId)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, ancestorId)
+	}
+	if len(result) == 0 {
+		return nil, serverutils.ErrNotFound
+	}
+
+	return result, nil
+}
+
+// LockTree serializes changes to the current owner's notebook hierarchy until
+// the surrounding transaction ends. It must be used within a transaction.
+func (n *notebookRepository) LockTree(ctx context.Context) error {
+	_, err := n.db.Exec(
+		ctx,
+		`SELECT pg_advisory_xact_lock(hashtextextended('notebook_tree:' || $1::text, 0))`,
+		serverutils.UserIdFromContext(ctx),
 	)
 	if err != nil {
 		return err
@@ -147,14 +280,81 @@ func (n *notebookRepository) DeleteById(ctx context.Context, id uuid.UUID) error
 	return nil
 }
 
-func (n *notebookRepository) NullifyParentById(ctx context.Context, parentId uuid.UUID) error {
+// GetDeletedDescendantIds returns the trashed descendants of rootId that were
+// trashed in the same cascade delete, i.e. share its deletedAt.
+func (n *notebookRepository) GetDeletedDescendantIds(ctx context.Context, rootId uuid.UUID, deletedAt time.Time) ([]uuid.UUID, error) {
+	rows, err := n.db.Query(
+		ctx,
+		`
+		WITH RECURSIVE subtree AS (
+			SELECT id, ARRAY[id] AS path
+			FROM notebook
+			WHERE parent_id = $1 AND owner_id = $2 AND is_deleted = true AND deleted_at = $3
+			UNION ALL
+			SELECT nb.id, s.path || nb.id
+			FROM notebook nb
+			JOIN subtree s ON nb.parent_id = s.id
+			WHERE nb.owner_id = $2 AND nb.is_deleted = true AND nb.deleted_at = $3 AND NOT nb.id = ANY(s.path)
+		)
+		SELECT id FROM subtree
+		`,
+		rootId,
+		serverutils.UserIdFromContext(ctx),
+		deletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, id)
+	}
+
+	return result, nil
+}
+
+func (n *notebookRepository) RestoreByIds(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range ids {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	_, err := n.db.Exec(
+		ctx,
+		fmt.Sprintf(`UPDATE notebook SET is_deleted = false, deleted_at = null, updated_at = $1 WHERE id IN (%s) AND owner_id = $2`, idSqlFormat),
+		time.Now(),
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DetachChildren moves the live children of parentId to the root and
+// remembers parentId so ReattachChildren can undo it.
+func (n *notebookRepository) DetachChildren(ctx context.Context, parentId uuid.UUID) error {
 	_, err := n.db.Exec(
 		ctx,
 		`
-		UPDATE notebook SET parent_id = null, updated_at = $1 WHERE parent_id = $2
+		UPDATE notebook SET parent_id = null, detached_from_id = $2, updated_at = $1 WHERE parent_id = $2 AND owner_id = $3 AND is_deleted = false
 		`,
 		time.Now(),
 		parentId,
+		serverutils.UserIdFromContext(ctx),
 	)
 	if err != nil {
 		return err
@@ -163,15 +363,198 @@ func (n *notebookRepository) NullifyParentById(ctx context.Context, parentId uui
 	return nil
 }
 
+// GetDetachedChildIds returns the live notebooks detached from parentId that
+// have not been moved somewhere else since.
+func (n *notebookRepository) GetDetachedChildIds(ctx context.Context, parentId uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := n.db.Query(
+		ctx,
+		`SELECT id FROM notebook WHERE detached_from_id = $1 AND owner_id = $2 AND is_deleted = false ORDER BY name ASC`,
+		parentId,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, id)
+	}
+
+	return result, nil
+}
+
 func (n *notebookRepository) UpdateParentId(ctx context.Context, id uuid.UUID, parentId *uuid.UUID) error {
 	_, err := n.db.Exec(
 		ctx,
 		`
-		UPDATE notebook SET parent_id = $1, updated_at = $2 WHERE id = $3
+		UPDATE notebook SET parent_id = $1, detached_from_id = null, updated_at = $2 WHERE id = $3 AND owner_id = $4
 		`,
 		parentId,
 		time.Now(),
 		id,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (n *notebookRepository) GetDeleted(ctx context.Context) ([]*entity.Notebook, error) {
+	rows, err := n.db.Query(
+		ctx,
+		`SELECT id, name, parent_id, detached_from_id, owner_id, created_at, updated_at, deleted_at, is_deleted FROM notebook WHERE is_deleted = true AND owner_id = $1 ORDER BY deleted_at DESC`,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entity.Notebook, 0)
+	for rows.Next() {
+		var notebook entity.Notebook
+		err = rows.Scan(
+			&notebook.Id,
+			&notebook.Name,
+			&notebook.ParentId,
+			&notebook.DetachedFromId,
+			&notebook.OwnerId,
+			&notebook.CreatedAt,
+			&notebook.UpdatedAt,
+			&notebook.DeletedAt,
+			&notebook.IsDeleted,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &notebook)
+	}
+
+	return result, nil
+}
+
+func (n *notebookRepository) GetDeletedById(ctx context.Context, id uuid.UUID) (*entity.Notebook, error) {
+	row := n.db.QueryRow(
+		ctx,
+		`SELECT id, name, parent_id, detached_from_id, owner_id, created_at, updated_at, deleted_at, is_deleted FROM notebook WHERE is_deleted = true AND id = $1 AND owner_id = $2`,
+		id,
+		serverutils.UserIdFromContext(ctx),
+	)
+
+	var notebook entity.Notebook
+	err := row.Scan(
+		&notebook.Id,
+		&notebook.Name,
+		&notebook.ParentId,
+		&notebook.DetachedFromId,
+		&notebook.OwnerId,
+		&notebook.CreatedAt,
+		&notebook.UpdatedAt,
+		&notebook.DeletedAt,
+		&notebook.IsDeleted,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, serverutils.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &notebook, nil
+}
+
+func (n *notebookRepository) Restore(ctx context.Context, notebook *entity.Notebook) error {
+	_, err := n.db.Exec(
+		ctx,
+		`
+		UPDATE notebook SET
+			parent_id = $1,
+			detached_from_id = $2,
+			updated_at = $3,
+			deleted_at = null,
+			is_deleted = false
+		WHERE id = $4 AND owner_id = $5
+		`,
+		notebook.ParentId,
+		notebook.DetachedFromId,
+		notebook.UpdatedAt,
+		notebook.Id,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetPurgeableIds returns notebooks of every owner that have been in the
+// trash since before deletedBefore.
+func (n *notebookRepository) GetPurgeableIds(ctx context.Context, deletedBefore time.Time) ([]uuid.UUID, error) {
+	rows, err := n.db.Query(
+		ctx,
+		`SELECT id FROM notebook WHERE is_deleted = true AND deleted_at < $1`,
+		deletedBefore,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, id)
+	}
+
+	return result, nil
+}
+
+// HardDeleteByIds permanently removes the notebooks. Notebooks still
+// pointing at them, e.g. children trashed earlier, lose that reference.
+func (n *notebookRepository) HardDeleteByIds(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range ids {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	_, err := n.db.Exec(
+		ctx,
+		fmt.Sprintf(`UPDATE notebook SET parent_id = null WHERE parent_id IN (%s)`, idSqlFormat),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.db.Exec(
+		ctx,
+		fmt.Sprintf(`UPDATE notebook SET detached_from_id = null WHERE detached_from_id IN (%s)`, idSqlFormat),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.db.Exec(
+		ctx,
+		fmt.Sprintf(`DELETE FROM notebook WHERE id IN (%s)`, idSqlFormat),
 	)
 	if err != nil {
 		return err
@@ -0,0 +1,374 @@
+package repository
+
+import (
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/pkg/database"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ITagRepository interface {
+	UsingTx(ctx context.Context, tx database.DatabaseQueryer) ITagRepository
+	Create(ctx context.Context, tag *entity.Tag) error
+	GetAll(ctx context.Context) ([]*entity.Tag, error)
+	GetById(ctx context.Context, id uuid.UUID) (*entity.Tag, error)
+	GetByNames(ctx context.Context, names []string) ([]*entity.Tag, error)
+	Update(ctx context.Context, tag *entity.Tag) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	GetByNoteIds(ctx context.Context, noteIds []uuid.UUID) (map[uuid.UUID][]*entity.Tag, error)
+	GetNoteIds(ctx context.Context, tagId uuid.UUID) ([]uuid.UUID, error)
+	SetNoteTags(ctx context.Context, noteId uuid.UUID, tagIds []uuid.UUID) error
+	Merge(ctx context.Context, sourceId uuid.UUID, targetId uuid.UUID) error
+	DeleteNoteTagsByNoteIds(ctx context.Context, noteIds []uuid.UUID) error
+}
+
+type tagRepository struct {
+	db database.DatabaseQueryer
+}
+
+func (t *tagRepository) UsingTx(ctx context.Context, tx database.DatabaseQueryer) ITagRepository {
+	return &tagRepository{
+		db: tx,
+	}
+}
+
+// Create inserts the tag. Names are unique per owner regardless of case, a
+// duplicate returns serverutils.ErrConflict.
+func (t *tagRepository) Create(ctx context.Context, tag *entity.Tag) error {
+	_, err := t.db.Exec(
+		ctx,
+		`INSERT INTO tag (id, name, owner_id, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)`,
+		tag.Id,
+		tag.Name,
+		tag.OwnerId,
+		tag.CreatedAt,
+		tag.UpdatedAt,
+	)
+	if err != nil {
+		return tagNameConflict(err, tag.Name)
+	}
+
+	return nil
+}
+
+func (t *tagRepository) GetAll(ctx context.Context) ([]*entity.Tag, error) {
+	rows, err := t.db.Query(
+		ctx,
+		`
+		SELECT t.id, t.name, t.owner_id, count(n.id), t.created_at, t.updated_at
+		FROM tag t
+		LEFT JOIN note_tag nt ON nt.tag_id = t.id
+		LEFT JOIN note n ON n.id = nt.note_id AND n.is_deleted = false
+		WHERE t.owner_id = $1
+		GROUP BY t.id
+		ORDER BY lower(t.name) ASC
+		`,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entity.Tag, 0)
+	for rows.Next() {
+		var tag entity.Tag
+		err = rows.Scan(
+			&tag.Id,
+			&tag.Name,
+			&tag.OwnerId,
+			&tag.NoteCount,
+			&tag.CreatedAt,
+			&tag.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &tag)
+	}
+
+	return result, nil
+}
+
+func (t *tagRepository) GetById(ctx context.Context, id uuid.UUID) (*entity.Tag, error) {
+	row := t.db.QueryRow(
+		ctx,
+		`SELECT id, name, owner_id, created_at, updated_at FROM tag WHERE id = $1 AND owner_id = $2`,
+		id,
+		serverutils.UserIdFromContext(ctx),
+	)
+
+	var tag entity.Tag
+	err := row.Scan(
+		&tag.Id,
+		&tag.Name,
+		&tag.OwnerId,
+		&tag.CreatedAt,
+		&tag.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, serverutils.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// GetByNames returns the tags matching names case-insensitively.
+func (t *tagRepository) GetByNames(ctx context.Context, names []string) ([]*entity.Tag, error) {
+	if len(names) == 0 {
+		return make([]*entity.Tag, 0), nil
+	}
+
+	lowerNames := make([]string, 0)
+	for _, name := range names {
+		lowerNames = append(lowerNames, strings.ToLower(name))
+	}
+
+	rows, err := t.db.Query(
+		ctx,
+		`SELECT id, name, owner_id, created_at, updated_at FROM tag WHERE owner_id = $1 AND lower(name) = ANY($2)`,
+		serverutils.UserIdFromContext(ctx),
+		lowerNames,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entity.Tag, 0)
+	for rows.Next() {
+		var tag entity.Tag
+		err = rows.Scan(
+			&tag.Id,
+			&tag.Name,
+			&tag.OwnerId,
+			&tag.CreatedAt,
+			&tag.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &tag)
+	}
+
+	return result, nil
+}
+
+func (t *tagRepository) Update(ctx context.Context, tag *entity.Tag) error {
+	_, err := t.db.Exec(
+		ctx,
+		`UPDATE tag SET name = $1, updated_at = $2 WHERE id = $3 AND owner_id = $4`,
+		tag.Name,
+		tag.UpdatedAt,
+		tag.Id,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return tagNameConflict(err, tag.Name)
+	}
+
+	return nil
+}
+
+func (t *tagRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := t.db.Exec(
+		ctx,
+		`DELETE FROM note_tag WHERE tag_id = (SELECT id FROM tag WHERE id = $1 AND owner_id = $2)`,
+		id,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.db.Exec(
+		ctx,
+		`DELETE FROM tag WHERE id = $1 AND owner_id = $2`,
+		id,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *tagRepository) GetByNoteIds(ctx context.Context, noteIds []uuid.UUID) (map[uuid.UUID][]*entity.Tag, error) {
+	result := make(map[uuid.UUID][]*entity.Tag)
+	if len(noteIds) == 0 {
+		return result, nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range noteIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	rows, err := t.db.Query(
+		ctx,
+		fmt.Sprintf(`
+		SELECT nt.note_id, t.id, t.name, t.owner_id, t.created_at, t.updated_at
+		FROM note_tag nt
+		JOIN tag t ON t.id = nt.tag_id
+		WHERE nt.note_id IN (%s) AND t.owner_id = $1
+		ORDER BY lower(t.name) ASC
+		`, idSqlFormat),
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var noteId uuid.UUID
+		var tag entity.Tag
+		err = rows.Scan(
+			&noteId,
+			&tag.Id,
+			&tag.Name,
+			&tag.OwnerId,
+			&tag.CreatedAt,
+			&tag.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result[noteId] = append(result[noteId], &tag)
+	}
+
+	return result, nil
+}
+
+func (t *tagRepository) GetNoteIds(ctx context.Context, tagId uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := t.db.Query(
+		ctx,
+		`
+		SELECT nt.note_id FROM note_tag nt
+		JOIN note n ON n.id = nt.note_id
+		WHERE nt.tag_id = $1 AND n.owner_id = $2 AND n.is_deleted = false
+		`,
+		tagId,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var noteId uuid.UUID
+		err = rows.Scan(&noteId)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, noteId)
+	}
+
+	return result, nil
+}
+
+// SetNoteTags replaces the tags of a note with tagIds.
+func (t *tagRepository) SetNoteTags(ctx context.Context, noteId uuid.UUID, tagIds []uuid.UUID) error {
+	_, err := t.db.Exec(
+		ctx,
+		`DELETE FROM note_tag WHERE note_id = $1`,
+		noteId,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(tagIds) == 0 {
+		return nil
+	}
+
+	_, err = t.db.Exec(
+		ctx,
+		`
+		INSERT INTO note_tag (note_id, tag_id, created_at)
+		SELECT $1, id, $2 FROM tag WHERE id = ANY($3) AND owner_id = $4
+		ON CONFLICT DO NOTHING
+		`,
+		noteId,
+		time.Now(),
+		tagIds,
+		serverutils.UserIdFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Merge moves every note of sourceId to targetId and deletes sourceId.
+func (t *tagRepository) Merge(ctx context.Context, sourceId uuid.UUID, targetId uuid.UUID) error {
+	_, err := t.db.Exec(
+		ctx,
+		`
+		INSERT INTO note_tag (note_id, tag_id, created_at)
+		SELECT note_id, $2, created_at FROM note_tag WHERE tag_id = $1
+		ON CONFLICT DO NOTHING
+		`,
+		sourceId,
+		targetId,
+	)
+	if err != nil {
+		return err
+	}
+
+	return t.Delete(ctx, sourceId)
+}
+
+func (t *tagRepository) DeleteNoteTagsByNoteIds(ctx context.Context, noteIds []uuid.UUID) error {
+	if len(noteIds) == 0 {
+		return nil
+	}
+
+	idStr := make([]string, 0)
+	for _, id := range noteIds {
+		idStr = append(idStr, fmt.Sprintf("'%s'", id.String()))
+	}
+	idSqlFormat := strings.Join(idStr, ", ")
+
+	_, err := t.db.Exec(
+		ctx,
+		fmt.Sprintf(`DELETE FROM note_tag WHERE note_id IN (%s)`, idSqlFormat),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func tagNameConflict(err error, name string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return fmt.Errorf("%w: tag %q already exists", serverutils.ErrConflict, name)
+	}
+
+	return err
+}
+
+func NewTagRepository(db *pgxpool.Pool) ITagRepository {
+	return &tagRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/pkg/database"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IUserRepository interface {
+	UsingTx(ctx context.Context, tx database.DatabaseQueryer) IUserRepository
+	Create(ctx context.Context, user *entity.User) error
+	GetById(ctx context.Context, id uuid.UUID) (*entity.User, error)
+	GetByEmail(ctx context.Context, email string) (*entity.User, error)
+}
+
+type userRepository struct {
+	db database.DatabaseQueryer
+}
+
+func (u *userRepository) UsingTx(ctx context.Context, tx database.DatabaseQueryer) IUserRepository {
+	return &userRepository{
+		db: tx,
+	}
+}
+
+func (u *userRepository) Create(ctx context.Context, user *entity.User) error {
+	_, err := u.db.Exec(
+		ctx,
+		`INSERT INTO app_user (id, name, email, password_hash, created_at, updated_at, deleted_at, is_deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		user.Id,
+		user.Name,
+		user.Email,
+		user.PasswordHash,
+		user.CreatedAt,
+		user.UpdatedAt,
+		user.DeletedAt,
+		user.IsDeleted,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *userRepository) GetById(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	row := u.db.QueryRow(
+		ctx,
+		`SELECT id, name, email, password_hash, created_at, updated_at FROM app_user WHERE id = $1 AND is_deleted = false`,
+		id,
+	)
+
+	return u.scanUser(row)
+}
+
+func (u *userRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	row := u.db.QueryRow(
+		ctx,
+		`SELECT id, name, email, password_hash, created_at, updated_at FROM app_user WHERE lower(email) = lower($1) AND is_deleted = false`,
+		email,
+	)
+
+	return u.scanUser(row)
+}
+
+func (u *userRepository) scanUser(row pgx.Row) (*entity.User, error) {
+	var user entity.User
+	err := row.Scan(
+		&user.Id,
+		&user.Name,
+		&user.Email,
+		&user.PasswordHash,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, serverutils.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func NewUserRepository(db *pgxpool.Pool) IUserRepository {
+	return &userRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/pkg/database"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IUserSessionRepository interface {
+	UsingTx(ctx context.Context, tx database.DatabaseQueryer) IUserSessionRepository
+	Create(ctx context.Context, userSession *entity.UserSession) error
+	GetById(ctx context.Context, id uuid.UUID) (*entity.UserSession, error)
+	GetByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (*entity.UserSession, error)
+	Update(ctx context.Context, userSession *entity.UserSession) error
+	Revoke(ctx context.Context, id uuid.UUID) error
+	RevokeByUserId(ctx context.Context, userId uuid.UUID) error
+}
+
+type userSessionRepository struct {
+	db database.DatabaseQueryer
+}
+
+func (u *userSessionRepository) UsingTx(ctx context.Context, tx database.DatabaseQueryer) IUserSessionRepository {
+	return &userSessionRepository{
+		db: tx,
+	}
+}
+
+func (u *userSessionRepository) Create(ctx context.Context, userSession *entity.UserSession) error {
+	_, err := u.db.Exec(
+		ctx,
+		`INSERT INTO user_session (id, user_id, refresh_token_hash, expires_at, revoked_at, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		userSession.Id,
+		userSession.UserId,
+		userSession.RefreshTokenHash,
+		userSession.ExpiresAt,
+		userSession.RevokedAt,
+		userSession.CreatedAt,
+		userSession.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *userSessionRepository) GetById(ctx context.Context, id uuid.UUID) (*entity.UserSession, error) {
+	row := u.db.QueryRow(
+		ctx,
+		`SELECT id, user_id, refresh_token_hash, expires_at, revoked_at, created_at, updated_at FROM user_session WHERE id = $1`,
+		id,
+	)
+
+	return u.scanUserSession(row)
+}
+
+func (u *userSessionRepository) GetByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (*entity.UserSession, error) {
+	row := u.db.QueryRow(
+		ctx,
+		`SELECT id, user_id, refresh_token_hash, expires_at, revoked_at, created_at, updated_at FROM user_session WHERE refresh_token_hash = $1`,
+		refreshTokenHash,
+	)
+
+	return u.scanUserSession(row)
+}
+
+func (u *userSessionRepository) Update(ctx context.Context, userSession *entity.UserSession) error {
+	_, err := u.db.Exec(
+		ctx,
+		`UPDATE user_session SET refresh_token_hash = $1, expires_at = $2, updated_at = $3 WHERE id = $4`,
+		userSession.RefreshTokenHash,
+		userSession.ExpiresAt,
+		userSession.UpdatedAt,
+		userSession.Id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *userSessionRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	_, err := u.db.Exec(
+		ctx,
+		`UPDATE user_session SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`,
+		time.Now(),
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *userSessionRepository) RevokeByUserId(ctx context.Context, userId uuid.UUID) error {
+	_, err := u.db.Exec(
+		ctx,
+		`UPDATE user_session SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`,
+		time.Now(),
+		userId,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *userSessionRepository) scanUserSession(row pgx.Row) (*entity.UserSession, error) {
+	var userSession entity.UserSession
+	err := row.Scan(
+		&userSession.Id,
+		&userSession.UserId,
+		&userSession.RefreshTokenHash,
+		&userSession.ExpiresAt,
+		&userSession.RevokedAt,
+		&userSession.CreatedAt,
+		&userSession.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, serverutils.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &userSession, nil
+}
+
+func NewUserSessionRepository(db *pgxpool.Pool) IUserSessionRepository {
+	return &userSessionRepository{
+		db: db,
+	}
+}
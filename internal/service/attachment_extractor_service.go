@@ -0,0 +1,203 @@
+package service
+
+import (
+	"ai-notetaking-be/internal/constant"
+	"ai-notetaking-be/internal/dto"
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/repository"
+	"ai-notetaking-be/pkg/blobstore"
+	"ai-notetaking-be/pkg/chunking"
+	"ai-notetaking-be/pkg/embedding"
+	"ai-notetaking-be/pkg/textextract"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IAttachmentExtractorService interface {
+	Consume(ctx context.Context) error
+}
+
+type attachmentExtractorService struct {
+	attachmentRepository    repository.IAttachmentRepository
+	noteEmbeddingRepository repository.INoteEmbeddingRepository
+	jobRepository           repository.IJobRepository
+	blobStore               blobstore.BlobStore
+	embedder                embedding.Embedder
+	chunker                 chunking.Chunker
+	topicName               string
+	config                  ConsumerConfig
+
+	db *pgxpool.Pool
+}
+
+func (as *attachmentExtractorService) Consume(ctx context.Context) error {
+	worker := jobWorker{
+		jobRepository: as.jobRepository,
+		topicName:     as.topicName,
+		config:        as.config,
+		handle:        as.processMessage,
+	}
+	worker.start(ctx)
+
+	return nil
+}
+
+func (as *attachmentExtractorService) processMessage(ctx context.Context, msgPayload []byte) error {
+	var payload dto.PublishExtractAttachmentMessage
+	err := json.Unmarshal(msgPayload, &payload)
+	if err != nil {
+		return err
+	}
+
+	ctx = serverutils.ContextWithUserId(ctx, payload.OwnerId)
+
+	attachment, err := as.attachmentRepository.GetById(ctx, payload.AttachmentId)
+	if err != nil {
+		if errors.Is(err, serverutils.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if textextract.Format(attachment.ContentType, attachment.FileName) == "" {
+		return as.finish(ctx, attachment, constant.AttachmentExtractionStatusUnsupported, nil, nil, nil)
+	}
+
+	body, err := as.blobStore.Get(ctx, attachment.StorageKey, 0, -1)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			extractionError := err.Error()
+			return as.finish(ctx, attachment, constant.AttachmentExtractionStatusFailed, &extractionError, nil, nil)
+		}
+		return err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, constant.AttachmentMaxSize+1))
+	if err != nil {
+		return err
+	}
+
+	text, err := textextract.Extract(attachment.ContentType, attachment.FileName, data)
+	if err != nil {
+		// A document that cannot be read will not get better by retrying.
+		extractionError := err.Error()
+		return as.finish(ctx, attachment, constant.AttachmentExtractionStatusFailed, &extractionError, nil, nil)
+	}
+	if runes := []rune(text); len(runes) > constant.AttachmentMaxExtractedLength {
+		text = string(runes[:constant.AttachmentMaxExtractedLength])
+	}
+
+	now := time.Now()
+	noteEmbeddings := make([]*entity.NoteEmbedding, 0)
+	for _, chunk := range as.chunker.Chunk(text) {
+		content := fmt.Sprintf(`
+	Attachment: %s
+
+	%s
+	`,
+			attachment.FileName,
+			chunk.Text,
+		)
+
+		embeddingValues, err := as.embedder.Embed(
+			ctx,
+			content,
+			embedding.TaskTypeRetrievalDocument,
+		)
+		if err != nil {
+			return err
+		}
+
+		noteEmbeddings = append(noteEmbeddings, &entity.NoteEmbedding{
+			Id:             uuid.New(),
+			Document:       content,
+			EmbeddingValue: embeddingValues,
+			NoteId:         attachment.NoteId,
+			AttachmentId:   &attachment.Id,
+			ChunkIndex:     chunk.Index,
+			StartOffset:    chunk.StartOffset,
+			EndOffset:      chunk.EndOffset,
+			CreatedAt:      now,
+		})
+	}
+
+	return as.finish(ctx, attachment, constant.AttachmentExtractionStatusDone, nil, &text, noteEmbeddings)
+}
+
+// finish replaces the attachment's embeddings and records the extraction
+// result in one transaction, so search never sees a half indexed document.
+func (as *attachmentExtractorService) finish(
+	ctx context.Context,
+	attachment *entity.Attachment,
+	status string,
+	extractionError *string,
+	extractedText *string,
+	noteEmbeddings []*entity.NoteEmbedding,
+) error {
+	now := time.Now()
+	attachment.ExtractionStatus = status
+	attachment.ExtractionError = extractionError
+	attachment.ExtractedAt = &now
+	if extractedText == nil {
+		emptyText := ""
+		extractedText = &emptyText
+	}
+
+	tx, err := as.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	noteEmbeddingRepository := as.noteEmbeddingRepository.UsingTx(ctx, tx)
+	err = noteEmbeddingRepository.DeleteByAttachmentId(ctx, attachment.Id, now)
+	if err != nil {
+		return err
+	}
+	for _, noteEmbedding := range noteEmbeddings {
+		err = noteEmbeddingRepository.Create(ctx, noteEmbedding)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = as.attachmentRepository.UsingTx(ctx, tx).UpdateExtraction(ctx, attachment, extractedText)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func NewAttachmentExtractorService(
+	jobRepository repository.IJobRepository,
+	topicName string,
+	config ConsumerConfig,
+	attachmentRepository repository.IAttachmentRepository,
+	noteEmbeddingRepository repository.INoteEmbeddingRepository,
+	blobStore blobstore.BlobStore,
+	embedder embedding.Embedder,
+	chunker chunking.Chunker,
+	db *pgxpool.Pool,
+) IAttachmentExtractorService {
+	return &attachmentExtractorService{
+		jobRepository:           jobRepository,
+		topicName:               topicName,
+		config:                  config,
+		attachmentRepository:    attachmentRepository,
+		noteEmbeddingRepository: noteEmbeddingRepository,
+		blobStore:               blobStore,
+		embedder:                embedder,
+		chunker:                 chunker,
+		db:                      db,
+	}
+}
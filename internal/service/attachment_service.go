@@ -0,0 +1,292 @@
+package service
+
+import (
+	"ai-notetaking-be/internal/constant"
+	"ai-notetaking-be/internal/dto"
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/repository"
+	"ai-notetaking-be/pkg/blobstore"
+	"ai-notetaking-be/pkg/database"
+	"ai-notetaking-be/pkg/textextract"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IAttachmentService interface {
+	Upload(ctx context.Context, req *dto.UploadAttachmentRequest) (*dto.AttachmentResponse, error)
+	GetByNoteId(ctx context.Context, noteId uuid.UUID) ([]*dto.AttachmentResponse, error)
+	Show(ctx context.Context, id uuid.UUID) (*dto.AttachmentResponse, error)
+	Download(ctx context.Context, req *dto.DownloadAttachmentRequest) (*dto.DownloadAttachmentResponse, error)
+	Extract(ctx context.Context, id uuid.UUID) (*dto.AttachmentResponse, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type attachmentService struct {
+	attachmentRepository    repository.IAttachmentRepository
+	noteRepository          repository.INoteRepository
+	noteEmbeddingRepository repository.INoteEmbeddingRepository
+	blobStore               blobstore.BlobStore
+	extractPublisher        IPublisherService
+
+	db *pgxpool.Pool
+}
+
+func NewAttachmentService(
+	attachmentRepository repository.IAttachmentRepository,
+	noteRepository repository.INoteRepository,
+	noteEmbeddingRepository repository.INoteEmbeddingRepository,
+	blobStore blobstore.BlobStore,
+	extractPublisher IPublisherService,
+	db *pgxpool.Pool,
+) IAttachmentService {
+	return &attachmentService{
+		attachmentRepository:    attachmentRepository,
+		noteRepository:          noteRepository,
+		noteEmbeddingRepository: noteEmbeddingRepository,
+		blobStore:               blobStore,
+		extractPublisher:        extractPublisher,
+		db:                      db,
+	}
+}
+
+func (c *attachmentService) Upload(ctx context.Context, req *dto.UploadAttachmentRequest) (*dto.AttachmentResponse, error) {
+	if req.Size > constant.AttachmentMaxSize {
+		return nil, serverutils.NewValidationError([]serverutils.ValidationErrorDetail{
+			{Field: "file", Message: fmt.Sprintf("file must be at most %d bytes", constant.AttachmentMaxSize)},
+		})
+	}
+
+	note, err := c.noteRepository.GetById(ctx, req.NoteId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sniff the type from the first bytes when the client did not send a
+	// useful one.
+	body := bufio.NewReaderSize(req.File, 512)
+	contentType := req.ContentType
+	if contentType == "" || contentType == constant.AttachmentDefaultContentType {
+		head, _ := body.Peek(512)
+		contentType = http.DetectContentType(head)
+	}
+
+	attachment := entity.Attachment{
+		Id:          uuid.New(),
+		NoteId:      note.Id,
+		FileName:    attachmentFileName(req.FileName),
+		ContentType: contentType,
+		Size:        req.Size,
+		OwnerId:     serverutils.UserIdFromContext(ctx),
+		CreatedAt:   time.Now(),
+	}
+	attachment.ExtractionStatus = constant.AttachmentExtractionStatusPending
+	if textextract.Format(attachment.ContentType, attachment.FileName) == "" {
+		attachment.ExtractionStatus = constant.AttachmentExtractionStatusUnsupported
+	}
+	attachment.StorageKey = fmt.Sprintf("%s/%s/%s", attachment.OwnerId, note.Id, attachment.Id)
+
+	err = c.blobStore.Put(ctx, attachment.StorageKey, body, attachment.Size, attachment.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.createAttachment(ctx, &attachment)
+	if err != nil {
+		if deleteErr := c.blobStore.Delete(ctx, attachment.StorageKey); deleteErr != nil {
+			log.Error(deleteErr)
+		}
+		return nil, err
+	}
+
+	return toAttachmentResponse(&attachment), nil
+}
+
+// createAttachment saves the attachment and queues its extraction in the same
+// transaction.
+func (c *attachmentService) createAttachment(ctx context.Context, attachment *entity.Attachment) error {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = c.attachmentRepository.UsingTx(ctx, tx).Create(ctx, attachment)
+	if err != nil {
+		return err
+	}
+
+	if attachment.ExtractionStatus == constant.AttachmentExtractionStatusPending {
+		err = c.publishExtract(ctx, tx, attachment)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (c *attachmentService) GetByNoteId(ctx context.Context, noteId uuid.UUID) ([]*dto.AttachmentResponse, error) {
+	_, err := c.noteRepository.GetById(ctx, noteId)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := c.attachmentRepository.GetByNoteId(ctx, noteId)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*dto.AttachmentResponse, 0)
+	for _, attachment := range attachments {
+		res = append(res, toAttachmentResponse(attachment))
+	}
+
+	return res, nil
+}
+
+func (c *attachmentService) Show(ctx context.Context, id uuid.UUID) (*dto.AttachmentResponse, error) {
+	attachment, err := c.attachmentRepository.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAttachmentResponse(attachment), nil
+}
+
+func (c *attachmentService) Download(ctx context.Context, req *dto.DownloadAttachmentRequest) (*dto.DownloadAttachmentResponse, error) {
+	attachment, err := c.attachmentRepository.GetById(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.blobStore.Get(ctx, attachment.StorageKey, req.Offset, req.Length)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return nil, serverutils.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &dto.DownloadAttachmentResponse{
+		Attachment: toAttachmentResponse(attachment),
+		Body:       body,
+	}, nil
+}
+
+// Extract queues the attachment for text extraction again, e.g. after a
+// failure or a change of the embedding model.
+func (c *attachmentService) Extract(ctx context.Context, id uuid.UUID) (*dto.AttachmentResponse, error) {
+	attachment, err := c.attachmentRepository.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if textextract.Format(attachment.ContentType, attachment.FileName) == "" {
+		return nil, serverutils.NewValidationError([]serverutils.ValidationErrorDetail{
+			{Field: "id", Message: "text cannot be extracted from this file type"},
+		})
+	}
+
+	attachment.ExtractionStatus = constant.AttachmentExtractionStatusPending
+	attachment.ExtractionError = nil
+
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = c.attachmentRepository.UsingTx(ctx, tx).UpdateExtraction(ctx, attachment, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.publishExtract(ctx, tx, attachment)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAttachmentResponse(attachment), nil
+}
+
+func (c *attachmentService) Delete(ctx context.Context, id uuid.UUID) error {
+	attachment, err := c.attachmentRepository.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = c.noteEmbeddingRepository.UsingTx(ctx, tx).HardDeleteByAttachmentId(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = c.attachmentRepository.UsingTx(ctx, tx).Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.blobStore.Delete(ctx, attachment.StorageKey)
+}
+
+func (c *attachmentService) publishExtract(ctx context.Context, tx database.DatabaseQueryer, attachment *entity.Attachment) error {
+	payload, err := json.Marshal(dto.PublishExtractAttachmentMessage{
+		AttachmentId: attachment.Id,
+		OwnerId:      attachment.OwnerId,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.extractPublisher.UsingTx(ctx, tx).Publish(ctx, attachment.Id.String(), payload)
+}
+
+// attachmentFileName keeps the base name of an uploaded file, browsers may
+// send a full path.
+func attachmentFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+
+	return name
+}
+
+func toAttachmentResponse(attachment *entity.Attachment) *dto.AttachmentResponse {
+	return &dto.AttachmentResponse{
+		Id:               attachment.Id,
+		NoteId:           attachment.NoteId,
+		FileName:         attachment.FileName,
+		ContentType:      attachment.ContentType,
+		Size:             attachment.Size,
+		ExtractionStatus: attachment.ExtractionStatus,
+		ExtractionError:  attachment.ExtractionError,
+		CreatedAt:        attachment.CreatedAt,
+	}
+}
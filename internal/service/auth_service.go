@@ -0,0 +1,259 @@
+package service
+
+import (
+	"ai-notetaking-be/internal/dto"
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/repository"
+	"ai-notetaking-be/pkg/jwt"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type IAuthService interface {
+	Register(ctx context.Context, req *dto.RegisterRequest) (*dto.RegisterResponse, error)
+	Login(ctx context.Context, req *dto.LoginRequest) (*dto.TokenResponse, error)
+	Refresh(ctx context.Context, req *dto.RefreshTokenRequest) (*dto.TokenResponse, error)
+	Logout(ctx context.Context, req *dto.LogoutRequest) error
+	Me(ctx context.Context) (*dto.MeResponse, error)
+	Authenticate(ctx context.Context, accessToken string) (uuid.UUID, error)
+}
+
+type authService struct {
+	userRepository        repository.IUserRepository
+	userSessionRepository repository.IUserSessionRepository
+	jwtSecret             []byte
+	accessTokenTtl        time.Duration
+	refreshTokenTtl       time.Duration
+}
+
+func NewAuthService(
+	userRepository repository.IUserRepository,
+	userSessionRepository repository.IUserSessionRepository,
+	jwtSecret []byte,
+	accessTokenTtl time.Duration,
+	refreshTokenTtl time.Duration,
+) IAuthService {
+	return &authService{
+		userRepository:        userRepository,
+		userSessionRepository: userSessionRepository,
+		jwtSecret:             jwtSecret,
+		accessTokenTtl:        accessTokenTtl,
+		refreshTokenTtl:       refreshTokenTtl,
+	}
+}
+
+func (c *authService) Register(ctx context.Context, req *dto.RegisterRequest) (*dto.RegisterResponse, error) {
+	email := strings.TrimSpace(req.Email)
+
+	_, err := c.userRepository.GetByEmail(ctx, email)
+	if err == nil {
+		return nil, fmt.Errorf("%w: email is already registered", serverutils.ErrConflict)
+	}
+	if !errors.Is(err, serverutils.ErrNotFound) {
+		return nil, err
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := entity.User{
+		Id:           uuid.New(),
+		Name:         req.Name,
+		Email:        email,
+		PasswordHash: string(passwordHash),
+		CreatedAt:    time.Now(),
+	}
+
+	err = c.userRepository.Create(ctx, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.RegisterResponse{
+		Id: user.Id,
+	}, nil
+}
+
+func (c *authService) Login(ctx context.Context, req *dto.LoginRequest) (*dto.TokenResponse, error) {
+	user, err := c.userRepository.GetByEmail(ctx, strings.TrimSpace(req.Email))
+	if err != nil {
+		if errors.Is(err, serverutils.ErrNotFound) {
+			return nil, serverutils.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
+	if err != nil {
+		return nil, serverutils.ErrUnauthorized
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	userSession := entity.UserSession{
+		Id:               uuid.New(),
+		UserId:           user.Id,
+		RefreshTokenHash: hashRefreshToken(refreshToken),
+		ExpiresAt:        now.Add(c.refreshTokenTtl),
+		CreatedAt:        now,
+	}
+
+	err = c.userSessionRepository.Create(ctx, &userSession)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.issueTokens(&userSession, refreshToken)
+}
+
+// Refresh rotates the refresh token, so a refresh token can only be used
+// once.
+func (c *authService) Refresh(ctx context.Context, req *dto.RefreshTokenRequest) (*dto.TokenResponse, error) {
+	userSession, err := c.getActiveSession(ctx, req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	userSession.RefreshTokenHash = hashRefreshToken(refreshToken)
+	userSession.ExpiresAt = now.Add(c.refreshTokenTtl)
+	userSession.UpdatedAt = &now
+
+	err = c.userSessionRepository.Update(ctx, userSession)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.issueTokens(userSession, refreshToken)
+}
+
+func (c *authService) Logout(ctx context.Context, req *dto.LogoutRequest) error {
+	userSession, err := c.getActiveSession(ctx, req.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	return c.userSessionRepository.Revoke(ctx, userSession.Id)
+}
+
+func (c *authService) Me(ctx context.Context) (*dto.MeResponse, error) {
+	user, err := c.userRepository.GetById(ctx, serverutils.UserIdFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MeResponse{
+		Id:        user.Id,
+		Name:      user.Name,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+	}, nil
+}
+
+// Authenticate validates an access token and checks that the session it was
+// issued for has not been revoked by a logout.
+func (c *authService) Authenticate(ctx context.Context, accessToken string) (uuid.UUID, error) {
+	claims, err := jwt.Parse(accessToken, c.jwtSecret)
+	if err != nil {
+		return uuid.Nil, serverutils.ErrUnauthorized
+	}
+
+	userId, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, serverutils.ErrUnauthorized
+	}
+	sessionId, err := uuid.Parse(claims.SessionId)
+	if err != nil {
+		return uuid.Nil, serverutils.ErrUnauthorized
+	}
+
+	userSession, err := c.userSessionRepository.GetById(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, serverutils.ErrNotFound) {
+			return uuid.Nil, serverutils.ErrUnauthorized
+		}
+		return uuid.Nil, err
+	}
+	if userSession.RevokedAt != nil || userSession.UserId != userId {
+		return uuid.Nil, serverutils.ErrUnauthorized
+	}
+
+	return userId, nil
+}
+
+func (c *authService) getActiveSession(ctx context.Context, refreshToken string) (*entity.UserSession, error) {
+	userSession, err := c.userSessionRepository.GetByRefreshTokenHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, serverutils.ErrNotFound) {
+			return nil, serverutils.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	if userSession.RevokedAt != nil || time.Now().After(userSession.ExpiresAt) {
+		return nil, serverutils.ErrUnauthorized
+	}
+
+	return userSession, nil
+}
+
+func (c *authService) issueTokens(userSession *entity.UserSession, refreshToken string) (*dto.TokenResponse, error) {
+	now := time.Now()
+	accessTokenExpiresAt := now.Add(c.accessTokenTtl)
+
+	accessToken, err := jwt.Sign(&jwt.Claims{
+		Subject:   userSession.UserId.String(),
+		SessionId: userSession.Id.String(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: accessTokenExpiresAt.Unix(),
+	}, c.jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TokenResponse{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessTokenExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: userSession.ExpiresAt,
+		TokenType:             "Bearer",
+	}, nil
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+
+	return hex.EncodeToString(sum[:])
+}
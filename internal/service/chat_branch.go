@@ -0,0 +1,127 @@
+package service
+
+import (
+	"ai-notetaking-be/internal/entity"
+
+	"github.com/google/uuid"
+)
+
+// chatTree indexes the messages of a session. Messages are linked to the one
+// they follow by ParentId, so regenerating a reply or editing a question
+// starts a new branch next to the old one instead of replacing it.
+type chatTree struct {
+	chatMessagesById map[uuid.UUID]*entity.ChatMessage
+	// children holds the messages following each message in creation order,
+	// the greeting of the session is kept under uuid.Nil.
+	children map[uuid.UUID][]*entity.ChatMessage
+	latest   *entity.ChatMessage
+}
+
+// newChatTree expects the messages ordered by creation time.
+func newChatTree(chatMessages []*entity.ChatMessage) *chatTree {
+	tree := chatTree{
+		chatMessagesById: make(map[uuid.UUID]*entity.ChatMessage),
+		children:         make(map[uuid.UUID][]*entity.ChatMessage),
+	}
+	for _, chatMessage := range chatMessages {
+		tree.chatMessagesById[chatMessage.Id] = chatMessage
+		parentId := uuid.Nil
+		if chatMessage.ParentId != nil {
+			parentId = *chatMessage.ParentId
+		}
+		tree.children[parentId] = append(tree.children[parentId], chatMessage)
+		tree.latest = chatMessage
+	}
+
+	return &tree
+}
+
+// activeMessage returns the last message of the branch the session continues
+// from, falling back to the latest message. It is nil for an empty session.
+func (t *chatTree) activeMessage(chatSession *entity.ChatSession) *entity.ChatMessage {
+	if chatSession.ActiveMessageId != nil {
+		if chatMessage, ok := t.chatMessagesById[*chatSession.ActiveMessageId]; ok {
+			return chatMessage
+		}
+	}
+
+	return t.latest
+}
+
+// branch returns the messages from the start of the session down to
+// chatMessage.
+func (t *chatTree) branch(chatMessage *entity.ChatMessage) []*entity.ChatMessage {
+	res := make([]*entity.ChatMessage, 0)
+	for chatMessage != nil && len(res) <= len(t.chatMessagesById) {
+		res = append(res, chatMessage)
+		if chatMessage.ParentId == nil {
+			break
+		}
+		chatMessage = t.chatMessagesById[*chatMessage.ParentId]
+	}
+
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+
+	return res
+}
+
+// latestLeaf follows the most recent message below chatMessage down to the
+// end of its branch.
+func (t *chatTree) latestLeaf(chatMessage *entity.ChatMessage) *entity.ChatMessage {
+	for i := 0; i < len(t.chatMessagesById); i++ {
+		children := t.children[chatMessage.Id]
+		if len(children) == 0 {
+			break
+		}
+		chatMessage = children[len(children)-1]
+	}
+
+	return chatMessage
+}
+
+// siblingIds returns the ids of the messages following the same message as
+// chatMessage, itself included, in creation order.
+func (t *chatTree) siblingIds(chatMessage *entity.ChatMessage) []uuid.UUID {
+	parentId := uuid.Nil
+	if chatMessage.ParentId != nil {
+		parentId = *chatMessage.ParentId
+	}
+
+	res := make([]uuid.UUID, 0)
+	for _, sibling := range t.children[parentId] {
+		res = append(res, sibling.Id)
+	}
+
+	return res
+}
+
+// branchRawChats splits the raw messages of a session into the instructions
+// it starts with and the raw messages of the branch, in branch order.
+func branchRawChats(
+	rawChats []*entity.ChatMessageRaw,
+	branch []*entity.ChatMessage,
+) ([]*entity.ChatMessageRaw, []*entity.ChatMessageRaw) {
+	// The first two raw messages are the instructions of the session.
+	instructionRawChats := rawChats
+	if len(instructionRawChats) > 2 {
+		instructionRawChats = instructionRawChats[:2]
+	}
+
+	rawChatsByChatMessageId := make(map[uuid.UUID]*entity.ChatMessageRaw)
+	for _, rawChat := range rawChats[len(instructionRawChats):] {
+		if rawChat.ChatMessageId != nil {
+			rawChatsByChatMessageId[*rawChat.ChatMessageId] = rawChat
+		}
+	}
+
+	turnRawChats := make([]*entity.ChatMessageRaw, 0)
+	for _, chatMessage := range branch {
+		if rawChat, ok := rawChatsByChatMessageId[chatMessage.Id]; ok {
+			turnRawChats = append(turnRawChats, rawChat)
+		}
+	}
+
+	return instructionRawChats, turnRawChats
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"ai-notetaking-be/internal/constant"
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/pkg/chatbot"
+	"context"
+	"strings"
+)
+
+// chatContext is the earlier conversation sent along with a new message:
+// the rolling summary of old turns followed by the recent turns.
+type chatContext struct {
+	chatHistories  []*chatbot.ChatHistory
+	summaryChanged bool
+}
+
+// buildChatContext keeps the earlier conversation of a branch within the
+// token budget. rawChats are the raw messages of the branch without the
+// instructions of the session. Reference blocks of earlier turns are dropped
+// since their answers already carry what was used, and when the remaining
+// turns are still over budget the oldest ones are folded into the session's
+// summary. A summary made on another branch is left out and replaced once
+// this branch needs one. The summary is updated on chatSession but not saved.
+func (cs *chatbotService) buildChatContext(
+	ctx context.Context,
+	chatSession *entity.ChatSession,
+	rawChats []*entity.ChatMessageRaw,
+) (*chatContext, error) {
+	summary := ""
+	recentRawChats := rawChats
+	if chatSession.SummarizedMessageId != nil {
+		for i, rawChat := range rawChats {
+			if rawChat.ChatMessageId != nil && *rawChat.ChatMessageId == *chatSession.SummarizedMessageId {
+				summary = chatSession.Summary
+				recentRawChats = rawChats[i+1:]
+				break
+			}
+		}
+	}
+
+	recentHistories := make([]*chatbot.ChatHistory, 0)
+	tokens := chatbot.EstimateTokens(summary)
+	for _, rawChat := range recentRawChats {
+		chat := rawChat.Chat
+		if rawChat.Role == constant.ChatMessageRoleUser {
+			chat = stripReferences(chat)
+		}
+		recentHistories = append(recentHistories, &chatbot.ChatHistory{
+			Chat: chat,
+			Role: rawChat.Role,
+		})
+		tokens += chatbot.EstimateTokens(chat)
+	}
+
+	// Fold whole turns only, so the kept history still starts with a user
+	// message.
+	fold := 0
+	for tokens > cs.contextTokenBudget && len(recentHistories)-fold > constant.ChatContextMinRecentMessages {
+		tokens -= chatbot.EstimateTokens(recentHistories[fold].Chat)
+		fold++
+		for fold < len(recentHistories) && recentHistories[fold].Role != constant.ChatMessageRoleUser {
+			tokens -= chatbot.EstimateTokens(recentHistories[fold].Chat)
+			fold++
+		}
+	}
+
+	res := chatContext{}
+	if fold > 0 {
+		var err error
+		summary, err = chatbot.Summarize(ctx, cs.chatModel, summary, recentHistories[:fold])
+		if err != nil {
+			return nil, err
+		}
+
+		chatSession.Summary = summary
+		chatSession.SummarizedMessageId = recentRawChats[fold-1].ChatMessageId
+		res.summaryChanged = true
+	}
+
+	res.chatHistories = make([]*chatbot.ChatHistory, 0)
+	if summary != "" {
+		res.chatHistories = append(
+			res.chatHistories,
+			&chatbot.ChatHistory{
+				Chat: constant.ChatSummaryMessageRawUserPromptV1 + summary,
+				Role: constant.ChatMessageRoleUser,
+			},
+			&chatbot.ChatHistory{
+				Chat: constant.ChatSummaryMessageRawModelPromptV1,
+				Role: constant.ChatMessageRoleModel,
+			},
+		)
+	}
+	res.chatHistories = append(res.chatHistories, recentHistories[fold:]...)
+
+	return &res, nil
+}
+
+// stripReferences keeps the question of a raw user message and drops the
+// reference blocks in front of it.
+func stripReferences(chat string) string {
+	i := strings.LastIndex(chat, constant.ChatMessageRawQuestionPrefix)
+	if i < 0 {
+		return chat
+	}
+
+	return strings.TrimSuffix(chat[i+len(constant.ChatMessageRawQuestionPrefix):], constant.ChatMessageRawQuestionSuffix)
+}
@@ -4,41 +4,69 @@ import (
 	"ai-notetaking-be/internal/constant"
 	"ai-notetaking-be/internal/dto"
 	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
 	"ai-notetaking-be/internal/repository"
 	"ai-notetaking-be/pkg/chatbot"
 	"ai-notetaking-be/pkg/embedding"
+	"ai-notetaking-be/pkg/rerank"
 	"context"
+	"errors"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/gofiber/fiber/v2/log"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type IChatbotService interface {
-	CreateSession(ctx context.Context) (*dto.CreateSessionResponse, error)
+	CreateSession(ctx context.Context, request *dto.CreateSessionRequest) (*dto.CreateSessionResponse, error)
 	GetAllSessions(ctx context.Context) ([]*dto.GetAllSessionsResponse, error)
 	GetChatHistory(ctx context.Context, sessionId uuid.UUID) ([]*dto.GetChatHistoryResponse, error)
 	SendChat(ctx context.Context, request *dto.SendChatRequest) (*dto.SendChatResponse, error)
+	SendChatStream(ctx context.Context, request *dto.SendChatRequest) (ChatStream, error)
+	RegenerateChat(ctx context.Context, request *dto.RegenerateChatRequest) (*dto.SendChatResponse, error)
+	RegenerateChatStream(ctx context.Context, request *dto.RegenerateChatRequest) (ChatStream, error)
+	EditChat(ctx context.Context, request *dto.EditChatRequest) (*dto.SendChatResponse, error)
+	EditChatStream(ctx context.Context, request *dto.EditChatRequest) (ChatStream, error)
+	SelectChatBranch(ctx context.Context, request *dto.SelectChatBranchRequest) ([]*dto.GetChatHistoryResponse, error)
 	DeleteSession(ctx context.Context, request *dto.DeleteSessionRequest) error
 }
 
+// ChatStream generates and saves the reply of a request that was already
+// validated, calling onDelta with each piece of the reply as it arrives.
+// Returning an error from onDelta aborts the generation.
+type ChatStream func(ctx context.Context, onDelta func(delta string) error) (*dto.SendChatResponse, error)
+
 type chatbotService struct {
-	db                       *pgxpool.Pool
-	chatSessionRepository    repository.IChatSessionRepository
-	chatMessageRepository    repository.IChatMessageRepository
-	chatMessageRawRepository repository.IChatMessageRawRepository
-	noteEmbeddingRepository  repository.INoteEmbeddingRepository
+	db                             *pgxpool.Pool
+	chatSessionRepository          repository.IChatSessionRepository
+	chatMessageRepository          repository.IChatMessageRepository
+	chatMessageRawRepository       repository.IChatMessageRawRepository
+	chatMessageReferenceRepository repository.IChatMessageReferenceRepository
+	noteRepository                 repository.INoteRepository
+	notebookRepository             repository.INotebookRepository
+	tagRepository                  repository.ITagRepository
+	noteEmbeddingRepository        repository.INoteEmbeddingRepository
+	attachmentRepository           repository.IAttachmentRepository
+	embedder                       embedding.Embedder
+	chatModel                      chatbot.ChatModel
+	// contextTokenBudget bounds the earlier conversation sent with a new
+	// message, see buildChatContext.
+	contextTokenBudget int
+	retrievalConfig    RetrievalConfig
+	// reranker is nil when re-ranking is disabled.
+	reranker rerank.Reranker
 }
 
-func (cs *chatbotService) CreateSession(ctx context.Context) (*dto.CreateSessionResponse, error) {
+func (cs *chatbotService) CreateSession(ctx context.Context, request *dto.CreateSessionRequest) (*dto.CreateSessionResponse, error) {
 
 	now := time.Now()
 	chatSession := entity.ChatSession{
 		Id:        uuid.New(),
 		Title:     "Unnamed session",
+		OwnerId:   serverutils.UserIdFromContext(ctx),
 		CreatedAt: now,
 	}
 	chatMessage := entity.ChatMessage{
@@ -48,6 +76,7 @@ func (cs *chatbotService) CreateSession(ctx context.Context) (*dto.CreateSession
 		ChatSessionId: chatSession.Id,
 		CreatedAt:     now,
 	}
+	chatSession.ActiveMessageId = &chatMessage.Id
 	chatMessageRawUser := entity.ChatMessageRaw{
 		Id:            uuid.New(),
 		Chat:          constant.ChatMessageRawInitialUserPromptV1,
@@ -96,7 +125,8 @@ func (cs *chatbotService) CreateSession(ctx context.Context) (*dto.CreateSession
 	}
 
 	return &dto.CreateSessionResponse{
-		Id: chatSession.Id,
+		Id:    chatSession.Id,
+		Scope: toChatScopeResponse(chatSession.Scope),
 	}, nil
 }
 
@@ -111,6 +141,7 @@ func (cs *chatbotService) GetAllSessions(ctx context.Context) ([]*dto.GetAllSess
 		response = append(response, &dto.GetAllSessionsResponse{
 			Id:        chatSession.Id,
 			Title:     chatSession.Title,
+			Scope:     toChatScopeResponse(chatSession.Scope),
 			CreatedAt: chatSession.CreatedAt,
 			UpdatedAt: chatSession.UpdatedAt,
 		})
@@ -119,176 +150,579 @@ func (cs *chatbotService) GetAllSessions(ctx context.Context) ([]*dto.GetAllSess
 	return response, nil
 }
 
+// GetChatHistory returns the active branch of the session.
 func (cs *chatbotService) GetChatHistory(ctx context.Context, sessionId uuid.UUID) ([]*dto.GetChatHistoryResponse, error) {
-	_, err := cs.chatSessionRepository.GetById(ctx, sessionId)
+	chatSession, tree, err := cs.getChatTree(ctx, sessionId)
 	if err != nil {
 		return nil, err
 	}
+	chatMessages := tree.branch(tree.activeMessage(chatSession))
 
-	chatMessages, err := cs.chatMessageRepository.GetByChatSessionId(ctx, sessionId)
+	chatMessageIds := make([]uuid.UUID, 0)
+	for _, chatMessage := range chatMessages {
+		chatMessageIds = append(chatMessageIds, chatMessage.Id)
+	}
+	chatMessageReferences, err := cs.chatMessageReferenceRepository.GetByChatMessageIds(ctx, chatMessageIds)
 	if err != nil {
 		return nil, err
 	}
+	referencesByChatMessageId := make(map[uuid.UUID][]*entity.ChatMessageReference)
+	for _, chatMessageReference := range chatMessageReferences {
+		referencesByChatMessageId[chatMessageReference.ChatMessageId] = append(
+			referencesByChatMessageId[chatMessageReference.ChatMessageId],
+			chatMessageReference,
+		)
+	}
 
 	response := make([]*dto.GetChatHistoryResponse, 0)
 	for _, chatMessage := range chatMessages {
 		response = append(response, &dto.GetChatHistoryResponse{
-			Id:        chatMessage.Id,
-			Role:      chatMessage.Role,
-			Chat:      chatMessage.Chat,
-			CreatedAt: chatMessage.CreatedAt,
+			Id:         chatMessage.Id,
+			ParentId:   chatMessage.ParentId,
+			SiblingIds: tree.siblingIds(chatMessage),
+			Role:       chatMessage.Role,
+			Chat:       chatMessage.Chat,
+			CreatedAt:  chatMessage.CreatedAt,
+			References: toChatMessageReferenceResponses(referencesByChatMessageId[chatMessage.Id]),
 		})
 	}
 
 	return response, nil
 }
 
-func (cs *chatbotService) SendChat(ctx context.Context, request *dto.SendChatRequest) (*dto.SendChatResponse, error) {
-	tx, err := cs.db.Begin(ctx)
+// SelectChatBranch continues the session from the branch holding the given
+// message, following its most recent replies to the end.
+func (cs *chatbotService) SelectChatBranch(ctx context.Context, request *dto.SelectChatBranchRequest) ([]*dto.GetChatHistoryResponse, error) {
+	chatSession, tree, err := cs.getChatTree(ctx, request.ChatSessionId)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback(ctx)
 
-	chatSessionRepository := cs.chatSessionRepository.UsingTx(ctx, tx)
-	chatMessageRepository := cs.chatMessageRepository.UsingTx(ctx, tx)
-	chatMessageRawRepository := cs.chatMessageRawRepository.UsingTx(ctx, tx)
-	noteEmbeddingRepository := cs.noteEmbeddingRepository.UsingTx(ctx, tx)
+	chatMessage, ok := tree.chatMessagesById[request.ChatMessageId]
+	if !ok {
+		return nil, serverutils.ErrNotFound
+	}
 
-	chatSession, err := chatSessionRepository.GetById(ctx, request.ChatSessionId)
+	chatSession.ActiveMessageId = &tree.latestLeaf(chatMessage).Id
+	err = cs.chatSessionRepository.UpdateActiveMessage(ctx, chatSession)
 	if err != nil {
 		return nil, err
 	}
 
-	existingRawChats, err := chatMessageRawRepository.GetByChatSessionId(ctx, request.ChatSessionId)
+	return cs.GetChatHistory(ctx, chatSession.Id)
+}
+
+func (cs *chatbotService) getChatTree(ctx context.Context, chatSessionId uuid.UUID) (*entity.ChatSession, *chatTree, error) {
+	chatSession, err := cs.chatSessionRepository.GetById(ctx, chatSessionId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chatMessages, err := cs.chatMessageRepository.GetByChatSessionId(ctx, chatSessionId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return chatSession, newChatTree(chatMessages), nil
+}
+
+// chatTurn is a user message to answer. branch holds the messages it
+// follows. chatMessage is set when a new reply is generated for an existing
+// user message, otherwise a user message is created from chat.
+type chatTurn struct {
+	chatSession *entity.ChatSession
+	branch      []*entity.ChatMessage
+	chat        string
+	scope       entity.ChatScope
+	chatMessage *entity.ChatMessage
+}
+
+type preparedChat struct {
+	chatSession          *entity.ChatSession
+	updateSessionTitle   bool
+	updateSummary        bool
+	newChatMessage       bool
+	updateChatMessageRaw bool
+	chatMessage          *entity.ChatMessage
+	chatMessageRaw       *entity.ChatMessageRaw
+	chatHistories        []*chatbot.ChatHistory
+	references           []*entity.ChatMessageReference
+}
+
+func (cs *chatbotService) SendChat(ctx context.Context, request *dto.SendChatRequest) (*dto.SendChatResponse, error) {
+	turn, err := cs.sendChatTurn(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	updateSessionTitle := len(existingRawChats) == 2
 
-	now := time.Now()
+	return cs.answerChat(ctx, turn, nil)
+}
 
-	chatMessage := entity.ChatMessage{
-		Id:            uuid.New(),
-		Chat:          request.Chat,
-		Role:          constant.ChatMessageRoleUser,
-		ChatSessionId: request.ChatSessionId,
-		CreatedAt:     now,
+func (cs *chatbotService) SendChatStream(ctx context.Context, request *dto.SendChatRequest) (ChatStream, error) {
+	turn, err := cs.sendChatTurn(ctx, request)
+	if err != nil {
+		return nil, err
 	}
 
-	embeddingRes, err := embedding.GetGeminiEmbedding(
-		os.Getenv("GOOGLE_GEMINI_API_KEY"),
-		request.Chat,
-		"RETRIEVAL_QUERY",
-	)
+	return cs.chatStream(turn), nil
+}
+
+func (cs *chatbotService) sendChatTurn(ctx context.Context, request *dto.SendChatRequest) (*chatTurn, error) {
+	chatSession, tree, err := cs.getChatTree(ctx, request.ChatSessionId)
 	if err != nil {
 		return nil, err
 	}
 
-	decideUseRAGChatHistories := make([]*chatbot.ChatHistory, 0)
-	for i, rawChat := range existingRawChats {
-		if i == 0 {
-			decideUseRAGChatHistories = append(decideUseRAGChatHistories, &chatbot.ChatHistory{
-				Chat: constant.DecideUseRAGMessageRawInitialUserPromptV1,
-				Role: constant.ChatMessageRoleUser,
-			})
-			continue
-		} else if i == 1 {
-			decideUseRAGChatHistories = append(decideUseRAGChatHistories, &chatbot.ChatHistory{
-				Chat: constant.DecideUseRAGMessageRawInitialModelPromptV1,
-				Role: constant.ChatMessageRoleModel,
-			})
-			continue
+	scope := chatSession.Scope
+	if request.Scope != nil {
+		scope, err = cs.resolveChatScope(ctx, request.Scope)
+		if err != nil {
+			return nil, err
 		}
+	}
+
+	return &chatTurn{
+		chatSession: chatSession,
+		branch:      tree.branch(tree.activeMessage(chatSession)),
+		chat:        request.Chat,
+		scope:       scope,
+	}, nil
+}
+
+func (cs *chatbotService) RegenerateChat(ctx context.Context, request *dto.RegenerateChatRequest) (*dto.SendChatResponse, error) {
+	turn, err := cs.regenerateChatTurn(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return cs.answerChat(ctx, turn, nil)
+}
+
+func (cs *chatbotService) RegenerateChatStream(ctx context.Context, request *dto.RegenerateChatRequest) (ChatStream, error) {
+	turn, err := cs.regenerateChatTurn(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return cs.chatStream(turn), nil
+}
+
+// regenerateChatTurn answers the question of the last reply of the active
+// branch again. The new reply becomes a sibling of the old one and retrieval
+// uses the scope of the session.
+func (cs *chatbotService) regenerateChatTurn(ctx context.Context, request *dto.RegenerateChatRequest) (*chatTurn, error) {
+	chatSession, tree, err := cs.getChatTree(ctx, request.ChatSessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	var chatMessage *entity.ChatMessage
+	reply := tree.activeMessage(chatSession)
+	if reply != nil && reply.Role == constant.ChatMessageRoleModel && reply.ParentId != nil {
+		chatMessage = tree.chatMessagesById[*reply.ParentId]
+	}
+	if chatMessage == nil || chatMessage.Role != constant.ChatMessageRoleUser {
+		return nil, serverutils.NewValidationError([]serverutils.ValidationErrorDetail{
+			{Field: "chat_session_id", Message: "session has no reply to regenerate"},
+		})
+	}
+
+	branch := tree.branch(chatMessage)
+
+	return &chatTurn{
+		chatSession: chatSession,
+		branch:      branch[:len(branch)-1],
+		chat:        chatMessage.Chat,
+		scope:       chatSession.Scope,
+		chatMessage: chatMessage,
+	}, nil
+}
+
+func (cs *chatbotService) EditChat(ctx context.Context, request *dto.EditChatRequest) (*dto.SendChatResponse, error) {
+	turn, err := cs.editChatTurn(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return cs.answerChat(ctx, turn, nil)
+}
+
+func (cs *chatbotService) EditChatStream(ctx context.Context, request *dto.EditChatRequest) (ChatStream, error) {
+	turn, err := cs.editChatTurn(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return cs.chatStream(turn), nil
+}
+
+// editChatTurn answers the edited message on a new branch next to the
+// original one, which is kept along with everything that followed it.
+func (cs *chatbotService) editChatTurn(ctx context.Context, request *dto.EditChatRequest) (*chatTurn, error) {
+	chatSession, tree, err := cs.getChatTree(ctx, request.ChatSessionId)
+	if err != nil {
+		return nil, err
+	}
 
-		decideUseRAGChatHistories = append(decideUseRAGChatHistories, &chatbot.ChatHistory{
-			Chat: rawChat.Chat,
-			Role: rawChat.Role,
+	chatMessage, ok := tree.chatMessagesById[request.ChatMessageId]
+	if !ok {
+		return nil, serverutils.ErrNotFound
+	}
+	if chatMessage.Role != constant.ChatMessageRoleUser {
+		return nil, serverutils.NewValidationError([]serverutils.ValidationErrorDetail{
+			{Field: "chat_message_id", Message: "only user messages can be edited"},
 		})
 	}
 
+	scope := chatSession.Scope
+	if request.Scope != nil {
+		scope, err = cs.resolveChatScope(ctx, request.Scope)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	branch := tree.branch(chatMessage)
+
+	return &chatTurn{
+		chatSession: chatSession,
+		branch:      branch[:len(branch)-1],
+		chat:        request.Chat,
+		scope:       scope,
+	}, nil
+}
+
+func (cs *chatbotService) chatStream(turn *chatTurn) ChatStream {
+	return func(ctx context.Context, onDelta func(delta string) error) (*dto.SendChatResponse, error) {
+		return cs.answerChat(ctx, turn, onDelta)
+	}
+}
+
+// answerChat streams the reply through onDelta when it is set.
+func (cs *chatbotService) answerChat(
+	ctx context.Context,
+	turn *chatTurn,
+	onDelta func(delta string) error,
+) (*dto.SendChatResponse, error) {
+	prepared, err := cs.prepareChat(ctx, turn)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply string
+	if onDelta == nil {
+		reply, err = cs.chatModel.Generate(
+			ctx,
+			prepared.chatHistories,
+		)
+	} else {
+		reply, err = cs.chatModel.GenerateStream(
+			ctx,
+			prepared.chatHistories,
+			onDelta,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cs.saveChat(ctx, prepared, reply)
+}
+
+// prepareChat retrieves the references and builds the conversation sent to
+// the chat model. Nothing is written to the database here so the model call
+// can happen outside of a transaction.
+func (cs *chatbotService) prepareChat(ctx context.Context, turn *chatTurn) (*preparedChat, error) {
+	chatSession := turn.chatSession
+
+	existingRawChats, err := cs.chatMessageRawRepository.GetByChatSessionId(ctx, chatSession.Id)
+	if err != nil {
+		return nil, err
+	}
+	instructionRawChats, turnRawChats := branchRawChats(existingRawChats, turn.branch)
+
+	// The first question of the session names it, the branch only holds the
+	// greeting then.
+	newChatMessage := turn.chatMessage == nil
+	updateSessionTitle := newChatMessage && len(turn.branch) <= 1
+
+	now := time.Now()
+
+	chatMessage := turn.chatMessage
+	if newChatMessage {
+		var parentId *uuid.UUID
+		if len(turn.branch) > 0 {
+			parentId = &turn.branch[len(turn.branch)-1].Id
+		}
+		chatMessage = &entity.ChatMessage{
+			Id:            uuid.New(),
+			Chat:          turn.chat,
+			Role:          constant.ChatMessageRoleUser,
+			ChatSessionId: chatSession.Id,
+			ParentId:      parentId,
+			CreatedAt:     now,
+		}
+	}
+
+	history, err := cs.buildChatContext(ctx, chatSession, turnRawChats)
+	if err != nil {
+		return nil, err
+	}
+
+	decideUseRAGChatHistories := []*chatbot.ChatHistory{
+		{
+			Chat: constant.DecideUseRAGMessageRawInitialUserPromptV1,
+			Role: constant.ChatMessageRoleUser,
+		},
+		{
+			Chat: constant.DecideUseRAGMessageRawInitialModelPromptV1,
+			Role: constant.ChatMessageRoleModel,
+		},
+	}
+	decideUseRAGChatHistories = append(decideUseRAGChatHistories, history.chatHistories...)
+
 	useRag, err := chatbot.DecideToUseRAG(
 		ctx,
-		os.Getenv("GOOGLE_GEMINI_API_KEY"),
+		cs.chatModel,
 		decideUseRAGChatHistories,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// Only a question answered from the notes is condensed and embedded, a
+	// direct answer skips both model calls.
+	var retrievalQuery *string
 	strBuilder := strings.Builder{}
+	references := make([]*entity.ChatMessageReference, 0)
 	if useRag {
-		noteEmbeddings, err := noteEmbeddingRepository.SearchSimilarity(
+		// Follow-up questions are rewritten so the query embedding does not miss
+		// what they refer to. Retrieval falls back to the question as asked when
+		// the rewrite fails.
+		query, err := chatbot.CondenseQuestion(
+			ctx,
+			cs.chatModel,
+			history.chatHistories,
+			chatMessage.Chat,
+		)
+		if err != nil {
+			log.Error(err)
+			query = chatMessage.Chat
+		}
+
+		embeddingValues, err := cs.embedder.Embed(
 			ctx,
-			embeddingRes.Embedding.Values,
+			query,
+			embedding.TaskTypeRetrievalQuery,
 		)
 		if err != nil {
 			return nil, err
 		}
+		retrievalQuery = &query
+
+		retrievalScope, err := cs.retrievalScope(ctx, turn.scope)
+		if err != nil {
+			return nil, err
+		}
+
+		noteEmbeddings := make([]*entity.NoteEmbedding, 0)
+		if retrievalScope != nil {
+			candidates, err := cs.noteEmbeddingRepository.SearchSimilarity(
+				ctx,
+				embeddingValues,
+				*retrievalScope,
+				cs.retrievalConfig.MinScore,
+				cs.retrievalConfig.CandidateLimit,
+			)
+			if err != nil {
+				return nil, err
+			}
+			noteEmbeddings = cs.selectReferences(ctx, query, candidates)
+		}
+
+		noteIds := make([]uuid.UUID, 0)
+		for _, noteEmbedding := range noteEmbeddings {
+			noteIds = append(noteIds, noteEmbedding.NoteId)
+		}
+		notes, err := cs.noteRepository.GetByIds(ctx, noteIds)
+		if err != nil {
+			return nil, err
+		}
+		notesById := make(map[uuid.UUID]*entity.Note)
+		for _, note := range notes {
+			notesById[note.Id] = note
+		}
+		attachmentTexts, err := getAttachmentTexts(ctx, cs.attachmentRepository, noteEmbeddings)
+		if err != nil {
+			return nil, err
+		}
 
 		for i, noteEmbedding := range noteEmbeddings {
 			strBuilder.WriteString(fmt.Sprintf("Reference %d\n", i+1))
 			strBuilder.WriteString(noteEmbedding.Document)
 			strBuilder.WriteString("\n\n")
+
+			note, ok := notesById[noteEmbedding.NoteId]
+			if !ok {
+				continue
+			}
+			reference := entity.ChatMessageReference{
+				Id:              uuid.New(),
+				ReferenceNumber: i + 1,
+				NoteId:          note.Id,
+				NoteTitle:       note.Title,
+				ChunkIndex:      noteEmbedding.ChunkIndex,
+				Snippet:         embeddingSnippet(note, attachmentTexts, noteEmbedding),
+				Score:           noteEmbedding.Score,
+				CreatedAt:       now,
+			}
+			if attachmentText, ok := attachmentTexts[noteEmbeddingAttachmentId(noteEmbedding)]; ok {
+				reference.AttachmentId = &attachmentText.AttachmentId
+				reference.AttachmentFileName = &attachmentText.FileName
+			}
+			references = append(references, &reference)
 		}
 	}
 
-	strBuilder.WriteString("User next question: ")
-	strBuilder.WriteString(request.Chat)
-	strBuilder.WriteString("\n\n")
-	strBuilder.WriteString("Your answer ?")
+	strBuilder.WriteString(constant.ChatMessageRawQuestionPrefix)
+	strBuilder.WriteString(chatMessage.Chat)
+	strBuilder.WriteString(constant.ChatMessageRawQuestionSuffix)
 	chatMessageRaw := entity.ChatMessageRaw{
-		Id:            uuid.New(),
-		Chat:          strBuilder.String(),
-		Role:          constant.ChatMessageRoleUser,
-		ChatSessionId: request.ChatSessionId,
-		CreatedAt:     now,
+		Id:             uuid.New(),
+		Chat:           strBuilder.String(),
+		Role:           constant.ChatMessageRoleUser,
+		ChatSessionId:  chatSession.Id,
+		ChatMessageId:  &chatMessage.Id,
+		RetrievalQuery: retrievalQuery,
+		CreatedAt:      now,
+	}
+
+	// A regenerated reply gets the references retrieved this time, so the raw
+	// message of its question is rewritten.
+	updateChatMessageRaw := false
+	if !newChatMessage {
+		for _, existingRawChat := range existingRawChats {
+			if existingRawChat.ChatMessageId != nil && *existingRawChat.ChatMessageId == chatMessage.Id {
+				chatMessageRaw.Id = existingRawChat.Id
+				chatMessageRaw.CreatedAt = existingRawChat.CreatedAt
+				chatMessageRaw.UpdatedAt = &now
+				updateChatMessageRaw = true
+				break
+			}
+		}
 	}
 
-	existingRawChats = append(
-		existingRawChats,
-		&chatMessageRaw,
-	)
-
-	geminiReq := make([]*chatbot.ChatHistory, 0)
-	for _, existingRawChat := range existingRawChats {
-		geminiReq = append(geminiReq, &chatbot.ChatHistory{
-			Chat: existingRawChat.Chat,
-			Role: existingRawChat.Role,
+	// The instructions of the session come first, then the earlier
+	// conversation and the new message with its references.
+	chatHistories := make([]*chatbot.ChatHistory, 0)
+	for _, instructionRawChat := range instructionRawChats {
+		chatHistories = append(chatHistories, &chatbot.ChatHistory{
+			Chat: instructionRawChat.Chat,
+			Role: instructionRawChat.Role,
 		})
 	}
+	chatHistories = append(chatHistories, history.chatHistories...)
+	chatHistories = append(chatHistories, &chatbot.ChatHistory{
+		Chat: chatMessageRaw.Chat,
+		Role: chatMessageRaw.Role,
+	})
+
+	return &preparedChat{
+		chatSession:          chatSession,
+		updateSessionTitle:   updateSessionTitle,
+		updateSummary:        history.summaryChanged,
+		newChatMessage:       newChatMessage,
+		updateChatMessageRaw: updateChatMessageRaw,
+		chatMessage:          chatMessage,
+		chatMessageRaw:       &chatMessageRaw,
+		chatHistories:        chatHistories,
+		references:           references,
+	}, nil
+}
 
-	reply, err := chatbot.GetGeminiResponse(
-		ctx,
-		os.Getenv("GOOGLE_GEMINI_API_KEY"),
-		geminiReq,
-	)
-	if err != nil {
-		return nil, err
+func (cs *chatbotService) saveChat(ctx context.Context, prepared *preparedChat, reply string) (*dto.SendChatResponse, error) {
+	chatSession := prepared.chatSession
+	chatMessage := prepared.chatMessage
+	replyAt := chatMessage.CreatedAt.Add(1 * time.Millisecond)
+	if !prepared.newChatMessage {
+		// A regenerated reply is ordered after the replies it replaces.
+		replyAt = time.Now()
 	}
 
 	chatMessageModel := entity.ChatMessage{
 		Id:            uuid.New(),
 		Chat:          reply,
 		Role:          constant.ChatMessageRoleModel,
-		ChatSessionId: request.ChatSessionId,
-		CreatedAt:     now.Add(1 * time.Millisecond),
+		ChatSessionId: chatSession.Id,
+		ParentId:      &chatMessage.Id,
+		CreatedAt:     replyAt,
 	}
 	chatMessageModelRaw := entity.ChatMessageRaw{
 		Id:            uuid.New(),
 		Chat:          reply,
 		Role:          constant.ChatMessageRoleModel,
-		ChatSessionId: request.ChatSessionId,
-		CreatedAt:     now.Add(1 * time.Millisecond),
+		ChatSessionId: chatSession.Id,
+		ChatMessageId: &chatMessageModel.Id,
+		CreatedAt:     replyAt,
+	}
+
+	tx, err := cs.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	chatSessionRepository := cs.chatSessionRepository.UsingTx(ctx, tx)
+	chatMessageRepository := cs.chatMessageRepository.UsingTx(ctx, tx)
+	chatMessageRawRepository := cs.chatMessageRawRepository.UsingTx(ctx, tx)
+	chatMessageReferenceRepository := cs.chatMessageReferenceRepository.UsingTx(ctx, tx)
+
+	if prepared.newChatMessage {
+		err = chatMessageRepository.Create(ctx, chatMessage)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = chatMessageRepository.Create(ctx, &chatMessageModel)
+	if err != nil {
+		return nil, err
+	}
+	if prepared.updateChatMessageRaw {
+		err = chatMessageRawRepository.Update(ctx, prepared.chatMessageRaw)
+	} else {
+		err = chatMessageRawRepository.Create(ctx, prepared.chatMessageRaw)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = chatMessageRawRepository.Create(ctx, &chatMessageModelRaw)
+	if err != nil {
+		return nil, err
+	}
+	for _, reference := range prepared.references {
+		reference.ChatMessageId = chatMessageModel.Id
+		err = chatMessageReferenceRepository.Create(ctx, reference)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	chatSession.ActiveMessageId = &chatMessageModel.Id
+	err = chatSessionRepository.UpdateActiveMessage(ctx, chatSession)
+	if err != nil {
+		return nil, err
 	}
 
-	chatMessageRepository.Create(ctx, &chatMessage)
-	chatMessageRepository.Create(ctx, &chatMessageModel)
-	chatMessageRawRepository.Create(ctx, &chatMessageRaw)
-	chatMessageRawRepository.Create(ctx, &chatMessageModelRaw)
+	if prepared.updateSummary {
+		err = chatSessionRepository.UpdateSummary(ctx, chatSession)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	if updateSessionTitle {
-		chatSession.Title = request.Chat
+	if prepared.updateSessionTitle {
+		now := time.Now()
+		chatSession.Title = chatMessage.Chat
 		chatSession.UpdatedAt = &now
 		err = chatSessionRepository.Update(ctx, chatSession)
 		if err != nil {
@@ -305,20 +739,138 @@ func (cs *chatbotService) SendChat(ctx context.Context, request *dto.SendChatReq
 		ChatSessionId:    chatSession.Id,
 		ChatSessionTitle: chatSession.Title,
 		Sent: &dto.SendChatResponseChat{
-			Id:        chatMessage.Id,
-			Chat:      chatMessage.Chat,
-			Role:      chatMessage.Role,
-			CreatedAt: chatMessage.CreatedAt,
+			Id:         chatMessage.Id,
+			ParentId:   chatMessage.ParentId,
+			Chat:       chatMessage.Chat,
+			Role:       chatMessage.Role,
+			CreatedAt:  chatMessage.CreatedAt,
+			References: make([]*dto.ChatMessageReferenceResponse, 0),
 		},
 		Reply: &dto.SendChatResponseChat{
-			Id:        chatMessageModel.Id,
-			Chat:      chatMessageModel.Chat,
-			Role:      chatMessageModel.Role,
-			CreatedAt: chatMessageModel.CreatedAt,
+			Id:         chatMessageModel.Id,
+			ParentId:   chatMessageModel.ParentId,
+			Chat:       chatMessageModel.Chat,
+			Role:       chatMessageModel.Role,
+			CreatedAt:  chatMessageModel.CreatedAt,
+			References: toChatMessageReferenceResponses(prepared.references),
 		},
 	}, nil
 }
 
+// resolveChatScope checks that everything a scope refers to exists and turns
+// its tag names into ids.
+func (cs *chatbotService) resolveChatScope(ctx context.Context, request *dto.ChatScopeRequest) (entity.ChatScope, error) {
+	scope := entity.ChatScope{}
+	if request == nil {
+		return scope, nil
+	}
+
+	details := make([]serverutils.ValidationErrorDetail, 0)
+	if request.NotebookId != nil {
+		_, err := cs.notebookRepository.GetById(ctx, *request.NotebookId)
+		if err != nil {
+			if !errors.Is(err, serverutils.ErrNotFound) {
+				return scope, err
+			}
+			details = append(details, serverutils.ValidationErrorDetail{Field: "scope.notebook_id", Message: "notebook not found"})
+		}
+		scope.NotebookId = request.NotebookId
+	}
+
+	if len(request.Tags) > 0 {
+		tagIds, err := resolveTagIds(ctx, cs.tagRepository, request.Tags)
+		if err != nil {
+			return scope, err
+		}
+		if tagIds == nil {
+			details = append(details, serverutils.ValidationErrorDetail{Field: "scope.tags", Message: "tag not found"})
+		}
+		scope.TagIds = tagIds
+	}
+
+	if len(request.NoteIds) > 0 {
+		seen := make(map[uuid.UUID]bool)
+		for _, noteId := range request.NoteIds {
+			if !seen[noteId] {
+				seen[noteId] = true
+				scope.NoteIds = append(scope.NoteIds, noteId)
+			}
+		}
+
+		notes, err := cs.noteRepository.GetByIds(ctx, scope.NoteIds)
+		if err != nil {
+			return scope, err
+		}
+		if len(notes) != len(scope.NoteIds) {
+			details = append(details, serverutils.ValidationErrorDetail{Field: "scope.note_ids", Message: "note not found"})
+		}
+	}
+
+	if len(details) > 0 {
+		return scope, serverutils.NewValidationError(details)
+	}
+
+	return scope, nil
+}
+
+// retrievalScope expands the notebook of a scope to its subtree. It returns
+// nil when nothing can match anymore, e.g. after the notebook was deleted.
+func (cs *chatbotService) retrievalScope(ctx context.Context, scope entity.ChatScope) (*repository.RetrievalScope, error) {
+	retrievalScope := repository.RetrievalScope{}
+	if len(scope.TagIds) > 0 {
+		retrievalScope.TagIds = scope.TagIds
+	}
+	if len(scope.NoteIds) > 0 {
+		retrievalScope.NoteIds = scope.NoteIds
+	}
+
+	if scope.NotebookId != nil {
+		notebooks, err := cs.notebookRepository.GetSubtree(ctx, *scope.NotebookId)
+		if err != nil {
+			if errors.Is(err, serverutils.ErrNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		for _, notebook := range notebooks {
+			retrievalScope.NotebookIds = append(retrievalScope.NotebookIds, notebook.Id)
+		}
+	}
+
+	return &retrievalScope, nil
+}
+
+func toChatScopeResponse(scope entity.ChatScope) *dto.ChatScopeResponse {
+	if scope.NotebookId == nil && len(scope.TagIds) == 0 && len(scope.NoteIds) == 0 {
+		return nil
+	}
+
+	return &dto.ChatScopeResponse{
+		NotebookId: scope.NotebookId,
+		TagIds:     scope.TagIds,
+		NoteIds:    scope.NoteIds,
+	}
+}
+
+func toChatMessageReferenceResponses(chatMessageReferences []*entity.ChatMessageReference) []*dto.ChatMessageReferenceResponse {
+	response := make([]*dto.ChatMessageReferenceResponse, 0)
+	for _, chatMessageReference := range chatMessageReferences {
+		response = append(response, &dto.ChatMessageReferenceResponse{
+			ReferenceNumber:    chatMessageReference.ReferenceNumber,
+			NoteId:             chatMessageReference.NoteId,
+			NoteTitle:          chatMessageReference.NoteTitle,
+			ChunkIndex:         chatMessageReference.ChunkIndex,
+			AttachmentId:       chatMessageReference.AttachmentId,
+			AttachmentFileName: chatMessageReference.AttachmentFileName,
+			Snippet:            chatMessageReference.Snippet,
+			Score:              chatMessageReference.Score,
+		})
+	}
+
+	return response
+}
+
 func (cs *chatbotService) DeleteSession(ctx context.Context, request *dto.DeleteSessionRequest) error {
 
 	tx, err := cs.db.Begin(ctx)
@@ -330,6 +882,7 @@ func (cs *chatbotService) DeleteSession(ctx context.Context, request *dto.Delete
 	chatSessionRepository := cs.chatSessionRepository.UsingTx(ctx, tx)
 	chatMessageRepository := cs.chatMessageRepository.UsingTx(ctx, tx)
 	chatMessageRawRepository := cs.chatMessageRawRepository.UsingTx(ctx, tx)
+	chatMessageReferenceRepository := cs.chatMessageReferenceRepository.UsingTx(ctx, tx)
 
 	_, err = chatSessionRepository.GetById(ctx, request.ChatSessionId)
 	if err != nil {
@@ -341,6 +894,11 @@ func (cs *chatbotService) DeleteSession(ctx context.Context, request *dto.Delete
 		return err
 	}
 
+	err = chatMessageReferenceRepository.DeleteByChatSessionId(ctx, request.ChatSessionId)
+	if err != nil {
+		return err
+	}
+
 	err = chatMessageRepository.DeleteByChatSessionId(ctx, request.ChatSessionId)
 	if err != nil {
 		return err
@@ -364,13 +922,33 @@ func NewChatbotService(
 	chatSessionRepository repository.IChatSessionRepository,
 	chatMessageRepository repository.IChatMessageRepository,
 	chatMessageRawRepository repository.IChatMessageRawRepository,
+	chatMessageReferenceRepository repository.IChatMessageReferenceRepository,
+	noteRepository repository.INoteRepository,
+	notebookRepository repository.INotebookRepository,
+	tagRepository repository.ITagRepository,
 	noteEmbeddingRepository repository.INoteEmbeddingRepository,
+	attachmentRepository repository.IAttachmentRepository,
+	embedder embedding.Embedder,
+	chatModel chatbot.ChatModel,
+	contextTokenBudget int,
+	retrievalConfig RetrievalConfig,
+	reranker rerank.Reranker,
 ) IChatbotService {
 	return &chatbotService{
-		db:                       db,
-		chatSessionRepository:    chatSessionRepository,
-		chatMessageRepository:    chatMessageRepository,
-		chatMessageRawRepository: chatMessageRawRepository,
-		noteEmbeddingRepository:  noteEmbeddingRepository,
+		db:                             db,
+		chatSessionRepository:          chatSessionRepository,
+		chatMessageRepository:          chatMessageRepository,
+		chatMessageRawRepository:       chatMessageRawRepository,
+		chatMessageReferenceRepository: chatMessageReferenceRepository,
+		noteRepository:                 noteRepository,
+		notebookRepository:             notebookRepository,
+		tagRepository:                  tagRepository,
+		noteEmbeddingRepository:        noteEmbeddingRepository,
+		attachmentRepository:           attachmentRepository,
+		embedder:                       embedder,
+		chatModel:                      chatModel,
+		contextTokenBudget:             contextTokenBudget,
+		retrievalConfig:                retrievalConfig,
+		reranker:                       reranker,
 	}
 }
@@ -3,17 +3,17 @@ package service
 import (
 	"ai-notetaking-be/internal/dto"
 	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
 	"ai-notetaking-be/internal/repository"
+	"ai-notetaking-be/pkg/chunking"
 	"ai-notetaking-be/pkg/embedding"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
-	"github.com/ThreeDotsLabs/watermill/message"
-	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
-	"github.com/gofiber/fiber/v2/log"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -26,124 +26,157 @@ type consumerService struct {
 	notebookRepository      repository.INotebookRepository
 	noteRepository          repository.INoteRepository
 	noteEmbeddingRepository repository.INoteEmbeddingRepository
-	pubSub                  *gochannel.GoChannel
+	tagRepository           repository.ITagRepository
+	jobRepository           repository.IJobRepository
+	embedder                embedding.Embedder
+	chunker                 chunking.Chunker
 	topicName               string
+	config                  ConsumerConfig
 
 	db *pgxpool.Pool
 }
 
 func (cs *consumerService) Consume(ctx context.Context) error {
-	messages, err := cs.pubSub.Subscribe(ctx, cs.topicName)
-	if err != nil {
-		return err
+	worker := jobWorker{
+		jobRepository: cs.jobRepository,
+		topicName:     cs.topicName,
+		config:        cs.config,
+		handle:        cs.processMessage,
 	}
-
-	go func() {
-		for msg := range messages {
-			cs.processMessage(ctx, msg)
-		}
-	}()
+	worker.start(ctx)
 
 	return nil
 }
 
-func (cs *consumerService) processMessage(ctx context.Context, msg *message.Message) {
-	defer msg.Nack()
-	defer func() {
-		if e := recover(); e != nil {
-			log.Error(e)
-		}
-	}()
-
+func (cs *consumerService) processMessage(ctx context.Context, msgPayload []byte) error {
 	var payload dto.PublishEmbedNoteMessage
-	err := json.Unmarshal(msg.Payload, &payload)
+	err := json.Unmarshal(msgPayload, &payload)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
+	ctx = serverutils.ContextWithUserId(ctx, payload.OwnerId)
+
 	note, err := cs.noteRepository.GetById(ctx, payload.NoteId)
 	if err != nil {
-		panic(err)
+		if errors.Is(err, serverutils.ErrNotFound) {
+			return nil
+		}
+		return err
 	}
 	notebook, err := cs.notebookRepository.GetById(ctx, note.NotebookId)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	tagsByNoteId, err := cs.tagRepository.GetByNoteIds(ctx, []uuid.UUID{note.Id})
+	if err != nil {
+		return err
+	}
+	tagNames := make([]string, 0)
+	for _, tag := range tagsByNoteId[note.Id] {
+		tagNames = append(tagNames, tag.Name)
+	}
+	noteTags := "-"
+	if len(tagNames) > 0 {
+		noteTags = strings.Join(tagNames, ", ")
 	}
 
 	noteUpdatedAt := "-"
 	if note.UpdatedAt != nil {
 		noteUpdatedAt = note.UpdatedAt.Format(time.RFC3339)
 	}
-	content := fmt.Sprintf(`
+
+	chunks := cs.chunker.Chunk(note.Content)
+	if len(chunks) == 0 {
+		chunks = append(chunks, &chunking.Chunk{})
+	}
+
+	now := time.Now()
+	noteEmbeddings := make([]*entity.NoteEmbedding, 0)
+	for _, chunk := range chunks {
+		content := fmt.Sprintf(`
 	Note Title: %s
 	Notebook Title: %s
+	Tags: %s
 
 	%s
 
 	Created At: %s
 	Updated At: %s
 	`,
-		note.Title,
-		notebook.Name,
-		note.Content,
-		note.CreatedAt.Format(time.RFC3339),
-		noteUpdatedAt,
-	)
-
-	res, err := embedding.GetGeminiEmbedding(
-		os.Getenv("GOOGLE_GEMINI_API_KEY"),
-		content,
-		"RETRIEVAL_DOCUMENT",
-	)
-	if err != nil {
-		panic(err)
-	}
+			note.Title,
+			notebook.Name,
+			noteTags,
+			chunk.Text,
+			note.CreatedAt.Format(time.RFC3339),
+			noteUpdatedAt,
+		)
+
+		embeddingValues, err := cs.embedder.Embed(
+			ctx,
+			content,
+			embedding.TaskTypeRetrievalDocument,
+		)
+		if err != nil {
+			return err
+		}
 
-	noteEmbedding := entity.NoteEmbedding{
-		Id:             uuid.New(),
-		Document:       content,
-		EmbeddingValue: res.Embedding.Values,
-		NoteId:         note.Id,
-		CreatedAt:      time.Now(),
+		noteEmbeddings = append(noteEmbeddings, &entity.NoteEmbedding{
+			Id:             uuid.New(),
+			Document:       content,
+			EmbeddingValue: embeddingValues,
+			NoteId:         note.Id,
+			ChunkIndex:     chunk.Index,
+			StartOffset:    chunk.StartOffset,
+			EndOffset:      chunk.EndOffset,
+			CreatedAt:      now,
+		})
 	}
 
 	tx, err := cs.db.Begin(ctx)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer tx.Rollback(ctx)
 
 	noteEmbeddingRepository := cs.noteEmbeddingRepository.UsingTx(ctx, tx)
-	err = noteEmbeddingRepository.DeleteByNoteId(ctx, note.Id)
+	err = noteEmbeddingRepository.DeleteContentByNoteId(ctx, note.Id, now)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	err = noteEmbeddingRepository.Create(ctx, &noteEmbedding)
-	if err != nil {
-		panic(err)
+	for _, noteEmbedding := range noteEmbeddings {
+		err = noteEmbeddingRepository.Create(ctx, noteEmbedding)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = tx.Commit(ctx)
-	if err != nil {
-		panic(err)
-	}
-	msg.Ack()
+	return tx.Commit(ctx)
 }
 
 func NewConsumerService(
-	pubSub *gochannel.GoChannel,
+	jobRepository repository.IJobRepository,
 	topicName string,
+	config ConsumerConfig,
 	noteRepository repository.INoteRepository,
 	noteEmbeddingRepository repository.INoteEmbeddingRepository,
 	notebookRepository repository.INotebookRepository,
+	tagRepository repository.ITagRepository,
+	embedder embedding.Embedder,
+	chunker chunking.Chunker,
 	db *pgxpool.Pool,
 ) IConsumerService {
 	return &consumerService{
-		pubSub:                  pubSub,
+		jobRepository:           jobRepository,
 		topicName:               topicName,
+		config:                  config,
 		noteRepository:          noteRepository,
 		noteEmbeddingRepository: noteEmbeddingRepository,
 		notebookRepository:      notebookRepository,
+		tagRepository:           tagRepository,
+		embedder:                embedder,
+		chunker:                 chunker,
 		db:                      db,
 	}
 }
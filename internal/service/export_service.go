@@ -0,0 +1,144 @@
+package service
+
+import (
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/repository"
+	"ai-notetaking-be/pkg/notearchive"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type IExportService interface {
+	ExportNotebook(ctx context.Context, id uuid.UUID) (*Export, error)
+	ExportWorkspace(ctx context.Context) (*Export, error)
+}
+
+// Export is an archive whose notebooks have been resolved but whose notes
+// are only loaded, one notebook at a time, while it is written.
+type Export struct {
+	FileName string
+
+	notebooks      []*entity.Notebook
+	noteRepository repository.INoteRepository
+}
+
+type exportService struct {
+	notebookRepository repository.INotebookRepository
+	noteRepository     repository.INoteRepository
+}
+
+func NewExportService(
+	notebookRepository repository.INotebookRepository,
+	noteRepository repository.INoteRepository,
+) IExportService {
+	return &exportService{
+		notebookRepository: notebookRepository,
+		noteRepository:     noteRepository,
+	}
+}
+
+func (c *exportService) ExportNotebook(ctx context.Context, id uuid.UUID) (*Export, error) {
+	notebooks, err := c.notebookRepository.GetSubtree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Export{
+		FileName:       fmt.Sprintf("%s.zip", notearchive.SanitizeName(notebooks[0].Name)),
+		notebooks:      orderByHierarchy(notebooks),
+		noteRepository: c.noteRepository,
+	}, nil
+}
+
+func (c *exportService) ExportWorkspace(ctx context.Context) (*Export, error) {
+	notebooks, err := c.notebookRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Export{
+		FileName:       fmt.Sprintf("notes-%s.zip", time.Now().Format("2006-01-02")),
+		notebooks:      orderByHierarchy(notebooks),
+		noteRepository: c.noteRepository,
+	}, nil
+}
+
+// WriteTo streams the archive to w. ctx must carry the owner of the notes.
+func (e *Export) WriteTo(ctx context.Context, w io.Writer) error {
+	archive := notearchive.NewWriter(w)
+	for _, notebook := range e.notebooks {
+		err := archive.AddNotebook(notearchive.Notebook{
+			Id:       notebook.Id,
+			ParentId: notebook.ParentId,
+			Name:     notebook.Name,
+		})
+		if err != nil {
+			return err
+		}
+
+		notes, err := e.noteRepository.GetByNotebookIds(ctx, []uuid.UUID{notebook.Id})
+		if err != nil {
+			return err
+		}
+		for _, note := range notes {
+			err = archive.AddNote(notearchive.Note{
+				Id:         note.Id,
+				NotebookId: note.NotebookId,
+				Title:      note.Title,
+				Content:    note.Content,
+				CreatedAt:  note.CreatedAt,
+				UpdatedAt:  note.UpdatedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return archive.Close()
+}
+
+// orderByHierarchy sorts notebooks depth first by name so every notebook
+// comes after its parent. Notebooks whose parent is not in the list are
+// treated as roots.
+func orderByHierarchy(notebooks []*entity.Notebook) []*entity.Notebook {
+	ids := make(map[uuid.UUID]bool)
+	for _, notebook := range notebooks {
+		ids[notebook.Id] = true
+	}
+
+	roots := make([]*entity.Notebook, 0)
+	children := make(map[uuid.UUID][]*entity.Notebook)
+	for _, notebook := range notebooks {
+		if notebook.ParentId != nil && ids[*notebook.ParentId] {
+			children[*notebook.ParentId] = append(children[*notebook.ParentId], notebook)
+		} else {
+			roots = append(roots, notebook)
+		}
+	}
+
+	result := make([]*entity.Notebook, 0, len(notebooks))
+	visited := make(map[uuid.UUID]bool)
+	var visit func(level []*entity.Notebook)
+	visit = func(level []*entity.Notebook) {
+		sort.SliceStable(level, func(i, j int) bool {
+			return level[i].Name < level[j].Name
+		})
+		for _, notebook := range level {
+			if visited[notebook.Id] {
+				continue
+			}
+			visited[notebook.Id] = true
+			result = append(result, notebook)
+			visit(children[notebook.Id])
+		}
+	}
+	visit(roots)
+
+	return result
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"ai-notetaking-be/internal/constant"
+	"ai-notetaking-be/internal/dto"
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/repository"
+	"ai-notetaking-be/pkg/notearchive"
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IImportService interface {
+	Import(ctx context.Context, req *dto.ImportRequest) (*dto.ImportResponse, error)
+}
+
+type importService struct {
+	notebookRepository     repository.INotebookRepository
+	noteRepository         repository.INoteRepository
+	noteRevisionRepository repository.INoteRevisionRepository
+	noteLinkRepository     repository.INoteLinkRepository
+	publisherService       IPublisherService
+	maxDepth               int
+	db                     *pgxpool.Pool
+}
+
+func NewImportService(
+	notebookRepository repository.INotebookRepository,
+	noteRepository repository.INoteRepository,
+	noteRevisionRepository repository.INoteRevisionRepository,
+	noteLinkRepository repository.INoteLinkRepository,
+	publisherService IPublisherService,
+	maxDepth int,
+	db *pgxpool.Pool,
+) IImportService {
+	return &importService{
+		notebookRepository:     notebookRepository,
+		noteRepository:         noteRepository,
+		noteRevisionRepository: noteRevisionRepository,
+		noteLinkRepository:     noteLinkRepository,
+		publisherService:       publisherService,
+		maxDepth:               maxDepth,
+		db:                     db,
+	}
+}
+
+// notebookImport tracks the notebooks created for the folders of one archive.
+type notebookImport struct {
+	req         *dto.ImportRequest
+	baseDepth   int
+	notebookIds map[string]uuid.UUID
+	created     int
+}
+
+// importBatch holds the notes written in one transaction, which are only
+// reported as imported once it is committed.
+type importBatch struct {
+	tx      pgx.Tx
+	notes   []*entity.Note
+	results []*dto.ImportFileResult
+}
+
+// Import creates a notebook per folder and a note per Markdown file of a ZIP
+// archive, such as a Markdown folder, an Obsidian vault or a Notion export.
+// A file that cannot be imported is reported and does not stop the import.
+func (c *importService) Import(ctx context.Context, req *dto.ImportRequest) (*dto.ImportResponse, error) {
+	zipReader, err := zip.NewReader(req.File, req.Size)
+	if err != nil {
+		return nil, serverutils.NewValidationError([]serverutils.ValidationErrorDetail{
+			{Field: "file", Message: "file is not a valid ZIP archive"},
+		})
+	}
+
+	importer := notebookImport{
+		req:         req,
+		notebookIds: make(map[string]uuid.UUID),
+	}
+	if req.NotebookId != nil {
+		ancestorIds, err := c.notebookRepository.GetAncestorIds(ctx, *req.NotebookId)
+		if err != nil {
+			return nil, err
+		}
+		importer.baseDepth = len(ancestorIds)
+		importer.notebookIds[""] = *req.NotebookId
+	}
+
+	files := make([]*zip.File, 0)
+	for _, file := range zipReader.File {
+		if !file.FileInfo().IsDir() {
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	res := dto.ImportResponse{
+		Files: make([]*dto.ImportFileResult, 0),
+	}
+	var batch *importBatch
+	for _, file := range files {
+		filePath := strings.TrimPrefix(path.Clean("/"+file.Name), "/")
+		if notearchive.IsHiddenPath(filePath) || filePath == notearchive.ManifestFileName {
+			continue
+		}
+
+		result := dto.ImportFileResult{
+			Path: filePath,
+		}
+		res.Files = append(res.Files, &result)
+
+		if !notearchive.IsNotePath(filePath) {
+			result.Status = constant.ImportFileStatusSkipped
+			result.Error = "not a Markdown file"
+			res.SkippedCount++
+			continue
+		}
+
+		note, err := c.readNote(ctx, &importer, file, filePath)
+		if err == nil {
+			if batch == nil {
+				tx, err := c.db.Begin(ctx)
+				if err != nil {
+					return nil, err
+				}
+				batch = &importBatch{tx: tx}
+			}
+			err = c.writeNote(ctx, batch.tx, note)
+		}
+		if err != nil {
+			result.Status = constant.ImportFileStatusFailed
+			result.Error = err.Error()
+			res.FailedCount++
+			continue
+		}
+
+		batch.notes = append(batch.notes, note)
+		batch.results = append(batch.results, &result)
+		if len(batch.notes) >= constant.ImportBatchSize {
+			c.commitBatch(ctx, batch, &res)
+			batch = nil
+		}
+	}
+	if batch != nil {
+		c.commitBatch(ctx, batch, &res)
+	}
+
+	res.NotebookCount = importer.created
+	return &res, nil
+}
+
+// commitBatch enqueues the embedding of the notes of batch and commits them
+// together, or reports all of their files as failed.
+func (c *importService) commitBatch(ctx context.Context, batch *importBatch, res *dto.ImportResponse) {
+	defer batch.tx.Rollback(ctx)
+
+	noteIds := make([]uuid.UUID, 0)
+	for _, note := range batch.notes {
+		noteIds = append(noteIds, note.Id)
+	}
+	err := publishEmbedNotes(ctx, c.publisherService.UsingTx(ctx, batch.tx), noteIds)
+	if err == nil {
+		err = batch.tx.Commit(ctx)
+	}
+
+	for i, result := range batch.results {
+		if err != nil {
+			result.Status = constant.ImportFileStatusFailed
+			result.Error = err.Error()
+			res.FailedCount++
+			continue
+		}
+
+		result.Status = constant.ImportFileStatusImported
+		result.NoteId = &batch.notes[i].Id
+		res.NoteCount++
+	}
+}
+
+// readNote reads a Markdown file of the archive into a note, creating the
+// notebooks of its folder.
+func (c *importService) readNote(ctx context.Context, importer *notebookImport, file *zip.File, filePath string) (*entity.Note, error) {
+	if file.UncompressedSize64 > constant.ImportMaxNoteSize {
+		return nil, fmt.Errorf("file is larger than %d bytes", constant.ImportMaxNoteSize)
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, constant.ImportMaxNoteSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > constant.ImportMaxNoteSize {
+		return nil, fmt.Errorf("file is larger than %d bytes", constant.ImportMaxNoteSize)
+	}
+	if !utf8.Valid(data) {
+		return nil, errors.New("file is not valid UTF-8 text")
+	}
+
+	notebookId, err := c.ensureNotebook(ctx, importer, path.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
+
+	frontMatter, body := notearchive.ParseNote(string(data))
+	title := frontMatter.Title
+	if title == "" {
+		title = notearchive.NameFromPath(filePath)
+	}
+	if title == "" {
+		title = "Untitled"
+	}
+	createdAt := file.Modified
+	if frontMatter.CreatedAt != nil {
+		createdAt = *frontMatter.CreatedAt
+	}
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	return &entity.Note{
+		Id:         uuid.New(),
+		Title:      title,
+		Content:    notearchive.FormatExtra(frontMatter, body),
+		NotebookId: notebookId,
+		OwnerId:    serverutils.UserIdFromContext(ctx),
+		CreatedAt:  createdAt,
+		UpdatedAt:  frontMatter.UpdatedAt,
+	}, nil
+}
+
+// writeNote creates note with its first revision and links in a savepoint of
+// tx, so a note that fails leaves the rest of its batch intact.
+func (c *importService) writeNote(ctx context.Context, tx pgx.Tx, note *entity.Note) error {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer savepoint.Rollback(ctx)
+
+	err = c.noteRepository.UsingTx(ctx, savepoint).Create(ctx, note)
+	if err != nil {
+		return err
+	}
+
+	err = c.noteRevisionRepository.UsingTx(ctx, savepoint).Create(ctx, &entity.NoteRevision{
+		Id:         uuid.New(),
+		NoteId:     note.Id,
+		Title:      note.Title,
+		Content:    note.Content,
+		NotebookId: note.NotebookId,
+		OwnerId:    note.OwnerId,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = syncNoteLinks(ctx, c.noteLinkRepository.UsingTx(ctx, savepoint), note)
+	if err != nil {
+		return err
+	}
+
+	return savepoint.Commit(ctx)
+}
+
+// ensureNotebook returns the notebook for an archive folder, creating it and
+// its parents on first use. Files at the archive root go into the target
+// notebook, or into a new notebook named after the archive.
+func (c *importService) ensureNotebook(ctx context.Context, importer *notebookImport, dir string) (uuid.UUID, error) {
+	if dir == "." {
+		dir = ""
+	}
+	if id, ok := importer.notebookIds[dir]; ok {
+		return id, nil
+	}
+
+	var parentId *uuid.UUID
+	name := notearchive.NameFromPath(dir)
+	depth := importer.baseDepth + 1
+	if dir == "" {
+		name = strings.TrimSuffix(notearchive.NameFromPath(importer.req.FileName), ".zip")
+		if name == "" || name == "." {
+			name = "Imported notes"
+		}
+	} else {
+		depth += strings.Count(dir, "/")
+		parentDir := path.Dir(dir)
+		if parentDir != "." {
+			id, err := c.ensureNotebook(ctx, importer, parentDir)
+			if err != nil {
+				return uuid.Nil, err
+			}
+			parentId = &id
+		} else {
+			parentId = importer.req.NotebookId
+		}
+	}
+	if name == "" {
+		name = "Untitled"
+	}
+	if depth > c.maxDepth {
+		return uuid.Nil, fmt.Errorf("folder %q is nested more than %d levels deep", dir, c.maxDepth)
+	}
+
+	notebook := entity.Notebook{
+		Id:        uuid.New(),
+		Name:      name,
+		ParentId:  parentId,
+		OwnerId:   serverutils.UserIdFromContext(ctx),
+		CreatedAt: time.Now(),
+	}
+	err := c.notebookRepository.Create(ctx, &notebook)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	importer.notebookIds[dir] = notebook.Id
+	importer.created++
+	return notebook.Id, nil
+}
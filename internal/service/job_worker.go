@@ -0,0 +1,108 @@
+package service
+
+import (
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/repository"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type ConsumerConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Lease is how long a claimed job stays locked before another worker
+	// may pick it up again, e.g. after a crash.
+	Lease       time.Duration
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// jobWorker polls the jobs of one topic and passes their payload to handle.
+// Failed jobs are retried with exponential backoff until they run out of
+// attempts.
+type jobWorker struct {
+	jobRepository repository.IJobRepository
+	topicName     string
+	config        ConsumerConfig
+	handle        func(ctx context.Context, payload []byte) error
+}
+
+func (w *jobWorker) start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(w.config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			w.processDueJobs(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (w *jobWorker) processDueJobs(ctx context.Context) {
+	for {
+		jobs, err := w.jobRepository.ClaimDue(ctx, w.topicName, w.config.BatchSize, w.config.Lease)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		if len(jobs) == 0 {
+			return
+		}
+
+		for _, job := range jobs {
+			w.processJob(ctx, job)
+		}
+	}
+}
+
+func (w *jobWorker) processJob(ctx context.Context, job *entity.Job) {
+	err := w.handleSafely(ctx, job.Payload)
+	if err == nil {
+		err = w.jobRepository.MarkDone(ctx, job.Id, job.Version)
+		if err != nil {
+			log.Error(err)
+		}
+		return
+	}
+
+	log.Errorf("job %s attempt %d failed: %v", job.Id, job.Attempts, err)
+	if job.Attempts >= job.MaxAttempts {
+		err = w.jobRepository.MarkDead(ctx, job.Id, job.Version, err.Error())
+	} else {
+		err = w.jobRepository.MarkFailed(ctx, job.Id, job.Version, err.Error(), time.Now().Add(w.backoff(job.Attempts)))
+	}
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+func (w *jobWorker) handleSafely(ctx context.Context, payload []byte) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("panic: %v", e)
+		}
+	}()
+
+	return w.handle(ctx, payload)
+}
+
+func (w *jobWorker) backoff(attempts int) time.Duration {
+	delay := w.config.BackoffBase
+	for i := 1; i < attempts && delay < w.config.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > w.config.BackoffMax {
+		delay = w.config.BackoffMax
+	}
+
+	return delay
+}
@@ -1,13 +1,20 @@
 package service
 
 import (
+	"ai-notetaking-be/internal/constant"
 	"ai-notetaking-be/internal/dto"
 	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
 	"ai-notetaking-be/internal/repository"
+	"ai-notetaking-be/pkg/database"
 	"ai-notetaking-be/pkg/embedding"
+	"ai-notetaking-be/pkg/textdiff"
+	"ai-notetaking-be/pkg/wikilink"
 	"context"
 	"encoding/json"
-	"os"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,26 +27,50 @@ type INoteService interface {
 	Update(ctx context.Context, req *dto.UpdateNoteRequest) (*dto.UpdateNoteResponse, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 	MoveNote(ctx context.Context, req *dto.MoveNoteRequest) (*dto.MoveNoteResponse, error)
-	SemanticSearch(ctx context.Context, search string) ([]*dto.SemanticSearchResponse, error)
+	SemanticSearch(ctx context.Context, req *dto.SemanticSearchRequest) ([]*dto.SemanticSearchResponse, error)
+	GetRevisions(ctx context.Context, noteId uuid.UUID) ([]*dto.NoteRevisionResponse, error)
+	ShowRevision(ctx context.Context, noteId uuid.UUID, revisionId uuid.UUID) (*dto.NoteRevisionResponse, error)
+	DiffRevisions(ctx context.Context, req *dto.DiffNoteRevisionRequest) (*dto.DiffNoteRevisionResponse, error)
+	RestoreRevision(ctx context.Context, req *dto.RestoreNoteRevisionRequest) (*dto.RestoreNoteRevisionResponse, error)
+	SetTags(ctx context.Context, req *dto.SetNoteTagsRequest) (*dto.SetNoteTagsResponse, error)
+	GetBacklinks(ctx context.Context, id uuid.UUID) ([]*dto.BacklinkResponse, error)
 }
 
 type noteService struct {
 	noteRepository          repository.INoteRepository
+	notebookRepository      repository.INotebookRepository
 	noteEmbeddingRepository repository.INoteEmbeddingRepository
+	noteRevisionRepository  repository.INoteRevisionRepository
+	tagRepository           repository.ITagRepository
+	noteLinkRepository      repository.INoteLinkRepository
+	attachmentRepository    repository.IAttachmentRepository
 	publisherService        IPublisherService
+	embedder                embedding.Embedder
 	db                      *pgxpool.Pool
 }
 
 func NewNoteService(
 	noteRepository repository.INoteRepository,
+	notebookRepository repository.INotebookRepository,
 	publisherService IPublisherService,
 	noteEmbeddingRepository repository.INoteEmbeddingRepository,
+	noteRevisionRepository repository.INoteRevisionRepository,
+	tagRepository repository.ITagRepository,
+	noteLinkRepository repository.INoteLinkRepository,
+	attachmentRepository repository.IAttachmentRepository,
+	embedder embedding.Embedder,
 	db *pgxpool.Pool,
 ) INoteService {
 	return &noteService{
 		noteRepository:          noteRepository,
+		notebookRepository:      notebookRepository,
 		noteEmbeddingRepository: noteEmbeddingRepository,
+		noteRevisionRepository:  noteRevisionRepository,
+		tagRepository:           tagRepository,
+		noteLinkRepository:      noteLinkRepository,
+		attachmentRepository:    attachmentRepository,
 		publisherService:        publisherService,
+		embedder:                embedder,
 		db:                      db,
 	}
 }
@@ -50,23 +81,58 @@ func (c *noteService) Create(ctx context.Context, req *dto.CreateNoteRequest) (*
 		Title:      req.Title,
 		Content:    req.Content,
 		NotebookId: req.NotebookId,
+		OwnerId:    serverutils.UserIdFromContext(ctx),
 		CreatedAt:  time.Now(),
 	}
 
-	err := c.noteRepository.Create(ctx, &note)
+	_, err := c.notebookRepository.GetById(ctx, req.NotebookId)
 	if err != nil {
 		return nil, err
 	}
 
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = c.noteRepository.UsingTx(ctx, tx).Create(ctx, &note)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.createRevision(ctx, c.noteRevisionRepository.UsingTx(ctx, tx), &note)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syncNoteLinks(ctx, c.noteLinkRepository.UsingTx(ctx, tx), &note)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Tags != nil {
+		_, err = c.setTags(ctx, c.tagRepository.UsingTx(ctx, tx), note.Id, req.Tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	msgPayload := dto.PublishEmbedNoteMessage{
-		NoteId: note.Id,
+		NoteId:  note.Id,
+		OwnerId: note.OwnerId,
 	}
 	msgJson, err := json.Marshal(msgPayload)
 	if err != nil {
 		return nil, err
 	}
 
-	err = c.publisherService.Publish(ctx, msgJson)
+	err = c.publisherService.UsingTx(ctx, tx).Publish(ctx, note.Id.String(), msgJson)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -82,11 +148,17 @@ func (c *noteService) Show(ctx context.Context, id uuid.UUID) (*dto.ShowNoteResp
 		return nil, err
 	}
 
+	tagsByNoteId, err := c.tagRepository.GetByNoteIds(ctx, []uuid.UUID{note.Id})
+	if err != nil {
+		return nil, err
+	}
+
 	res := dto.ShowNoteResponse{
 		Id:         note.Id,
 		Title:      note.Title,
 		Content:    note.Content,
 		NotebookId: note.NotebookId,
+		Tags:       toTagResponses(tagsByNoteId[note.Id]),
 		CreatedAt:  note.CreatedAt,
 		UpdatedAt:  note.UpdatedAt,
 	}
@@ -95,144 +167,651 @@ func (c *noteService) Show(ctx context.Context, id uuid.UUID) (*dto.ShowNoteResp
 }
 
 func (c *noteService) Update(ctx context.Context, req *dto.UpdateNoteRequest) (*dto.UpdateNoteResponse, error) {
-	note, err := c.noteRepository.GetById(ctx, req.Id)
+	_, err := c.updateWithRevision(ctx, req.Id, func(note *entity.Note) {
+		note.Title = req.Title
+		note.Content = req.Content
+	}, req.Tags)
 	if err != nil {
 		return nil, err
 	}
 
-	now := time.Now()
+	return &dto.UpdateNoteResponse{
+		Id: req.Id,
+	}, nil
+}
 
-	note.Title = req.Title
-	note.Content = req.Content
-	note.UpdatedAt = &now
+func (c *noteService) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := c.noteRepository.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	noteRepository := c.noteRepository.UsingTx(ctx, tx)
+	noteEmbeddingRepository := c.noteEmbeddingRepository.UsingTx(ctx, tx)
+
+	deletedAt := time.Now()
+	err = noteRepository.Delete(ctx, id, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	err = noteEmbeddingRepository.DeleteByNoteId(ctx, id, deletedAt)
+	if err != nil {
+		return err
+	}
 
-	err = c.noteRepository.Update(ctx, note)
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *noteService) MoveNote(ctx context.Context, req *dto.MoveNoteRequest) (*dto.MoveNoteResponse, error) {
+	_, err := c.notebookRepository.GetById(ctx, req.NotebookId)
 	if err != nil {
 		return nil, err
 	}
 
-	payload := dto.PublishEmbedNoteMessage{
-		NoteId: note.Id,
+	_, err = c.updateWithRevision(ctx, req.Id, func(note *entity.Note) {
+		note.NotebookId = req.NotebookId
+	}, nil)
+	if err != nil {
+		return nil, err
 	}
-	payloadJson, err := json.Marshal(payload)
+
+	return &dto.MoveNoteResponse{
+		Id: req.Id,
+	}, nil
+}
+
+func (c *noteService) GetRevisions(ctx context.Context, noteId uuid.UUID) ([]*dto.NoteRevisionResponse, error) {
+	_, err := c.noteRepository.GetById(ctx, noteId)
 	if err != nil {
 		return nil, err
 	}
-	err = c.publisherService.Publish(ctx, payloadJson)
+
+	noteRevisions, err := c.noteRevisionRepository.GetByNoteId(ctx, noteId)
 	if err != nil {
 		return nil, err
 	}
 
-	return &dto.UpdateNoteResponse{
-		Id: note.Id,
+	res := make([]*dto.NoteRevisionResponse, 0)
+	for _, noteRevision := range noteRevisions {
+		res = append(res, &dto.NoteRevisionResponse{
+			Id:             noteRevision.Id,
+			NoteId:         noteRevision.NoteId,
+			RevisionNumber: noteRevision.RevisionNumber,
+			Title:          noteRevision.Title,
+			NotebookId:     noteRevision.NotebookId,
+			CreatedAt:      noteRevision.CreatedAt,
+		})
+	}
+
+	return res, nil
+}
+
+func (c *noteService) ShowRevision(ctx context.Context, noteId uuid.UUID, revisionId uuid.UUID) (*dto.NoteRevisionResponse, error) {
+	noteRevision, err := c.noteRevisionRepository.GetById(ctx, noteId, revisionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.NoteRevisionResponse{
+		Id:             noteRevision.Id,
+		NoteId:         noteRevision.NoteId,
+		RevisionNumber: noteRevision.RevisionNumber,
+		Title:          noteRevision.Title,
+		Content:        noteRevision.Content,
+		NotebookId:     noteRevision.NotebookId,
+		CreatedAt:      noteRevision.CreatedAt,
 	}, nil
 }
 
-func (c *noteService) Delete(ctx context.Context, id uuid.UUID) error {
-	_, err := c.noteRepository.GetById(ctx, id)
+func (c *noteService) DiffRevisions(ctx context.Context, req *dto.DiffNoteRevisionRequest) (*dto.DiffNoteRevisionResponse, error) {
+	from, err := c.noteRevisionRepository.GetById(ctx, req.NoteId, req.FromRevisionId)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	to, err := c.noteRevisionRepository.GetById(ctx, req.NoteId, req.ToRevisionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.DiffNoteRevisionResponse{
+		FromRevisionNumber: from.RevisionNumber,
+		ToRevisionNumber:   to.RevisionNumber,
+		FromTitle:          from.Title,
+		ToTitle:            to.Title,
+		Diff: textdiff.Unified(
+			fmt.Sprintf("revision %d", from.RevisionNumber),
+			fmt.Sprintf("revision %d", to.RevisionNumber),
+			from.Content,
+			to.Content,
+			3,
+		),
+	}, nil
+}
+
+// RestoreRevision brings back the title and content of a revision as a new
+// revision. The note stays in its current notebook.
+func (c *noteService) RestoreRevision(ctx context.Context, req *dto.RestoreNoteRevisionRequest) (*dto.RestoreNoteRevisionResponse, error) {
+	noteRevision, err := c.noteRevisionRepository.GetById(ctx, req.NoteId, req.RevisionId)
+	if err != nil {
+		return nil, err
+	}
+
+	restored, err := c.updateWithRevision(ctx, req.NoteId, func(note *entity.Note) {
+		note.Title = noteRevision.Title
+		note.Content = noteRevision.Content
+	}, nil)
+	if err != nil {
+		return nil, err
 	}
 
+	return &dto.RestoreNoteRevisionResponse{
+		Id:             req.NoteId,
+		RevisionNumber: restored.RevisionNumber,
+	}, nil
+}
+
+// updateWithRevision applies change to the note read under its row lock,
+// saves it and records its new state as a revision in the same transaction.
+// The tags are replaced unless tagNames is nil. When the title changed, links
+// to the note in other notes are rewritten. The note and the rewritten notes
+// are queued for embedding in the same transaction.
+func (c *noteService) updateWithRevision(ctx context.Context, id uuid.UUID, change func(note *entity.Note), tagNames []string) (*entity.NoteRevision, error) {
 	tx, err := c.db.Begin(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
-	noteRepository := c.noteRepository.UsingTx(ctx, tx)
-	noteEmbeddingRepository := c.noteEmbeddingRepository.UsingTx(ctx, tx)
+	// The revision number is the next one in the note's history, concurrent
+	// writes to the same note have to wait for this one to commit. The note
+	// is read after the lock so fields this write leaves alone keep the values
+	// committed by those writes.
+	err = c.noteRepository.UsingTx(ctx, tx).LockById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	note, err := c.noteRepository.UsingTx(ctx, tx).GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
-	err = noteRepository.Delete(ctx, id)
+	now := time.Now()
+	previousTitle := note.Title
+	change(note)
+	note.UpdatedAt = &now
+
+	err = c.noteRepository.UsingTx(ctx, tx).Update(ctx, note)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = noteEmbeddingRepository.DeleteByNoteId(ctx, id)
+	noteRevision, err := c.createRevision(ctx, c.noteRevisionRepository.UsingTx(ctx, tx), note)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if tagNames != nil {
+		_, err = c.setTags(ctx, c.tagRepository.UsingTx(ctx, tx), note.Id, tagNames)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = syncNoteLinks(ctx, c.noteLinkRepository.UsingTx(ctx, tx), note)
+	if err != nil {
+		return nil, err
+	}
+
+	relinkedNoteIds := make([]uuid.UUID, 0)
+	if note.Title != previousTitle {
+		relinkedNoteIds, err = c.renameLinks(ctx, tx, note, previousTitle)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = publishEmbedNotes(ctx, c.publisherService.UsingTx(ctx, tx), append([]uuid.UUID{note.Id}, relinkedNoteIds...))
+	if err != nil {
+		return nil, err
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return noteRevision, nil
 }
 
-func (c *noteService) MoveNote(ctx context.Context, req *dto.MoveNoteRequest) (*dto.MoveNoteResponse, error) {
-	note, err := c.noteRepository.GetById(ctx, req.Id)
+// renameLinks rewrites [[previousTitle]] to the new title of note in every
+// live note whose link with that title points at note, recording a revision
+// for each changed note. A new title that cannot be written as a link is
+// rejected while such notes exist.
+func (c *noteService) renameLinks(ctx context.Context, tx database.DatabaseQueryer, note *entity.Note, previousTitle string) ([]uuid.UUID, error) {
+	noteRepository := c.noteRepository.UsingTx(ctx, tx)
+	noteRevisionRepository := c.noteRevisionRepository.UsingTx(ctx, tx)
+	noteLinkRepository := c.noteLinkRepository.UsingTx(ctx, tx)
+
+	noteLinks, err := noteLinkRepository.GetByTargetId(ctx, note.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// A source can still point at note through an older title, its
+	// [[previousTitle]] links then belong to another note.
+	sourceIds := make([]uuid.UUID, 0)
+	for _, noteLink := range noteLinks {
+		if noteLink.SourceNoteId != note.Id && strings.EqualFold(noteLink.TargetTitle, previousTitle) {
+			sourceIds = append(sourceIds, noteLink.SourceNoteId)
+		}
+	}
+	if len(sourceIds) > 0 && !wikilink.CanLink(note.Title) {
+		return nil, serverutils.NewValidationError([]serverutils.ValidationErrorDetail{
+			{Field: "title", Message: "title of a linked note cannot contain [, ], |, # or line breaks"},
+		})
+	}
+
+	err = noteLinkRepository.RetargetTitle(ctx, note.Id, previousTitle, note.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	// The linking notes are locked so a concurrent edit of one of them cannot
+	// be overwritten by the rewritten content read here.
+	sources, err := noteRepository.GetByIdsForUpdate(ctx, sourceIds)
 	if err != nil {
 		return nil, err
 	}
 
 	now := time.Now()
-	note.NotebookId = req.NotebookId
-	note.UpdatedAt = &now
+	relinkedNoteIds := make([]uuid.UUID, 0)
+	for _, source := range sources {
+		content := wikilink.Rename(source.Content, previousTitle, note.Title)
+		if content == source.Content {
+			continue
+		}
+
+		source.Content = content
+		source.UpdatedAt = &now
+		err = noteRepository.Update(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = c.createRevision(ctx, noteRevisionRepository, source)
+		if err != nil {
+			return nil, err
+		}
+
+		err = syncNoteLinks(ctx, noteLinkRepository, source)
+		if err != nil {
+			return nil, err
+		}
+
+		relinkedNoteIds = append(relinkedNoteIds, source.Id)
+	}
+
+	return relinkedNoteIds, nil
+}
+
+func (c *noteService) GetBacklinks(ctx context.Context, id uuid.UUID) ([]*dto.BacklinkResponse, error) {
+	_, err := c.noteRepository.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	noteLinks, err := c.noteLinkRepository.GetByTargetId(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceIds := make([]uuid.UUID, 0)
+	for _, noteLink := range noteLinks {
+		sourceIds = append(sourceIds, noteLink.SourceNoteId)
+	}
+	sources, err := c.noteRepository.GetByIds(ctx, sourceIds)
+	if err != nil {
+		return nil, err
+	}
+	sourcesById := make(map[uuid.UUID]*entity.Note)
+	for _, source := range sources {
+		sourcesById[source.Id] = source
+	}
+
+	res := make([]*dto.BacklinkResponse, 0)
+	for _, noteLink := range noteLinks {
+		source, ok := sourcesById[noteLink.SourceNoteId]
+		if !ok {
+			continue
+		}
+
+		res = append(res, &dto.BacklinkResponse{
+			Id:         source.Id,
+			Title:      source.Title,
+			NotebookId: source.NotebookId,
+			Excerpt:    linkExcerpt(source.Content, noteLink.TargetTitle),
+			CreatedAt:  source.CreatedAt,
+			UpdatedAt:  source.UpdatedAt,
+		})
+	}
+
+	return res, nil
+}
+
+// syncNoteLinks stores the [[...]] links written in the note and resolves the
+// dangling links to its title.
+func syncNoteLinks(ctx context.Context, noteLinkRepository repository.INoteLinkRepository, note *entity.Note) error {
+	err := noteLinkRepository.ReplaceBySourceId(ctx, note.Id, wikilink.Titles(note.Content))
+	if err != nil {
+		return err
+	}
+
+	return noteLinkRepository.ResolveTitle(ctx, note.Id, note.Title)
+}
+
+// linkExcerpt returns the text around the first link to title in content.
+func linkExcerpt(content string, title string) string {
+	for _, link := range wikilink.Parse(content) {
+		if !strings.EqualFold(link.Title, title) {
+			continue
+		}
+
+		before := []rune(content[:link.Start])
+		start := len(before) - constant.BacklinkExcerptContext
+		if start <= 0 {
+			return excerpt(content, constant.NoteExcerptLength)
+		}
+
+		return "…" + excerpt(string([]rune(content)[start:]), constant.NoteExcerptLength)
+	}
 
-	err = c.noteRepository.Update(ctx, note)
+	return excerpt(content, constant.NoteExcerptLength)
+}
+
+func (c *noteService) SetTags(ctx context.Context, req *dto.SetNoteTagsRequest) (*dto.SetNoteTagsResponse, error) {
+	note, err := c.noteRepository.GetById(ctx, req.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	payload := dto.PublishEmbedNoteMessage{
-		NoteId: note.Id,
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tags, err := c.setTags(ctx, c.tagRepository.UsingTx(ctx, tx), note.Id, req.Tags)
+	if err != nil {
+		return nil, err
 	}
-	payloadJson, err := json.Marshal(payload)
+
+	err = publishEmbedNotes(ctx, c.publisherService.UsingTx(ctx, tx), []uuid.UUID{note.Id})
 	if err != nil {
 		return nil, err
 	}
-	err = c.publisherService.Publish(ctx, payloadJson)
+
+	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return &dto.MoveNoteResponse{
-		Id: note.Id,
+	return &dto.SetNoteTagsResponse{
+		Id:   note.Id,
+		Tags: toTagResponses(tags),
 	}, nil
 }
 
-func (c *noteService) SemanticSearch(ctx context.Context, search string) ([]*dto.SemanticSearchResponse, error) {
-	embeddingRes, err := embedding.GetGeminiEmbedding(
-		os.Getenv("GOOGLE_GEMINI_API_KEY"),
-		search,
-		"RETRIEVAL_QUERY",
-	)
+func (c *noteService) setTags(ctx context.Context, tagRepository repository.ITagRepository, noteId uuid.UUID, tagNames []string) ([]*entity.Tag, error) {
+	tags, err := ensureTags(ctx, tagRepository, tagNames)
 	if err != nil {
 		return nil, err
 	}
 
-	noteEmbeddings, err := c.noteEmbeddingRepository.SemanticSearch(ctx, embeddingRes.Embedding.Values)
+	tagIds := make([]uuid.UUID, 0)
+	for _, tag := range tags {
+		tagIds = append(tagIds, tag.Id)
+	}
+	err = tagRepository.SetNoteTags(ctx, noteId, tagIds)
 	if err != nil {
 		return nil, err
 	}
 
-	ids := make([]uuid.UUID, 0)
-	for _, noteEmbedding := range noteEmbeddings {
-		ids = append(ids, noteEmbedding.NoteId)
+	return tags, nil
+}
+
+func (c *noteService) createRevision(ctx context.Context, noteRevisionRepository repository.INoteRevisionRepository, note *entity.Note) (*entity.NoteRevision, error) {
+	noteRevision := entity.NoteRevision{
+		Id:         uuid.New(),
+		NoteId:     note.Id,
+		Title:      note.Title,
+		Content:    note.Content,
+		NotebookId: note.NotebookId,
+		OwnerId:    note.OwnerId,
+		CreatedAt:  time.Now(),
 	}
 
-	notes, err := c.noteRepository.GetByIds(ctx, ids)
+	err := noteRevisionRepository.Create(ctx, &noteRevision)
 	if err != nil {
 		return nil, err
 	}
 
+	return &noteRevision, nil
+}
+
+// SemanticSearch ranks notes by keyword match, embedding similarity or, in
+// hybrid mode, both rankings merged with reciprocal rank fusion.
+func (c *noteService) SemanticSearch(ctx context.Context, req *dto.SemanticSearchRequest) ([]*dto.SemanticSearchResponse, error) {
 	response := make([]*dto.SemanticSearchResponse, 0)
-	for _, noteEmbedding := range noteEmbeddings {
-		for _, note := range notes {
-			if noteEmbedding.NoteId == note.Id {
-				response = append(response, &dto.SemanticSearchResponse{
-					Id:         note.Id,
-					Title:      note.Title,
-					Content:    note.Content,
-					NotebookId: note.NotebookId,
-					CreatedAt:  note.CreatedAt,
-					UpdatedAt:  note.UpdatedAt,
-				})
+	if strings.TrimSpace(req.Query) == "" {
+		return response, nil
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = constant.SearchModeHybrid
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = constant.SearchDefaultLimit
+	}
+	window := req.Offset + limit
+
+	filter := repository.NoteSearchFilter{
+		NotebookId: req.NotebookId,
+	}
+	if len(req.Tags) > 0 {
+		tagIds, err := resolveTagIds(ctx, c.tagRepository, req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		if tagIds == nil {
+			return response, nil
+		}
+		filter.TagIds = tagIds
+	}
+
+	rankings := make([][]uuid.UUID, 0)
+	if mode != constant.SearchModeSemantic {
+		keywordNotes, err := c.noteRepository.KeywordSearch(ctx, req.Query, filter, window)
+		if err != nil {
+			return nil, err
+		}
+
+		ranking := make([]uuid.UUID, 0)
+		for _, note := range keywordNotes {
+			ranking = append(ranking, note.Id)
+		}
+		rankings = append(rankings, ranking)
+	}
+
+	noteEmbeddingsByNoteId := make(map[uuid.UUID]*entity.NoteEmbedding)
+	attachmentTexts := make(map[uuid.UUID]*entity.AttachmentText)
+	if mode != constant.SearchModeKeyword {
+		embeddingValues, err := c.embedder.Embed(
+			ctx,
+			req.Query,
+			embedding.TaskTypeRetrievalQuery,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		noteEmbeddings, err := c.noteEmbeddingRepository.SemanticSearch(ctx, embeddingValues, filter, window)
+		if err != nil {
+			return nil, err
+		}
+
+		ranking := make([]uuid.UUID, 0)
+		for _, noteEmbedding := range noteEmbeddings {
+			ranking = append(ranking, noteEmbedding.NoteId)
+			noteEmbeddingsByNoteId[noteEmbedding.NoteId] = noteEmbedding
+		}
+		rankings = append(rankings, ranking)
+
+		attachmentTexts, err = getAttachmentTexts(ctx, c.attachmentRepository, noteEmbeddings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ids, scores := reciprocalRankFusion(rankings...)
+	if req.Offset >= len(ids) {
+		return response, nil
+	}
+	ids = ids[req.Offset:]
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	notes, err := c.noteRepository.GetByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	notesById := make(map[uuid.UUID]*entity.Note)
+	for _, note := range notes {
+		notesById[note.Id] = note
+	}
+	tagsByNoteId, err := c.tagRepository.GetByNoteIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		note, ok := notesById[id]
+		if !ok {
+			continue
+		}
+
+		res := dto.SemanticSearchResponse{
+			Id:         note.Id,
+			Title:      note.Title,
+			Content:    note.Content,
+			Score:      scores[id],
+			NotebookId: note.NotebookId,
+			Tags:       toTagResponses(tagsByNoteId[id]),
+			CreatedAt:  note.CreatedAt,
+			UpdatedAt:  note.UpdatedAt,
+		}
+		if noteEmbedding, ok := noteEmbeddingsByNoteId[id]; ok {
+			res.Snippet = embeddingSnippet(note, attachmentTexts, noteEmbedding)
+			res.ChunkIndex = noteEmbedding.ChunkIndex
+			if attachmentText, ok := attachmentTexts[noteEmbeddingAttachmentId(noteEmbedding)]; ok {
+				res.AttachmentId = &attachmentText.AttachmentId
+				res.AttachmentFileName = &attachmentText.FileName
 			}
 		}
+		response = append(response, &res)
 	}
 
 	return response, nil
 }
+
+// reciprocalRankFusion merges rankings by summing 1 / (k + rank) of every
+// id across them, and returns the ids ordered by that score.
+func reciprocalRankFusion(rankings ...[]uuid.UUID) ([]uuid.UUID, map[uuid.UUID]float64) {
+	scores := make(map[uuid.UUID]float64)
+	ids := make([]uuid.UUID, 0)
+	for _, ranking := range rankings {
+		for i, id := range ranking {
+			if _, ok := scores[id]; !ok {
+				ids = append(ids, id)
+			}
+			scores[id] += 1 / float64(constant.ReciprocalRankFusionK+i+1)
+		}
+	}
+
+	sort.SliceStable(ids, func(i, j int) bool {
+		return scores[ids[i]] > scores[ids[j]]
+	})
+
+	return ids, scores
+}
+
+// getAttachmentTexts loads the extracted text of the attachments that the
+// embeddings were chunked from, keyed by attachment id.
+func getAttachmentTexts(ctx context.Context, attachmentRepository repository.IAttachmentRepository, noteEmbeddings []*entity.NoteEmbedding) (map[uuid.UUID]*entity.AttachmentText, error) {
+	attachmentIds := make([]uuid.UUID, 0)
+	for _, noteEmbedding := range noteEmbeddings {
+		if noteEmbedding.AttachmentId != nil {
+			attachmentIds = append(attachmentIds, *noteEmbedding.AttachmentId)
+		}
+	}
+
+	attachmentTexts, err := attachmentRepository.GetTextsByIds(ctx, attachmentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[uuid.UUID]*entity.AttachmentText)
+	for _, attachmentText := range attachmentTexts {
+		res[attachmentText.AttachmentId] = attachmentText
+	}
+
+	return res, nil
+}
+
+func noteEmbeddingAttachmentId(noteEmbedding *entity.NoteEmbedding) uuid.UUID {
+	if noteEmbedding.AttachmentId == nil {
+		return uuid.Nil
+	}
+
+	return *noteEmbedding.AttachmentId
+}
+
+// embeddingSnippet returns the chunk text of the embedding, taken from the
+// attachment's extracted text for attachment chunks.
+func embeddingSnippet(note *entity.Note, attachmentTexts map[uuid.UUID]*entity.AttachmentText, noteEmbedding *entity.NoteEmbedding) string {
+	if noteEmbedding.AttachmentId == nil {
+		return chunkSnippet(note.Content, noteEmbedding)
+	}
+
+	attachmentText, ok := attachmentTexts[*noteEmbedding.AttachmentId]
+	if !ok {
+		return ""
+	}
+
+	return chunkSnippet(attachmentText.Text, noteEmbedding)
+}
+
+func chunkSnippet(content string, noteEmbedding *entity.NoteEmbedding) string {
+	runes := []rune(content)
+	start := noteEmbedding.StartOffset
+	end := noteEmbedding.EndOffset
+	if start < 0 || end > len(runes) || start >= end {
+		return ""
+	}
+
+	return string(runes[start:end])
+}
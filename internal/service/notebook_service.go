@@ -1,11 +1,15 @@
 package service
 
 import (
+	"ai-notetaking-be/internal/constant"
 	"ai-notetaking-be/internal/dto"
 	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
 	"ai-notetaking-be/internal/repository"
 	"context"
-	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,19 +18,26 @@ import (
 )
 
 type INotebookService interface {
-	GetAll(ctx context.Context) ([]*dto.GetAllNotebookResponse, error)
+	GetAll(ctx context.Context, req *dto.GetAllNotebookRequest) ([]*dto.GetAllNotebookResponse, error)
 	Create(ctx context.Context, req *dto.CreateNotebookRequest) (*dto.CreateNotebookResponse, error)
 	Show(ctx context.Context, id uuid.UUID) (*dto.ShowNotebookResponse, error)
 	Update(ctx context.Context, req *dto.UpdateNotebookRequest) (*dto.UpdateNotebookResponse, error)
-	Delete(ctx context.Context, id uuid.UUID) error
+	Delete(ctx context.Context, req *dto.DeleteNotebookRequest) error
 	MoveNotebook(ctx context.Context, req *dto.MoveNotebookRequest) (*dto.MoveNotebookResponse, error)
+	GetTree(ctx context.Context, id uuid.UUID) (*dto.NotebookTreeResponse, error)
+	GetAllTree(ctx context.Context, req *dto.GetNotebookTreeRequest) ([]*dto.NotebookTreeResponse, error)
+	GetNotes(ctx context.Context, req *dto.GetNotebookNotesRequest) (*dto.GetNotebookNotesResponse, error)
+	GetGraph(ctx context.Context, id uuid.UUID) (*dto.NotebookGraphResponse, error)
 }
 
 type notebookService struct {
 	notebookRepository      repository.INotebookRepository
 	noteRepository          repository.INoteRepository
 	noteEmbeddingRepository repository.INoteEmbeddingRepository
+	tagRepository           repository.ITagRepository
+	noteLinkRepository      repository.INoteLinkRepository
 	publisherService        IPublisherService
+	maxDepth                int
 	db                      *pgxpool.Pool
 }
 
@@ -36,6 +47,9 @@ func NewNotebookService(
 	db *pgxpool.Pool,
 	publisherService IPublisherService,
 	noteEmbeddingRepository repository.INoteEmbeddingRepository,
+	tagRepository repository.ITagRepository,
+	noteLinkRepository repository.INoteLinkRepository,
+	maxDepth int,
 ) INotebookService {
 	return &notebookService{
 		notebookRepository:      notebookRepository,
@@ -43,10 +57,13 @@ func NewNotebookService(
 		db:                      db,
 		publisherService:        publisherService,
 		noteEmbeddingRepository: noteEmbeddingRepository,
+		tagRepository:           tagRepository,
+		noteLinkRepository:      noteLinkRepository,
+		maxDepth:                maxDepth,
 	}
 }
 
-func (c *notebookService) GetAll(ctx context.Context) ([]*dto.GetAllNotebookResponse, error) {
+func (c *notebookService) GetAll(ctx context.Context, req *dto.GetAllNotebookRequest) ([]*dto.GetAllNotebookResponse, error) {
 	notebooks, err := c.notebookRepository.GetAll(ctx)
 	if err != nil {
 		return nil, err
@@ -73,13 +90,37 @@ func (c *notebookService) GetAll(ctx context.Context) ([]*dto.GetAllNotebookResp
 		return nil, err
 	}
 
+	noteIds := make([]uuid.UUID, 0)
+	for _, note := range notes {
+		noteIds = append(noteIds, note.Id)
+	}
+	tagsByNoteId, err := c.tagRepository.GetByNoteIds(ctx, noteIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var filterTagIds []uuid.UUID
+	if len(req.Tags) > 0 {
+		filterTagIds, err = resolveTagIds(ctx, c.tagRepository, req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		if filterTagIds == nil {
+			notes = nil
+		}
+	}
+
 	for i := 0; i < len(result); i++ {
 		for j := 0; j < len(notes); j++ {
+			if !hasAllTags(tagsByNoteId[notes[j].Id], filterTagIds) {
+				continue
+			}
 			if notes[j].NotebookId == result[i].Id {
 				result[i].Notes = append(result[i].Notes, &dto.GetAllNotebookResponseNote{
 					Id:        notes[j].Id,
 					Title:     notes[j].Title,
 					Content:   notes[j].Content,
+					Tags:      toTagResponses(tagsByNoteId[notes[j].Id]),
 					CreatedAt: notes[j].CreatedAt,
 					UpdatedAt: notes[j].UpdatedAt,
 				})
@@ -95,10 +136,41 @@ func (c *notebookService) Create(ctx context.Context, req *dto.CreateNotebookReq
 		Id:        uuid.New(),
 		Name:      req.Name,
 		ParentId:  req.ParentId,
+		OwnerId:   serverutils.UserIdFromContext(ctx),
 		CreatedAt: time.Now(),
 	}
 
-	err := c.notebookRepository.Create(ctx, &notebook)
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	notebookRepository := c.notebookRepository.UsingTx(ctx, tx)
+
+	if req.ParentId != nil {
+		// The lock keeps the parent from being moved deeper before the
+		// notebook is created under it.
+		err = notebookRepository.LockTree(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		ancestorIds, err := notebookRepository.GetAncestorIds(ctx, *req.ParentId)
+		if err != nil {
+			return nil, err
+		}
+		if len(ancestorIds)+1 > c.maxDepth {
+			return nil, maxDepthError(c.maxDepth)
+		}
+	}
+
+	err = notebookRepository.Create(ctx, &notebook)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -135,31 +207,34 @@ func (c *notebookService) Update(ctx context.Context, req *dto.UpdateNotebookReq
 	notebook.Name = req.Name
 	notebook.UpdatedAt = &now
 
-	err = c.notebookRepository.Update(ctx, notebook)
+	tx, err := c.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
 
-	notes, err := c.noteRepository.GetByNotebookIds(ctx, []uuid.UUID{notebook.Id})
+	err = c.notebookRepository.UsingTx(ctx, tx).Update(ctx, notebook)
 	if err != nil {
 		return nil, err
 	}
 
+	notes, err := c.noteRepository.UsingTx(ctx, tx).GetByNotebookIds(ctx, []uuid.UUID{notebook.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	noteIds := make([]uuid.UUID, 0)
 	for _, note := range notes {
-		msg := dto.PublishEmbedNoteMessage{
-			NoteId: note.Id,
-		}
-		msgJson, err := json.Marshal(msg)
-		if err != nil {
-			return nil, err
-		}
-		err = c.publisherService.Publish(
-			ctx,
-			msgJson,
-		)
-		if err != nil {
-			return nil, err
-		}
+		noteIds = append(noteIds, note.Id)
+	}
+	err = publishEmbedNotes(ctx, c.publisherService.UsingTx(ctx, tx), noteIds)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	res := dto.UpdateNotebookResponse{
@@ -169,8 +244,11 @@ func (c *notebookService) Update(ctx context.Context, req *dto.UpdateNotebookReq
 	return &res, nil
 }
 
-func (c *notebookService) Delete(ctx context.Context, id uuid.UUID) error {
-	_, err := c.notebookRepository.GetById(ctx, id)
+// Delete moves a notebook and its notes to the trash. Its child notebooks are
+// moved to the root, unless req.Cascade is set in which case the whole
+// subtree is trashed with it.
+func (c *notebookService) Delete(ctx context.Context, req *dto.DeleteNotebookRequest) error {
+	_, err := c.notebookRepository.GetById(ctx, req.Id)
 	if err != nil {
 		return err
 	}
@@ -185,22 +263,44 @@ func (c *notebookService) Delete(ctx context.Context, id uuid.UUID) error {
 	noteRepo := c.noteRepository.UsingTx(ctx, tx)
 	noteEmbeddingRepo := c.noteEmbeddingRepository.UsingTx(ctx, tx)
 
-	err = notebookRepo.DeleteById(ctx, id)
+	err = notebookRepo.LockTree(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = noteEmbeddingRepo.DeleteByNotebookId(ctx, id)
+	ids := []uuid.UUID{req.Id}
+	if req.Cascade {
+		subtree, err := notebookRepo.GetSubtree(ctx, req.Id)
+		if err != nil {
+			return err
+		}
+
+		ids = make([]uuid.UUID, 0)
+		for _, notebook := range subtree {
+			ids = append(ids, notebook.Id)
+		}
+	}
+
+	// Everything trashed here shares deletedAt so it can be restored together.
+	deletedAt := time.Now()
+	err = notebookRepo.DeleteByIds(ctx, ids, deletedAt)
 	if err != nil {
 		return err
 	}
 
-	err = notebookRepo.NullifyParentById(ctx, id)
+	err = noteEmbeddingRepo.DeleteByNotebookIds(ctx, ids, deletedAt)
 	if err != nil {
 		return err
 	}
 
-	err = noteRepo.DeleteByNotebookId(ctx, id)
+	if !req.Cascade {
+		err = notebookRepo.DetachChildren(ctx, req.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = noteRepo.DeleteByNotebookIds(ctx, ids, deletedAt)
 	if err != nil {
 		return err
 	}
@@ -213,19 +313,59 @@ func (c *notebookService) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// MoveNotebook re-parents a notebook, rejecting moves that would make it its
+// own ancestor or nest its subtree deeper than the maximum depth.
 func (c *notebookService) MoveNotebook(ctx context.Context, req *dto.MoveNotebookRequest) (*dto.MoveNotebookResponse, error) {
-	_, err := c.notebookRepository.GetById(ctx, req.Id)
+	if req.ParentId != nil && *req.ParentId == req.Id {
+		return nil, serverutils.NewValidationError([]serverutils.ValidationErrorDetail{
+			{Field: "parent_id", Message: "a notebook cannot be its own parent"},
+		})
+	}
+
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	notebookRepository := c.notebookRepository.UsingTx(ctx, tx)
+
+	err = notebookRepository.LockTree(ctx)
 	if err != nil {
 		return nil, err
 	}
+
+	subtree, err := notebookRepository.GetSubtree(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	parentDepth := 0
 	if req.ParentId != nil {
-		_, err = c.notebookRepository.GetById(ctx, *req.ParentId)
+		ancestorIds, err := notebookRepository.GetAncestorIds(ctx, *req.ParentId)
 		if err != nil {
 			return nil, err
 		}
+		for _, ancestorId := range ancestorIds {
+			if ancestorId == req.Id {
+				return nil, serverutils.NewValidationError([]serverutils.ValidationErrorDetail{
+					{Field: "parent_id", Message: "a notebook cannot be moved into its own descendant"},
+				})
+			}
+		}
+		parentDepth = len(ancestorIds)
+	}
+
+	if parentDepth+subtreeHeight(req.Id, subtree) > c.maxDepth {
+		return nil, maxDepthError(c.maxDepth)
 	}
 
-	err = c.notebookRepository.UpdateParentId(ctx, req.Id, req.ParentId)
+	err = notebookRepository.UpdateParentId(ctx, req.Id, req.ParentId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -234,3 +374,255 @@ func (c *notebookService) MoveNotebook(ctx context.Context, req *dto.MoveNoteboo
 		Id: req.Id,
 	}, nil
 }
+
+// subtreeHeight returns the number of levels in subtree, which must be
+// ordered by depth as returned by GetSubtree, counting rootId as one.
+func subtreeHeight(rootId uuid.UUID, subtree []*entity.Notebook) int {
+	depths := map[uuid.UUID]int{rootId: 1}
+	height := 1
+	for _, notebook := range subtree {
+		if notebook.Id == rootId || notebook.ParentId == nil {
+			continue
+		}
+		depth := depths[*notebook.ParentId] + 1
+		depths[notebook.Id] = depth
+		height = max(height, depth)
+	}
+
+	return height
+}
+
+func maxDepthError(maxDepth int) error {
+	return serverutils.NewValidationError([]serverutils.ValidationErrorDetail{
+		{Field: "parent_id", Message: fmt.Sprintf("notebooks cannot be nested more than %d levels deep", maxDepth)},
+	})
+}
+
+func (c *notebookService) GetTree(ctx context.Context, id uuid.UUID) (*dto.NotebookTreeResponse, error) {
+	notebooks, err := c.notebookRepository.GetSubtree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0)
+	for _, notebook := range notebooks {
+		ids = append(ids, notebook.Id)
+	}
+	noteCounts, err := c.noteRepository.CountByNotebookIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// GetSubtree orders by depth, so every parent is seen before its children.
+	nodes := make(map[uuid.UUID]*dto.NotebookTreeResponse)
+	for _, notebook := range notebooks {
+		node := dto.NotebookTreeResponse{
+			Id:        notebook.Id,
+			Name:      notebook.Name,
+			ParentId:  notebook.ParentId,
+			NoteCount: noteCounts[notebook.Id],
+			CreatedAt: notebook.CreatedAt,
+			UpdatedAt: notebook.UpdatedAt,
+			Children:  make([]*dto.NotebookTreeResponse, 0),
+		}
+		nodes[notebook.Id] = &node
+
+		if notebook.Id != id && notebook.ParentId != nil {
+			if parent, ok := nodes[*notebook.ParentId]; ok {
+				parent.Children = append(parent.Children, &node)
+			}
+		}
+	}
+
+	var totalNoteCount func(node *dto.NotebookTreeResponse) int
+	totalNoteCount = func(node *dto.NotebookTreeResponse) int {
+		total := node.NoteCount
+		for _, child := range node.Children {
+			total += totalNoteCount(child)
+		}
+		node.TotalNoteCount = total
+		return total
+	}
+	totalNoteCount(nodes[id])
+
+	return nodes[id], nil
+}
+
+// GetGraph returns the notes of a notebook and its descendants with the
+// wiki-links between them.
+func (c *notebookService) GetGraph(ctx context.Context, id uuid.UUID) (*dto.NotebookGraphResponse, error) {
+	notebooks, err := c.notebookRepository.GetSubtree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	notebookIds := make([]uuid.UUID, 0)
+	for _, notebook := range notebooks {
+		notebookIds = append(notebookIds, notebook.Id)
+	}
+	notes, err := c.noteRepository.GetByNotebookIds(ctx, notebookIds)
+	if err != nil {
+		return nil, err
+	}
+
+	res := dto.NotebookGraphResponse{
+		Nodes: make([]*dto.NotebookGraphNode, 0),
+		Edges: make([]*dto.NotebookGraphEdge, 0),
+	}
+	noteIds := make([]uuid.UUID, 0)
+	for _, note := range notes {
+		noteIds = append(noteIds, note.Id)
+		res.Nodes = append(res.Nodes, &dto.NotebookGraphNode{
+			Id:         note.Id,
+			Title:      note.Title,
+			NotebookId: note.NotebookId,
+		})
+	}
+
+	noteLinks, err := c.noteLinkRepository.GetBetweenNoteIds(ctx, noteIds)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[[2]uuid.UUID]bool)
+	for _, noteLink := range noteLinks {
+		edge := [2]uuid.UUID{noteLink.SourceNoteId, *noteLink.TargetNoteId}
+		if seen[edge] {
+			continue
+		}
+		seen[edge] = true
+
+		res.Edges = append(res.Edges, &dto.NotebookGraphEdge{
+			SourceId: noteLink.SourceNoteId,
+			TargetId: *noteLink.TargetNoteId,
+		})
+	}
+
+	return &res, nil
+}
+
+// GetAllTree returns every notebook nested under its parent, each with a
+// summary of its most recently updated notes instead of their full content.
+func (c *notebookService) GetAllTree(ctx context.Context, req *dto.GetNotebookTreeRequest) ([]*dto.NotebookTreeResponse, error) {
+	notesLimit := req.NotesLimit
+	if notesLimit == 0 {
+		notesLimit = constant.NotebookNotesDefaultLimit
+	}
+
+	notebooks, err := c.notebookRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0)
+	nodes := make(map[uuid.UUID]*dto.NotebookTreeResponse)
+	for _, notebook := range notebooks {
+		ids = append(ids, notebook.Id)
+		nodes[notebook.Id] = &dto.NotebookTreeResponse{
+			Id:        notebook.Id,
+			Name:      notebook.Name,
+			ParentId:  notebook.ParentId,
+			CreatedAt: notebook.CreatedAt,
+			UpdatedAt: notebook.UpdatedAt,
+			Notes:     make([]*dto.NoteSummaryResponse, 0),
+			Children:  make([]*dto.NotebookTreeResponse, 0),
+		}
+	}
+
+	noteCounts, err := c.noteRepository.CountByNotebookIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	noteSummaries, err := c.noteRepository.GetSummariesByNotebookIds(ctx, ids, notesLimit)
+	if err != nil {
+		return nil, err
+	}
+	for _, noteSummary := range noteSummaries {
+		node := nodes[noteSummary.NotebookId]
+		node.Notes = append(node.Notes, toNoteSummaryResponse(noteSummary))
+	}
+
+	roots := make([]*dto.NotebookTreeResponse, 0)
+	for _, notebook := range notebooks {
+		node := nodes[notebook.Id]
+		node.NoteCount = noteCounts[notebook.Id]
+		node.HasMoreNotes = node.NoteCount > len(node.Notes)
+
+		if notebook.ParentId != nil {
+			if parent, ok := nodes[*notebook.ParentId]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	var sortAndCount func(nodes []*dto.NotebookTreeResponse) int
+	sortAndCount = func(nodes []*dto.NotebookTreeResponse) int {
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return nodes[i].Name < nodes[j].Name
+		})
+
+		total := 0
+		for _, node := range nodes {
+			node.TotalNoteCount = node.NoteCount + sortAndCount(node.Children)
+			total += node.TotalNoteCount
+		}
+		return total
+	}
+	sortAndCount(roots)
+
+	return roots, nil
+}
+
+func (c *notebookService) GetNotes(ctx context.Context, req *dto.GetNotebookNotesRequest) (*dto.GetNotebookNotesResponse, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = constant.NotebookNotesDefaultLimit
+	}
+
+	_, err := c.notebookRepository.GetById(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	noteCounts, err := c.noteRepository.CountByNotebookIds(ctx, []uuid.UUID{req.Id})
+	if err != nil {
+		return nil, err
+	}
+	noteSummaries, err := c.noteRepository.GetSummariesByNotebookId(ctx, req.Id, limit, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	res := dto.GetNotebookNotesResponse{
+		Notes:  make([]*dto.NoteSummaryResponse, 0),
+		Total:  noteCounts[req.Id],
+		Limit:  limit,
+		Offset: req.Offset,
+	}
+	for _, noteSummary := range noteSummaries {
+		res.Notes = append(res.Notes, toNoteSummaryResponse(noteSummary))
+	}
+
+	return &res, nil
+}
+
+func toNoteSummaryResponse(noteSummary *entity.NoteSummary) *dto.NoteSummaryResponse {
+	return &dto.NoteSummaryResponse{
+		Id:        noteSummary.Id,
+		Title:     noteSummary.Title,
+		Excerpt:   excerpt(noteSummary.Excerpt, constant.NoteExcerptLength),
+		CreatedAt: noteSummary.CreatedAt,
+		UpdatedAt: noteSummary.UpdatedAt,
+	}
+}
+
+// excerpt collapses whitespace in text and cuts it to at most length runes.
+func excerpt(text string, length int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= length {
+		return string(runes)
+	}
+
+	return strings.TrimSpace(string(runes[:length])) + "…"
+}
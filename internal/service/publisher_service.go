@@ -1,28 +1,61 @@
 package service
 
 import (
+	"ai-notetaking-be/internal/dto"
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/repository"
+	"ai-notetaking-be/pkg/database"
 	"context"
+	"encoding/json"
+	"time"
 
-	"github.com/ThreeDotsLabs/watermill"
-	"github.com/ThreeDotsLabs/watermill/message"
-	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/google/uuid"
 )
 
 type IPublisherService interface {
-	Publish(ctx context.Context, payload []byte) error
+	// UsingTx enqueues within tx, so the messages are only published if the
+	// writes they are about are committed.
+	UsingTx(ctx context.Context, tx database.DatabaseQueryer) IPublisherService
+	// Publish durably enqueues payload. Publishing again with the same key
+	// before the previous message is processed replaces it.
+	Publish(ctx context.Context, key string, payload []byte) error
+	// PublishBatch enqueues all messages at once. When several messages
+	// share a key only the last one is kept.
+	PublishBatch(ctx context.Context, messages []PublishMessage) error
+}
+
+type PublishMessage struct {
+	Key     string
+	Payload []byte
 }
 
 type publisherService struct {
-	pubSub *gochannel.GoChannel
+	jobRepository repository.IJobRepository
+
+	topicName   string
+	maxAttempts int
+}
 
-	topicName string
+func (ps *publisherService) UsingTx(ctx context.Context, tx database.DatabaseQueryer) IPublisherService {
+	return &publisherService{
+		jobRepository: ps.jobRepository.UsingTx(ctx, tx),
+		topicName:     ps.topicName,
+		maxAttempts:   ps.maxAttempts,
+	}
 }
 
-func (ps *publisherService) Publish(ctx context.Context, payload []byte) error {
-	err := ps.pubSub.Publish(
-		ps.topicName,
-		message.NewMessage(watermill.NewUUID(), payload),
-	)
+func (ps *publisherService) Publish(ctx context.Context, key string, payload []byte) error {
+	now := time.Now()
+	err := ps.jobRepository.Enqueue(ctx, &entity.Job{
+		Id:          uuid.New(),
+		Topic:       ps.topicName,
+		DedupKey:    key,
+		Payload:     payload,
+		MaxAttempts: ps.maxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+	})
 	if err != nil {
 		return err
 	}
@@ -30,9 +63,53 @@ func (ps *publisherService) Publish(ctx context.Context, payload []byte) error {
 	return nil
 }
 
-func NewPublisherService(topicName string, pubSub *gochannel.GoChannel) IPublisherService {
+func (ps *publisherService) PublishBatch(ctx context.Context, messages []PublishMessage) error {
+	now := time.Now()
+	jobs := make([]*entity.Job, 0)
+	jobIndexByKey := make(map[string]int)
+	for _, message := range messages {
+		job := &entity.Job{
+			Id:          uuid.New(),
+			Topic:       ps.topicName,
+			DedupKey:    message.Key,
+			Payload:     message.Payload,
+			MaxAttempts: ps.maxAttempts,
+			RunAt:       now,
+			CreatedAt:   now,
+		}
+
+		if i, ok := jobIndexByKey[message.Key]; ok {
+			jobs[i] = job
+			continue
+		}
+		jobIndexByKey[message.Key] = len(jobs)
+		jobs = append(jobs, job)
+	}
+
+	return ps.jobRepository.EnqueueBatch(ctx, jobs)
+}
+
+// publishEmbedNotes enqueues the embedding of each note of the owner in ctx.
+func publishEmbedNotes(ctx context.Context, publisherService IPublisherService, noteIds []uuid.UUID) error {
+	messages := make([]PublishMessage, 0)
+	for _, noteId := range noteIds {
+		payload, err := json.Marshal(dto.PublishEmbedNoteMessage{
+			NoteId:  noteId,
+			OwnerId: serverutils.UserIdFromContext(ctx),
+		})
+		if err != nil {
+			return err
+		}
+		messages = append(messages, PublishMessage{Key: noteId.String(), Payload: payload})
+	}
+
+	return publisherService.PublishBatch(ctx, messages)
+}
+
+func NewPublisherService(topicName string, maxAttempts int, jobRepository repository.IJobRepository) IPublisherService {
 	return &publisherService{
-		topicName: topicName,
-		pubSub:    pubSub,
+		topicName:     topicName,
+		maxAttempts:   maxAttempts,
+		jobRepository: jobRepository,
 	}
 }
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"ai-notetaking-be/internal/repository"
+	"ai-notetaking-be/pkg/blobstore"
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IPurgerService interface {
+	Start(ctx context.Context) error
+}
+
+type purgerService struct {
+	notebookRepository      repository.INotebookRepository
+	noteRepository          repository.INoteRepository
+	noteEmbeddingRepository repository.INoteEmbeddingRepository
+	noteRevisionRepository  repository.INoteRevisionRepository
+	tagRepository           repository.ITagRepository
+	noteLinkRepository      repository.INoteLinkRepository
+	attachmentRepository    repository.IAttachmentRepository
+	blobStore               blobstore.BlobStore
+	interval                time.Duration
+	retention               time.Duration
+
+	db *pgxpool.Pool
+}
+
+func NewPurgerService(
+	notebookRepository repository.INotebookRepository,
+	noteRepository repository.INoteRepository,
+	noteEmbeddingRepository repository.INoteEmbeddingRepository,
+	noteRevisionRepository repository.INoteRevisionRepository,
+	tagRepository repository.ITagRepository,
+	noteLinkRepository repository.INoteLinkRepository,
+	attachmentRepository repository.IAttachmentRepository,
+	blobStore blobstore.BlobStore,
+	interval time.Duration,
+	retention time.Duration,
+	db *pgxpool.Pool,
+) IPurgerService {
+	return &purgerService{
+		notebookRepository:      notebookRepository,
+		noteRepository:          noteRepository,
+		noteEmbeddingRepository: noteEmbeddingRepository,
+		noteRevisionRepository:  noteRevisionRepository,
+		tagRepository:           tagRepository,
+		noteLinkRepository:      noteLinkRepository,
+		attachmentRepository:    attachmentRepository,
+		blobStore:               blobStore,
+		interval:                interval,
+		retention:               retention,
+		db:                      db,
+	}
+}
+
+// Start hard deletes trashed items older than the retention every interval
+// until ctx is cancelled.
+func (ps *purgerService) Start(ctx context.Context) error {
+	go func() {
+		ticker := time.NewTicker(ps.interval)
+		defer ticker.Stop()
+
+		for {
+			err := ps.purge(ctx, time.Now().Add(-ps.retention))
+			if err != nil {
+				log.Error(err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (ps *purgerService) purge(ctx context.Context, deletedBefore time.Time) error {
+	tx, err := ps.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	notebookRepository := ps.notebookRepository.UsingTx(ctx, tx)
+	noteRepository := ps.noteRepository.UsingTx(ctx, tx)
+	noteEmbeddingRepository := ps.noteEmbeddingRepository.UsingTx(ctx, tx)
+	noteRevisionRepository := ps.noteRevisionRepository.UsingTx(ctx, tx)
+	tagRepository := ps.tagRepository.UsingTx(ctx, tx)
+	noteLinkRepository := ps.noteLinkRepository.UsingTx(ctx, tx)
+	attachmentRepository := ps.attachmentRepository.UsingTx(ctx, tx)
+
+	noteIds, err := noteRepository.GetPurgeableIds(ctx, deletedBefore)
+	if err != nil {
+		return err
+	}
+
+	// This also drops embeddings replaced by re-embedding a live note.
+	err = noteEmbeddingRepository.PurgeDeletedBefore(ctx, deletedBefore)
+	if err != nil {
+		return err
+	}
+
+	err = noteEmbeddingRepository.HardDeleteByNoteIds(ctx, noteIds)
+	if err != nil {
+		return err
+	}
+
+	err = noteRevisionRepository.DeleteByNoteIds(ctx, noteIds)
+	if err != nil {
+		return err
+	}
+
+	err = tagRepository.DeleteNoteTagsByNoteIds(ctx, noteIds)
+	if err != nil {
+		return err
+	}
+
+	err = noteLinkRepository.DeleteByNoteIds(ctx, noteIds)
+	if err != nil {
+		return err
+	}
+
+	storageKeys, err := attachmentRepository.GetStorageKeysByNoteIds(ctx, noteIds)
+	if err != nil {
+		return err
+	}
+	err = attachmentRepository.DeleteByNoteIds(ctx, noteIds)
+	if err != nil {
+		return err
+	}
+
+	err = noteRepository.HardDeleteByIds(ctx, noteIds)
+	if err != nil {
+		return err
+	}
+
+	notebookIds, err := notebookRepository.GetPurgeableIds(ctx, deletedBefore)
+	if err != nil {
+		return err
+	}
+
+	err = notebookRepository.HardDeleteByIds(ctx, notebookIds)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Files are removed once their rows are gone, a failure only leaves an
+	// unreferenced blob behind.
+	for _, storageKey := range storageKeys {
+		err = ps.blobStore.Delete(ctx, storageKey)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+
+	if len(noteIds) > 0 || len(notebookIds) > 0 {
+		log.Infof("purged %d notes and %d notebooks from the trash", len(noteIds), len(notebookIds))
+	}
+
+	return nil
+}
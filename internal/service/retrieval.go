@@ -0,0 +1,68 @@
+package service
+
+import (
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/pkg/rerank"
+	"context"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+)
+
+type RetrievalConfig struct {
+	// MinScore is the lowest cosine similarity to the query a chunk needs to
+	// be used as a reference.
+	MinScore float64
+	// CandidateLimit is how many chunks are fetched before de-duplication,
+	// re-ranking and MMR narrow them down to TopK.
+	CandidateLimit int
+	TopK           int
+	// MMRLambda weighs relevance against diversity, 1 ignores diversity.
+	MMRLambda float64
+}
+
+// selectReferences narrows the candidate chunks, ordered best first, down to
+// the references of a chat message. Only the best chunk of each note is kept,
+// whether it comes from the note's content or one of its attachments, the
+// reranker reorders them when one is configured, and MMR picks a diverse set
+// among the rest.
+func (cs *chatbotService) selectReferences(ctx context.Context, query string, candidates []*entity.NoteEmbedding) []*entity.NoteEmbedding {
+	seen := make(map[uuid.UUID]bool)
+	noteEmbeddings := make([]*entity.NoteEmbedding, 0)
+	for _, candidate := range candidates {
+		if seen[candidate.NoteId] {
+			continue
+		}
+		seen[candidate.NoteId] = true
+		noteEmbeddings = append(noteEmbeddings, candidate)
+	}
+
+	relevance := make([]float64, 0)
+	vectors := make([][]float32, 0)
+	for _, noteEmbedding := range noteEmbeddings {
+		relevance = append(relevance, noteEmbedding.Score)
+		vectors = append(vectors, noteEmbedding.EmbeddingValue)
+	}
+
+	if cs.reranker != nil && len(noteEmbeddings) > 0 {
+		documents := make([]string, 0)
+		for _, noteEmbedding := range noteEmbeddings {
+			documents = append(documents, noteEmbedding.Document)
+		}
+
+		// Similarity still gives a usable order when the reranker is down.
+		scores, err := cs.reranker.Rerank(ctx, query, documents)
+		if err != nil {
+			log.Error(err)
+		} else {
+			relevance = scores
+		}
+	}
+
+	res := make([]*entity.NoteEmbedding, 0)
+	for _, i := range rerank.MMR(relevance, vectors, cs.retrievalConfig.MMRLambda, cs.retrievalConfig.TopK) {
+		res = append(res, noteEmbeddings[i])
+	}
+
+	return res
+}
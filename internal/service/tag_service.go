@@ -0,0 +1,321 @@
+package service
+
+import (
+	"ai-notetaking-be/internal/dto"
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/repository"
+	"ai-notetaking-be/pkg/database"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ITagService interface {
+	GetAll(ctx context.Context) ([]*dto.GetAllTagResponse, error)
+	Create(ctx context.Context, req *dto.CreateTagRequest) (*dto.CreateTagResponse, error)
+	Update(ctx context.Context, req *dto.UpdateTagRequest) (*dto.UpdateTagResponse, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	Merge(ctx context.Context, req *dto.MergeTagRequest) (*dto.MergeTagResponse, error)
+}
+
+type tagService struct {
+	tagRepository    repository.ITagRepository
+	publisherService IPublisherService
+	db               *pgxpool.Pool
+}
+
+func NewTagService(
+	tagRepository repository.ITagRepository,
+	publisherService IPublisherService,
+	db *pgxpool.Pool,
+) ITagService {
+	return &tagService{
+		tagRepository:    tagRepository,
+		publisherService: publisherService,
+		db:               db,
+	}
+}
+
+func (c *tagService) GetAll(ctx context.Context) ([]*dto.GetAllTagResponse, error) {
+	tags, err := c.tagRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*dto.GetAllTagResponse, 0)
+	for _, tag := range tags {
+		res = append(res, &dto.GetAllTagResponse{
+			Id:        tag.Id,
+			Name:      tag.Name,
+			NoteCount: tag.NoteCount,
+			CreatedAt: tag.CreatedAt,
+			UpdatedAt: tag.UpdatedAt,
+		})
+	}
+
+	return res, nil
+}
+
+func (c *tagService) Create(ctx context.Context, req *dto.CreateTagRequest) (*dto.CreateTagResponse, error) {
+	name := normalizeTagName(req.Name)
+	if name == "" {
+		return nil, emptyTagNameError()
+	}
+
+	tag := entity.Tag{
+		Id:        uuid.New(),
+		Name:      name,
+		OwnerId:   serverutils.UserIdFromContext(ctx),
+		CreatedAt: time.Now(),
+	}
+	err := c.tagRepository.Create(ctx, &tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.CreateTagResponse{
+		Id: tag.Id,
+	}, nil
+}
+
+// Update renames a tag. Renaming to the name of another tag is a conflict,
+// use Merge to combine them.
+func (c *tagService) Update(ctx context.Context, req *dto.UpdateTagRequest) (*dto.UpdateTagResponse, error) {
+	name := normalizeTagName(req.Name)
+	if name == "" {
+		return nil, emptyTagNameError()
+	}
+
+	tag, err := c.tagRepository.GetById(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tag.Name = name
+	tag.UpdatedAt = &now
+
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = c.tagRepository.UsingTx(ctx, tx).Update(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.republishNotes(ctx, tx, tag.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.UpdateTagResponse{
+		Id: tag.Id,
+	}, nil
+}
+
+func (c *tagService) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := c.tagRepository.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	noteIds, err := c.tagRepository.GetNoteIds(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = c.tagRepository.UsingTx(ctx, tx).Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = publishEmbedNotes(ctx, c.publisherService.UsingTx(ctx, tx), noteIds)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Merge moves every note of a tag to the target tag and deletes it.
+func (c *tagService) Merge(ctx context.Context, req *dto.MergeTagRequest) (*dto.MergeTagResponse, error) {
+	if req.Id == req.TargetId {
+		return nil, serverutils.NewValidationError([]serverutils.ValidationErrorDetail{
+			{Field: "target_id", Message: "a tag cannot be merged into itself"},
+		})
+	}
+
+	_, err := c.tagRepository.GetById(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = c.tagRepository.GetById(ctx, req.TargetId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = c.tagRepository.UsingTx(ctx, tx).Merge(ctx, req.Id, req.TargetId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.republishNotes(ctx, tx, req.TargetId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MergeTagResponse{
+		Id: req.TargetId,
+	}, nil
+}
+
+// republishNotes re-embeds the notes of a tag within tx, since tag names are
+// part of the embedded document.
+func (c *tagService) republishNotes(ctx context.Context, tx database.DatabaseQueryer, tagId uuid.UUID) error {
+	noteIds, err := c.tagRepository.UsingTx(ctx, tx).GetNoteIds(ctx, tagId)
+	if err != nil {
+		return err
+	}
+
+	return publishEmbedNotes(ctx, c.publisherService.UsingTx(ctx, tx), noteIds)
+}
+
+// ensureTags returns the tags named by names, creating the missing ones.
+func ensureTags(ctx context.Context, tagRepository repository.ITagRepository, names []string) ([]*entity.Tag, error) {
+	normalized := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = normalizeTagName(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		normalized = append(normalized, name)
+	}
+
+	tags, err := tagRepository.GetByNames(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool)
+	for _, tag := range tags {
+		existing[strings.ToLower(tag.Name)] = true
+	}
+	for _, name := range normalized {
+		if existing[strings.ToLower(name)] {
+			continue
+		}
+
+		tag := entity.Tag{
+			Id:        uuid.New(),
+			Name:      name,
+			OwnerId:   serverutils.UserIdFromContext(ctx),
+			CreatedAt: time.Now(),
+		}
+		err = tagRepository.Create(ctx, &tag)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+
+	return tags, nil
+}
+
+// normalizeTagName trims a leading "#" and collapses whitespace.
+func normalizeTagName(name string) string {
+	return strings.Join(strings.Fields(strings.TrimLeft(strings.TrimSpace(name), "#")), " ")
+}
+
+func emptyTagNameError() error {
+	return serverutils.NewValidationError([]serverutils.ValidationErrorDetail{
+		{Field: "name", Message: "tag name is required"},
+	})
+}
+
+func toTagResponses(tags []*entity.Tag) []*dto.TagResponse {
+	res := make([]*dto.TagResponse, 0)
+	for _, tag := range tags {
+		res = append(res, &dto.TagResponse{
+			Id:   tag.Id,
+			Name: tag.Name,
+		})
+	}
+
+	return res
+}
+
+// resolveTagIds looks up the tags used as a filter. It returns nil when one
+// of them does not exist, since no note can match.
+func resolveTagIds(ctx context.Context, tagRepository repository.ITagRepository, names []string) ([]uuid.UUID, error) {
+	normalized := make([]string, 0)
+	for _, name := range names {
+		normalized = append(normalized, normalizeTagName(name))
+	}
+
+	tags, err := tagRepository.GetByNames(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool)
+	tagIds := make([]uuid.UUID, 0)
+	for _, tag := range tags {
+		found[strings.ToLower(tag.Name)] = true
+		tagIds = append(tagIds, tag.Id)
+	}
+	for _, name := range normalized {
+		if !found[strings.ToLower(name)] {
+			return nil, nil
+		}
+	}
+
+	return tagIds, nil
+}
+
+func hasAllTags(tags []*entity.Tag, tagIds []uuid.UUID) bool {
+	for _, tagId := range tagIds {
+		found := false
+		for _, tag := range tags {
+			if tag.Id == tagId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
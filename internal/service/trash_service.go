@@ -0,0 +1,298 @@
+package service
+
+import (
+	"ai-notetaking-be/internal/dto"
+	"ai-notetaking-be/internal/entity"
+	"ai-notetaking-be/internal/pkg/serverutils"
+	"ai-notetaking-be/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ITrashService interface {
+	GetAll(ctx context.Context) (*dto.GetTrashResponse, error)
+	RestoreNotebook(ctx context.Context, id uuid.UUID) (*dto.RestoreNotebookResponse, error)
+	RestoreNote(ctx context.Context, id uuid.UUID) (*dto.RestoreNoteResponse, error)
+}
+
+type trashService struct {
+	notebookRepository      repository.INotebookRepository
+	noteRepository          repository.INoteRepository
+	noteEmbeddingRepository repository.INoteEmbeddingRepository
+	retention               time.Duration
+	maxDepth                int
+	db                      *pgxpool.Pool
+}
+
+func NewTrashService(
+	notebookRepository repository.INotebookRepository,
+	noteRepository repository.INoteRepository,
+	noteEmbeddingRepository repository.INoteEmbeddingRepository,
+	retention time.Duration,
+	maxDepth int,
+	db *pgxpool.Pool,
+) ITrashService {
+	return &trashService{
+		notebookRepository:      notebookRepository,
+		noteRepository:          noteRepository,
+		noteEmbeddingRepository: noteEmbeddingRepository,
+		retention:               retention,
+		maxDepth:                maxDepth,
+		db:                      db,
+	}
+}
+
+// GetAll lists trashed notebooks and the notes that were trashed on their
+// own. Notes trashed together with their notebook are only counted under it
+// because they can only be restored with it.
+func (c *trashService) GetAll(ctx context.Context) (*dto.GetTrashResponse, error) {
+	notebooks, err := c.notebookRepository.GetDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := c.noteRepository.GetDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := dto.GetTrashResponse{
+		Notebooks: make([]*dto.GetTrashResponseNotebook, 0),
+		Notes:     make([]*dto.GetTrashResponseNote, 0),
+	}
+
+	// Descendants trashed by a cascade delete share the deletedAt of the
+	// notebook the delete started from and are restored with it, so they are
+	// folded into that notebook.
+	notebooksById := make(map[uuid.UUID]*entity.Notebook)
+	for _, notebook := range notebooks {
+		notebooksById[notebook.Id] = notebook
+	}
+	batchRootId := func(id uuid.UUID) uuid.UUID {
+		for range notebooks {
+			notebook := notebooksById[id]
+			if notebook.ParentId == nil {
+				break
+			}
+			parent, ok := notebooksById[*notebook.ParentId]
+			if !ok || !parent.DeletedAt.Equal(*notebook.DeletedAt) {
+				break
+			}
+			id = parent.Id
+		}
+		return id
+	}
+
+	items := make(map[uuid.UUID]*dto.GetTrashResponseNotebook)
+	for _, notebook := range notebooks {
+		if batchRootId(notebook.Id) != notebook.Id {
+			continue
+		}
+
+		item := dto.GetTrashResponseNotebook{
+			Id:        notebook.Id,
+			Name:      notebook.Name,
+			ParentId:  notebook.ParentId,
+			DeletedAt: *notebook.DeletedAt,
+			PurgeAt:   notebook.DeletedAt.Add(c.retention),
+		}
+		res.Notebooks = append(res.Notebooks, &item)
+		items[notebook.Id] = &item
+	}
+
+	for _, note := range notes {
+		notebook, ok := notebooksById[note.NotebookId]
+		if ok && notebook.DeletedAt.Equal(*note.DeletedAt) {
+			items[batchRootId(notebook.Id)].NoteCount++
+			continue
+		}
+
+		res.Notes = append(res.Notes, &dto.GetTrashResponseNote{
+			Id:         note.Id,
+			Title:      note.Title,
+			NotebookId: note.NotebookId,
+			DeletedAt:  *note.DeletedAt,
+			PurgeAt:    note.DeletedAt.Add(c.retention),
+		})
+	}
+
+	return &res, nil
+}
+
+// RestoreNotebook brings back a notebook together with the descendants,
+// notes and embeddings trashed with it, and moves its former children back
+// under it.
+// If its own parent is still in the trash it is restored to the root and
+// reattached once that parent is restored. The notebook, or a former child,
+// that would end up in a cycle or too deep under its old parent since the
+// tree changed is left at the root instead.
+func (c *trashService) RestoreNotebook(ctx context.Context, id uuid.UUID) (*dto.RestoreNotebookResponse, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	notebookRepository := c.notebookRepository.UsingTx(ctx, tx)
+	noteRepository := c.noteRepository.UsingTx(ctx, tx)
+	noteEmbeddingRepository := c.noteEmbeddingRepository.UsingTx(ctx, tx)
+
+	err = notebookRepository.LockTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	notebook, err := notebookRepository.GetDeletedById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if notebook.ParentId != nil {
+		_, err = notebookRepository.GetById(ctx, *notebook.ParentId)
+		if err != nil {
+			if !errors.Is(err, serverutils.ErrNotFound) {
+				return nil, err
+			}
+
+			notebook.DetachedFromId = notebook.ParentId
+			notebook.ParentId = nil
+		}
+	}
+
+	now := time.Now()
+	notebook.UpdatedAt = &now
+	err = notebookRepository.Restore(ctx, notebook)
+	if err != nil {
+		return nil, err
+	}
+
+	descendantIds, err := notebookRepository.GetDeletedDescendantIds(ctx, notebook.Id, *notebook.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = notebookRepository.RestoreByIds(ctx, descendantIds)
+	if err != nil {
+		return nil, err
+	}
+
+	if notebook.ParentId != nil {
+		fits, err := c.fitsUnder(ctx, notebookRepository, notebook.Id, *notebook.ParentId)
+		if err != nil {
+			return nil, err
+		}
+		if !fits {
+			notebook.ParentId = nil
+			err = notebookRepository.UpdateParentId(ctx, notebook.Id, nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	childIds, err := notebookRepository.GetDetachedChildIds(ctx, notebook.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, childId := range childIds {
+		fits, err := c.fitsUnder(ctx, notebookRepository, childId, notebook.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		var parentId *uuid.UUID
+		if fits {
+			parentId = &notebook.Id
+		}
+		err = notebookRepository.UpdateParentId(ctx, childId, parentId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	notebookIds := append([]uuid.UUID{notebook.Id}, descendantIds...)
+	err = noteRepository.RestoreByNotebookIds(ctx, notebookIds, *notebook.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = noteEmbeddingRepository.RestoreByNotebookIds(ctx, notebookIds, *notebook.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.RestoreNotebookResponse{
+		Id:       notebook.Id,
+		ParentId: notebook.ParentId,
+	}, nil
+}
+
+// fitsUnder reports whether notebook id can be moved under parentId with the
+// same rules as MoveNotebook, i.e. without a cycle or exceeding maxDepth.
+func (c *trashService) fitsUnder(ctx context.Context, notebookRepository repository.INotebookRepository, id uuid.UUID, parentId uuid.UUID) (bool, error) {
+	ancestorIds, err := notebookRepository.GetAncestorIds(ctx, parentId)
+	if err != nil {
+		return false, err
+	}
+	for _, ancestorId := range ancestorIds {
+		if ancestorId == id {
+			return false, nil
+		}
+	}
+
+	subtree, err := notebookRepository.GetSubtree(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	return len(ancestorIds)+subtreeHeight(id, subtree) <= c.maxDepth, nil
+}
+
+func (c *trashService) RestoreNote(ctx context.Context, id uuid.UUID) (*dto.RestoreNoteResponse, error) {
+	note, err := c.noteRepository.GetDeletedById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.notebookRepository.GetById(ctx, note.NotebookId)
+	if err != nil {
+		if errors.Is(err, serverutils.ErrNotFound) {
+			return nil, fmt.Errorf("%w: the note's notebook is in the trash, restore it first", serverutils.ErrConflict)
+		}
+		return nil, err
+	}
+
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = c.noteRepository.UsingTx(ctx, tx).Restore(ctx, note.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.noteEmbeddingRepository.UsingTx(ctx, tx).RestoreByNoteId(ctx, note.Id, *note.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.RestoreNoteResponse{
+		Id: note.Id,
+	}, nil
+}
@@ -0,0 +1,24 @@
+CREATE TABLE app_user (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    email TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    is_deleted BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE UNIQUE INDEX app_user_email_idx ON app_user (lower(email)) WHERE is_deleted = false;
+
+CREATE TABLE user_session (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES app_user (id),
+    refresh_token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE INDEX user_session_user_id_idx ON user_session (user_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS note;
+DROP TABLE IF EXISTS notebook;
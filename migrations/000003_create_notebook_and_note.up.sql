@@ -0,0 +1,47 @@
+-- Deployments from before the migrations already have notebook and note
+-- without owner_id, so the tables are only created when missing and the
+-- newer columns are added to existing ones.
+CREATE TABLE IF NOT EXISTS notebook (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    parent_id UUID REFERENCES notebook (id),
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    is_deleted BOOLEAN NOT NULL DEFAULT false
+);
+
+ALTER TABLE notebook ADD COLUMN IF NOT EXISTS owner_id UUID REFERENCES app_user (id);
+
+CREATE TABLE IF NOT EXISTS note (
+    id UUID PRIMARY KEY,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL DEFAULT '',
+    notebook_id UUID NOT NULL REFERENCES notebook (id),
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    is_deleted BOOLEAN NOT NULL DEFAULT false
+);
+
+ALTER TABLE note ADD COLUMN IF NOT EXISTS owner_id UUID REFERENCES app_user (id);
+
+-- Existing rows predate accounts and are given to a placeholder user that
+-- cannot log in. To hand them to a real account once it is registered:
+--   UPDATE notebook SET owner_id = '<user id>' WHERE owner_id = '00000000-0000-0000-0000-000000000001';
+-- and the same for note, note_revision, note_link, tag, attachment and
+-- chat_session.
+INSERT INTO app_user (id, name, email, password_hash, created_at)
+SELECT '00000000-0000-0000-0000-000000000001', 'Legacy owner', 'legacy-owner@localhost', '!', now()
+WHERE EXISTS (SELECT 1 FROM notebook WHERE owner_id IS NULL)
+    OR EXISTS (SELECT 1 FROM note WHERE owner_id IS NULL)
+ON CONFLICT (id) DO NOTHING;
+
+UPDATE notebook SET owner_id = '00000000-0000-0000-0000-000000000001' WHERE owner_id IS NULL;
+UPDATE note SET owner_id = '00000000-0000-0000-0000-000000000001' WHERE owner_id IS NULL;
+
+ALTER TABLE notebook ALTER COLUMN owner_id SET NOT NULL;
+ALTER TABLE note ALTER COLUMN owner_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS notebook_owner_id_parent_id_idx ON notebook (owner_id, parent_id);
+CREATE INDEX IF NOT EXISTS note_owner_id_notebook_id_idx ON note (owner_id, notebook_id);
@@ -0,0 +1,24 @@
+-- Existing note_embedding tables are adopted like notebook and note, see
+-- 000003. ${EMBEDDING_DIMENSION} is replaced by the configured dimension.
+CREATE TABLE IF NOT EXISTS note_embedding (
+    id UUID PRIMARY KEY,
+    document TEXT NOT NULL,
+    embedding_value VECTOR(${EMBEDDING_DIMENSION}) NOT NULL,
+    note_id UUID NOT NULL REFERENCES note (id),
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    is_deleted BOOLEAN NOT NULL DEFAULT false
+);
+
+ALTER TABLE note_embedding ADD COLUMN IF NOT EXISTS chunk_index INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE note_embedding ADD COLUMN IF NOT EXISTS start_offset INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE note_embedding ADD COLUMN IF NOT EXISTS end_offset INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS note_embedding_note_id_idx ON note_embedding (note_id);
+
+-- HNSW indexes on vector are limited to 2000 dimensions, so the index is
+-- built on a half precision cast which supports up to 4000.
+CREATE INDEX IF NOT EXISTS note_embedding_embedding_value_hnsw_idx ON note_embedding
+    USING hnsw ((embedding_value::halfvec(${EMBEDDING_DIMENSION})) halfvec_cosine_ops)
+    WHERE is_deleted = false;
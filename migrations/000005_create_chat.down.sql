@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS chat_message_reference;
+DROP TABLE IF EXISTS chat_message_raw;
+DROP TABLE IF EXISTS chat_message;
+DROP TABLE IF EXISTS chat_session;
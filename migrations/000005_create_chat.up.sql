@@ -0,0 +1,66 @@
+-- Existing chat tables are adopted like notebook and note, see 000003.
+CREATE TABLE IF NOT EXISTS chat_session (
+    id UUID PRIMARY KEY,
+    title TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    is_deleted BOOLEAN NOT NULL DEFAULT false
+);
+
+ALTER TABLE chat_session ADD COLUMN IF NOT EXISTS owner_id UUID REFERENCES app_user (id);
+
+INSERT INTO app_user (id, name, email, password_hash, created_at)
+SELECT '00000000-0000-0000-0000-000000000001', 'Legacy owner', 'legacy-owner@localhost', '!', now()
+WHERE EXISTS (SELECT 1 FROM chat_session WHERE owner_id IS NULL)
+ON CONFLICT (id) DO NOTHING;
+
+UPDATE chat_session SET owner_id = '00000000-0000-0000-0000-000000000001' WHERE owner_id IS NULL;
+
+ALTER TABLE chat_session ALTER COLUMN owner_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS chat_session_owner_id_idx ON chat_session (owner_id);
+
+CREATE TABLE IF NOT EXISTS chat_message (
+    id UUID PRIMARY KEY,
+    role TEXT NOT NULL,
+    chat TEXT NOT NULL,
+    chat_session_id UUID NOT NULL REFERENCES chat_session (id),
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    is_deleted BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS chat_message_chat_session_id_idx ON chat_message (chat_session_id, created_at);
+
+CREATE TABLE IF NOT EXISTS chat_message_raw (
+    id UUID PRIMARY KEY,
+    role TEXT NOT NULL,
+    chat TEXT NOT NULL,
+    chat_session_id UUID NOT NULL REFERENCES chat_session (id),
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    is_deleted BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS chat_message_raw_chat_session_id_idx ON chat_message_raw (chat_session_id, created_at);
+
+-- note_id has no foreign key so citations survive the note being purged.
+CREATE TABLE chat_message_reference (
+    id UUID PRIMARY KEY,
+    chat_message_id UUID NOT NULL REFERENCES chat_message (id),
+    reference_number INTEGER NOT NULL,
+    note_id UUID NOT NULL,
+    note_title TEXT NOT NULL,
+    chunk_index INTEGER NOT NULL DEFAULT 0,
+    snippet TEXT NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    is_deleted BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX chat_message_reference_chat_message_id_idx ON chat_message_reference (chat_message_id);
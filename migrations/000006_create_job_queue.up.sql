@@ -0,0 +1,18 @@
+CREATE TABLE job_queue (
+    id UUID PRIMARY KEY,
+    topic TEXT NOT NULL,
+    dedup_key TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    run_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ,
+    UNIQUE (topic, dedup_key)
+);
+
+CREATE INDEX job_queue_topic_status_run_at_idx ON job_queue (topic, status, run_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS note_search_vector_idx;
+ALTER TABLE note DROP COLUMN IF EXISTS search_vector;
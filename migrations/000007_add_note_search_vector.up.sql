@@ -0,0 +1,6 @@
+ALTER TABLE note ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(content, '')), 'B')
+) STORED;
+
+CREATE INDEX note_search_vector_idx ON note USING gin (search_vector);
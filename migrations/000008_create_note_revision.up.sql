@@ -0,0 +1,17 @@
+CREATE TABLE note_revision (
+    id UUID PRIMARY KEY,
+    note_id UUID NOT NULL REFERENCES note (id),
+    revision_number INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL DEFAULT '',
+    notebook_id UUID NOT NULL,
+    owner_id UUID NOT NULL REFERENCES app_user (id),
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX note_revision_note_id_revision_number_idx ON note_revision (note_id, revision_number);
+
+-- Every existing note starts its history with its current state.
+INSERT INTO note_revision (id, note_id, revision_number, title, content, notebook_id, owner_id, created_at)
+SELECT gen_random_uuid(), id, 1, title, content, notebook_id, owner_id, coalesce(updated_at, created_at)
+FROM note;
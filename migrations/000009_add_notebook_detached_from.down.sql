@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS note_embedding_deleted_at_idx;
+DROP INDEX IF EXISTS note_deleted_at_idx;
+DROP INDEX IF EXISTS notebook_deleted_at_idx;
+DROP INDEX IF EXISTS notebook_detached_from_id_idx;
+ALTER TABLE notebook DROP COLUMN IF EXISTS detached_from_id;
@@ -0,0 +1,8 @@
+-- When a notebook is trashed its live children are moved to the root. The
+-- former parent is remembered so restoring it can put them back.
+ALTER TABLE notebook ADD COLUMN detached_from_id UUID;
+
+CREATE INDEX notebook_detached_from_id_idx ON notebook (detached_from_id) WHERE detached_from_id IS NOT NULL;
+CREATE INDEX notebook_deleted_at_idx ON notebook (deleted_at) WHERE is_deleted = true;
+CREATE INDEX note_deleted_at_idx ON note (deleted_at) WHERE is_deleted = true;
+CREATE INDEX note_embedding_deleted_at_idx ON note_embedding (deleted_at) WHERE is_deleted = true;
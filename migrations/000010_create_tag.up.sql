@@ -0,0 +1,18 @@
+CREATE TABLE tag (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    owner_id UUID NOT NULL REFERENCES app_user (id),
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX tag_owner_id_name_idx ON tag (owner_id, lower(name));
+
+CREATE TABLE note_tag (
+    note_id UUID NOT NULL REFERENCES note (id),
+    tag_id UUID NOT NULL REFERENCES tag (id),
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (note_id, tag_id)
+);
+
+CREATE INDEX note_tag_tag_id_idx ON note_tag (tag_id);
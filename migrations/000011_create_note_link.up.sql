@@ -0,0 +1,30 @@
+CREATE TABLE note_link (
+    id UUID PRIMARY KEY,
+    source_note_id UUID NOT NULL REFERENCES note (id),
+    -- target_title is the title written inside [[...]]. target_note_id stays
+    -- null until a note with that title exists.
+    target_title TEXT NOT NULL,
+    target_note_id UUID REFERENCES note (id),
+    owner_id UUID NOT NULL REFERENCES app_user (id),
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX note_link_source_note_id_target_title_idx ON note_link (source_note_id, lower(target_title));
+CREATE INDEX note_link_target_note_id_idx ON note_link (target_note_id);
+CREATE INDEX note_link_owner_id_target_title_idx ON note_link (owner_id, lower(target_title)) WHERE target_note_id IS NULL;
+
+-- Links already written in existing notes, ignoring ![[...]] embeds.
+INSERT INTO note_link (id, source_note_id, target_title, owner_id, created_at)
+SELECT gen_random_uuid(), source_note_id, target_title, owner_id, now()
+FROM (
+    SELECT DISTINCT ON (n.id, lower(trim(m[2]))) n.id AS source_note_id, trim(m[2]) AS target_title, n.owner_id
+    FROM note n, regexp_matches(n.content, '(^|[^!])\[\[([^][|#\n]+)(#[^][|\n]*)?(\|[^][\n]*)?\]\]', 'g') AS m
+    WHERE trim(m[2]) <> ''
+) link;
+
+UPDATE note_link l SET target_note_id = (
+    SELECT t.id FROM note t
+    WHERE t.owner_id = l.owner_id AND t.is_deleted = false AND lower(t.title) = lower(l.target_title)
+    ORDER BY t.created_at ASC
+    LIMIT 1
+);
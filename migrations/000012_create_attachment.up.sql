@@ -0,0 +1,12 @@
+CREATE TABLE attachment (
+    id UUID PRIMARY KEY,
+    note_id UUID NOT NULL REFERENCES note (id),
+    file_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    storage_key TEXT NOT NULL,
+    owner_id UUID NOT NULL REFERENCES app_user (id),
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX attachment_note_id_idx ON attachment (note_id);
@@ -0,0 +1,15 @@
+ALTER TABLE chat_message_reference
+    DROP COLUMN IF EXISTS attachment_file_name,
+    DROP COLUMN IF EXISTS attachment_id;
+
+DROP INDEX IF EXISTS note_embedding_attachment_id_idx;
+
+DELETE FROM note_embedding WHERE attachment_id IS NOT NULL;
+
+ALTER TABLE note_embedding DROP COLUMN IF EXISTS attachment_id;
+
+ALTER TABLE attachment
+    DROP COLUMN IF EXISTS extracted_at,
+    DROP COLUMN IF EXISTS extracted_text,
+    DROP COLUMN IF EXISTS extraction_error,
+    DROP COLUMN IF EXISTS extraction_status;
@@ -0,0 +1,13 @@
+ALTER TABLE attachment
+    ADD COLUMN extraction_status TEXT NOT NULL DEFAULT 'pending',
+    ADD COLUMN extraction_error TEXT,
+    ADD COLUMN extracted_text TEXT,
+    ADD COLUMN extracted_at TIMESTAMPTZ;
+
+ALTER TABLE note_embedding ADD COLUMN attachment_id UUID REFERENCES attachment (id);
+
+CREATE INDEX note_embedding_attachment_id_idx ON note_embedding (attachment_id);
+
+ALTER TABLE chat_message_reference
+    ADD COLUMN attachment_id UUID,
+    ADD COLUMN attachment_file_name TEXT;
@@ -0,0 +1,4 @@
+ALTER TABLE chat_session
+    DROP COLUMN IF EXISTS scope_note_ids,
+    DROP COLUMN IF EXISTS scope_tag_ids,
+    DROP COLUMN IF EXISTS scope_notebook_id;
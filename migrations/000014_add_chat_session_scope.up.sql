@@ -0,0 +1,4 @@
+ALTER TABLE chat_session
+    ADD COLUMN scope_notebook_id UUID,
+    ADD COLUMN scope_tag_ids UUID[],
+    ADD COLUMN scope_note_ids UUID[];
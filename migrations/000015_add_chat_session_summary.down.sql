@@ -0,0 +1,3 @@
+ALTER TABLE chat_session
+    DROP COLUMN IF EXISTS summarized_until,
+    DROP COLUMN IF EXISTS summary;
@@ -0,0 +1,3 @@
+ALTER TABLE chat_session
+    ADD COLUMN summary TEXT NOT NULL DEFAULT '',
+    ADD COLUMN summarized_until TIMESTAMPTZ;
@@ -0,0 +1 @@
+ALTER TABLE chat_message_raw DROP COLUMN IF EXISTS retrieval_query;
@@ -0,0 +1 @@
+ALTER TABLE chat_message_raw ADD COLUMN retrieval_query TEXT;
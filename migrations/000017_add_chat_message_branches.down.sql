@@ -0,0 +1,17 @@
+ALTER TABLE chat_session ADD COLUMN IF NOT EXISTS summarized_until TIMESTAMPTZ;
+
+UPDATE chat_session s
+SET summarized_until = (
+    SELECT m.created_at FROM chat_message m WHERE m.id = s.summarized_message_id
+)
+WHERE s.summarized_message_id IS NOT NULL;
+
+ALTER TABLE chat_session
+    DROP COLUMN IF EXISTS summarized_message_id,
+    DROP COLUMN IF EXISTS active_message_id;
+
+ALTER TABLE chat_message_raw DROP COLUMN IF EXISTS chat_message_id;
+
+DROP INDEX IF EXISTS chat_message_parent_id_idx;
+
+ALTER TABLE chat_message DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,40 @@
+ALTER TABLE chat_message ADD COLUMN parent_id UUID REFERENCES chat_message (id);
+
+CREATE INDEX chat_message_parent_id_idx ON chat_message (parent_id);
+
+ALTER TABLE chat_message_raw ADD COLUMN chat_message_id UUID REFERENCES chat_message (id);
+
+-- The active branch ends at active_message_id. The summary covers the
+-- branch up to summarized_message_id and only applies while that message is
+-- on the active branch.
+ALTER TABLE chat_session
+    ADD COLUMN active_message_id UUID,
+    ADD COLUMN summarized_message_id UUID;
+
+-- Existing sessions are a single branch in creation order. Raw messages were
+-- stored with the same role and timestamp as the message they belong to.
+UPDATE chat_message m
+SET parent_id = p.previous_id
+FROM (
+    SELECT id, LAG(id) OVER (PARTITION BY chat_session_id ORDER BY created_at ASC) AS previous_id
+    FROM chat_message
+) p
+WHERE m.id = p.id;
+
+UPDATE chat_message_raw r
+SET chat_message_id = m.id
+FROM chat_message m
+WHERE m.chat_session_id = r.chat_session_id AND m.role = r.role AND m.created_at = r.created_at;
+
+UPDATE chat_session s
+SET active_message_id = (
+    SELECT m.id FROM chat_message m WHERE m.chat_session_id = s.id ORDER BY m.created_at DESC LIMIT 1
+);
+
+UPDATE chat_session s
+SET summarized_message_id = (
+    SELECT m.id FROM chat_message m WHERE m.chat_session_id = s.id AND m.created_at = s.summarized_until LIMIT 1
+)
+WHERE s.summarized_until IS NOT NULL;
+
+ALTER TABLE chat_session DROP COLUMN summarized_until;
@@ -0,0 +1,48 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	ProviderLocal = "local"
+	ProviderS3    = "s3"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get reads length bytes of the blob starting at offset. A negative
+	// length reads to the end.
+	Get(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error)
+	// Delete removes the blob, deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+type Config struct {
+	Provider string
+	// LocalDir is the root directory of the local provider.
+	LocalDir string
+
+	Endpoint     string
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	UsePathStyle bool
+}
+
+func NewBlobStore(config Config) (BlobStore, error) {
+	switch config.Provider {
+	case "", ProviderLocal:
+		return NewLocalBlobStore(config.LocalDir)
+	case ProviderS3:
+		return NewS3BlobStore(config.Endpoint, config.Region, config.Bucket, config.AccessKey, config.SecretKey, config.UsePathStyle)
+	default:
+		return nil, fmt.Errorf("unknown blob store provider %q", config.Provider)
+	}
+}
@@ -0,0 +1,120 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const defaultS3Region = "us-east-1"
+
+// s3BlobStore talks to S3 or any S3-compatible server such as MinIO.
+type s3BlobStore struct {
+	client *minio.Core
+	bucket string
+}
+
+func (s *s3BlobStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	_, err := s.client.Client.PutObject(ctx, s.bucket, key, body, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("error from s3: %w", err)
+	}
+
+	return nil
+}
+
+func (s *s3BlobStore) Get(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	opts := minio.GetObjectOptions{}
+	var err error
+	if length > 0 {
+		err = opts.SetRange(offset, offset+length-1)
+	} else if offset > 0 {
+		err = opts.SetRange(offset, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The core client sends the request right away, so a missing blob is
+	// reported here rather than on the first read.
+	body, _, _, err := s.client.GetObject(ctx, s.bucket, key, opts)
+	if err != nil {
+		return nil, s3Error(err)
+	}
+
+	return body, nil
+}
+
+func (s *s3BlobStore) Delete(ctx context.Context, key string) error {
+	err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+	if err != nil {
+		err = s3Error(err)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+func s3Error(err error) error {
+	res := minio.ToErrorResponse(err)
+	if res.StatusCode == http.StatusNotFound || res.Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+
+	return fmt.Errorf("error from s3: %w", err)
+}
+
+func NewS3BlobStore(endpoint string, region string, bucket string, accessKey string, secretKey string, usePathStyle bool) (BlobStore, error) {
+	if endpoint == "" {
+		endpoint = "https://s3.amazonaws.com"
+	}
+	if region == "" {
+		region = defaultS3Region
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("s3 blob store requires a bucket")
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" || strings.Trim(u.Path, "/") != "" {
+		return nil, fmt.Errorf("s3 endpoint must be a scheme and host, got %q", endpoint)
+	}
+
+	bucketLookup := minio.BucketLookupDNS
+	if usePathStyle {
+		bucketLookup = minio.BucketLookupPath
+	}
+	client, err := minio.NewCore(u.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure:       u.Scheme == "https",
+		Region:       region,
+		BucketLookup: bucketLookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3BlobStore{
+		client: client,
+		bucket: bucket,
+	}, nil
+}
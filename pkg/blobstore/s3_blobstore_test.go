@@ -0,0 +1,197 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 serves path style object requests from memory.
+type fakeS3 struct {
+	mu           sync.Mutex
+	objects      map[string]string
+	contentTypes map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/bucket/")
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(r.Header.Get("x-amz-content-sha256"), "STREAMING-") {
+			body = decodeAwsChunked(body)
+		}
+		f.objects[key] = string(body)
+		f.contentTypes[key] = r.Header.Get("Content-Type")
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		object, ok := f.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				fmt.Fprintf(w, `<Error><Code>NoSuchKey</Code><Key>%s</Key></Error>`, key)
+			}
+			return
+		}
+
+		status := http.StatusOK
+		if spec, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes="); ok {
+			first, last, _ := strings.Cut(spec, "-")
+			start, _ := strconv.Atoi(first)
+			end := len(object) - 1
+			if last != "" {
+				end, _ = strconv.Atoi(last)
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(object)))
+			object = object[start : end+1]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(object)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			io.WriteString(w, object)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeAwsChunked strips the chunk headers of a streaming signed upload,
+// "<size in hex>;chunk-signature=<signature>\r\n<data>\r\n" per chunk.
+func decodeAwsChunked(body []byte) []byte {
+	decoded := make([]byte, 0)
+	for {
+		header, rest, ok := strings.Cut(string(body), "\r\n")
+		if !ok {
+			return decoded
+		}
+		sizeHex, _, _ := strings.Cut(header, ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil || size == 0 || int(size) > len(rest) {
+			return decoded
+		}
+		decoded = append(decoded, rest[:size]...)
+		body = []byte(strings.TrimPrefix(rest[size:], "\r\n"))
+	}
+}
+
+func newTestS3BlobStore(t *testing.T) (BlobStore, *fakeS3) {
+	fake := &fakeS3{
+		objects:      make(map[string]string),
+		contentTypes: make(map[string]string),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3BlobStore(server.URL, "", "bucket", "access", "secret", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return store, fake
+}
+
+func readBlob(t *testing.T, store BlobStore, key string, offset int64, length int64) string {
+	body, err := store.Get(context.Background(), key, offset, length)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return string(data)
+}
+
+func TestS3BlobStore(t *testing.T) {
+	store, fake := newTestS3BlobStore(t)
+	ctx := context.Background()
+
+	err := store.Put(ctx, "owner/note/file", strings.NewReader("hello world"), 11, "text/plain")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fake.objects["owner/note/file"] != "hello world" || fake.contentTypes["owner/note/file"] != "text/plain" {
+		t.Fatalf("got object %q of type %q", fake.objects["owner/note/file"], fake.contentTypes["owner/note/file"])
+	}
+
+	if got := readBlob(t, store, "owner/note/file", 0, -1); got != "hello world" {
+		t.Fatalf("got %q", got)
+	}
+	if got := readBlob(t, store, "owner/note/file", 6, -1); got != "world" {
+		t.Fatalf("got %q", got)
+	}
+	if got := readBlob(t, store, "owner/note/file", 0, 5); got != "hello" {
+		t.Fatalf("got %q", got)
+	}
+	if got := readBlob(t, store, "owner/note/file", 3, 0); got != "" {
+		t.Fatalf("got %q", got)
+	}
+
+	err = store.Delete(ctx, "owner/note/file")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = store.Get(ctx, "owner/note/file", 0, -1)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got error %v, want ErrNotFound", err)
+	}
+	err = store.Delete(ctx, "owner/note/file")
+	if err != nil {
+		t.Fatalf("deleting a missing blob: %v", err)
+	}
+}
+
+func TestNewS3BlobStore(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		bucket   string
+		wantErr  bool
+	}{
+		{name: "default endpoint", bucket: "bucket"},
+		{name: "custom endpoint", endpoint: "http://localhost:9000", bucket: "bucket"},
+		{name: "missing bucket", wantErr: true},
+		{name: "endpoint with path", endpoint: "http://localhost:9000/s3", bucket: "bucket", wantErr: true},
+		{name: "endpoint without scheme", endpoint: "localhost:9000", bucket: "bucket", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewS3BlobStore(tt.endpoint, "", tt.bucket, "access", "secret", false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
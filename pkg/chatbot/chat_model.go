@@ -0,0 +1,84 @@
+package chatbot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+
+	SchemaTypeObject  = "OBJECT"
+	SchemaTypeBoolean = "BOOLEAN"
+	SchemaTypeString  = "STRING"
+	SchemaTypeNumber  = "NUMBER"
+	SchemaTypeInteger = "INTEGER"
+	SchemaTypeArray   = "ARRAY"
+
+	defaultTimeout = time.Minute
+)
+
+type ChatHistory struct {
+	Chat string
+	Role string
+}
+
+type Schema struct {
+	Type       string             `json:"type"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+}
+
+type ChatModel interface {
+	Generate(ctx context.Context, chatHistories []*ChatHistory) (string, error)
+	// GenerateStructured asks the model to reply with JSON matching schema
+	// and unmarshals the reply into result.
+	GenerateStructured(ctx context.Context, chatHistories []*ChatHistory, schema *Schema, result any) error
+	// GenerateStream calls onDelta with each piece of text as it arrives and
+	// returns the full reply once the model is done. Returning an error from
+	// onDelta aborts the generation.
+	GenerateStream(ctx context.Context, chatHistories []*ChatHistory, onDelta func(delta string) error) (string, error)
+}
+
+type Config struct {
+	Provider string
+	Model    string
+	BaseUrl  string
+	ApiKey   string
+	// Timeout bounds each call to the provider, 0 uses a minute. A streamed
+	// reply only has to start within it.
+	Timeout time.Duration
+}
+
+func NewChatModel(config Config) (ChatModel, error) {
+	switch config.Provider {
+	case "", ProviderGemini:
+		return NewGeminiChatModel(config.BaseUrl, config.ApiKey, config.Model, config.Timeout), nil
+	case ProviderOpenAI:
+		return NewOpenAIChatModel(config.BaseUrl, config.ApiKey, config.Model, config.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown chat provider %q", config.Provider)
+	}
+}
+
+// newHTTPClient gives up on a provider that does not start answering within
+// timeout. Streamed replies can take longer than that to finish, so the calls
+// that are not streamed put the timeout on their context instead.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+
+	return &http.Client{Transport: transport}
+}
+
+func providerTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return defaultTimeout
+	}
+
+	return timeout
+}
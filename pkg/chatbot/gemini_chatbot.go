@@ -7,6 +7,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGeminiChatModel = "gemini-2.0-flash-exp"
+	defaultGeminiBaseUrl   = "https://generativelanguage.googleapis.com/v1beta"
 )
 
 type GeminiChatParts struct {
@@ -20,12 +27,7 @@ type GeminiChatContent struct {
 
 type GeminiChatRequest struct {
 	Contents         []*GeminiChatContent        `json:"contents"`
-	GenerationConfig *GeminiChatGenerationConfig `json:"generationConfig"`
-}
-
-type ChatHistory struct {
-	Chat string
-	Role string
+	GenerationConfig *GeminiChatGenerationConfig `json:"generationConfig,omitempty"`
 }
 
 type GeminiChatCandidate struct {
@@ -36,77 +38,65 @@ type GeminiChatResponse struct {
 	Candidates []*GeminiChatCandidate `json:"candidates"`
 }
 
-type GeminiChaPropertySchema struct {
-	Type string `json:"type"`
+type GeminiChatGenerationConfig struct {
+	ResponseMimeType string  `json:"responseMimeType"`
+	ResponseSchema   *Schema `json:"responseSchema"`
 }
 
-type GeminiChatAppSchema struct {
-	AnswerDirectly *GeminiChaPropertySchema `json:"answer_directly"`
+type geminiChatModel struct {
+	baseUrl string
+	apiKey  string
+	model   string
+	timeout time.Duration
+	client  *http.Client
 }
 
-type GeminiChatResponseSchema struct {
-	Type       string               `json:"type"`
-	Properties *GeminiChatAppSchema `json:"properties"`
-	Required   []string             `json:"required"`
+func (g *geminiChatModel) Generate(ctx context.Context, chatHistories []*ChatHistory) (string, error) {
+	return g.generateContent(ctx, chatHistories, nil)
 }
 
-type GeminiChatGenerationConfig struct {
-	ResponseMimeType string                    `json:"responseMimeType"`
-	ResponseSchema   *GeminiChatResponseSchema `json:"responseSchema"`
-}
+func (g *geminiChatModel) GenerateStructured(ctx context.Context, chatHistories []*ChatHistory, schema *Schema, result any) error {
+	reply, err := g.generateContent(ctx, chatHistories, &GeminiChatGenerationConfig{
+		ResponseMimeType: "application/json",
+		ResponseSchema:   schema,
+	})
+	if err != nil {
+		return err
+	}
 
-type GeminiResponseAppSchema struct {
-	AnswerDirectly bool `json:"answer_directly"`
+	return json.Unmarshal([]byte(reply), result)
 }
 
-func GetGeminiResponse(
-	ctx context.Context,
-	apiKey string,
-	chatHistories []*ChatHistory,
-) (string, error) {
-	chatContents := make([]*GeminiChatContent, 0)
-	for _, chatHistory := range chatHistories {
-		chatContents = append(chatContents, &GeminiChatContent{
-			Parts: []*GeminiChatParts{
-				&GeminiChatParts{
-					Text: chatHistory.Chat,
-				},
-			},
-			Role: chatHistory.Role,
-		})
-	}
+func (g *geminiChatModel) GenerateStream(ctx context.Context, chatHistories []*ChatHistory, onDelta func(delta string) error) (string, error) {
 	payload := GeminiChatRequest{
-		Contents: chatContents,
+		Contents: g.toContents(chatHistories),
 	}
 	payloadJson, err := json.Marshal(payload)
 	if err != nil {
 		return "", err
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		"POST",
-		"https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash-exp:generateContent",
+		fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", g.baseUrl, g.model),
 		bytes.NewBuffer(payloadJson),
 	)
 	if err != nil {
 		return "", err
 	}
 
-	req.Header.Set("x-goog-api-key", apiKey)
+	req.Header.Set("x-goog-api-key", g.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-
-	resBody, err := io.ReadAll(res.Body)
+	res, err := g.client.Do(req)
 	if err != nil {
 		return "", err
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
+		resBody, _ := io.ReadAll(res.Body)
 		return "", fmt.Errorf(
 			"status error, got status %d. with response body %s",
 			res.StatusCode,
@@ -114,78 +104,80 @@ func GetGeminiResponse(
 		)
 	}
 
-	var geminiRes GeminiChatResponse
-	err = json.Unmarshal(resBody, &geminiRes)
+	reply := strings.Builder{}
+	err = readServerSentEvents(res.Body, func(data string) error {
+		var geminiRes GeminiChatResponse
+		err := json.Unmarshal([]byte(data), &geminiRes)
+		if err != nil {
+			return err
+		}
+
+		if len(geminiRes.Candidates) == 0 || geminiRes.Candidates[0].Content == nil {
+			return nil
+		}
+		for _, part := range geminiRes.Candidates[0].Content.Parts {
+			if part.Text == "" {
+				continue
+			}
+			reply.WriteString(part.Text)
+			err = onDelta(part.Text)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
 		return "", err
 	}
 
-	return geminiRes.Candidates[0].Content.Parts[0].Text, nil
+	return reply.String(), nil
 }
 
-func DecideToUseRAG(
+func (g *geminiChatModel) generateContent(
 	ctx context.Context,
-	apiKey string,
 	chatHistories []*ChatHistory,
-) (bool, error) {
-	chatContents := make([]*GeminiChatContent, 0)
-	for _, chatHistory := range chatHistories {
-		chatContents = append(chatContents, &GeminiChatContent{
-			Parts: []*GeminiChatParts{
-				&GeminiChatParts{
-					Text: chatHistory.Chat,
-				},
-			},
-			Role: chatHistory.Role,
-		})
-	}
+	generationConfig *GeminiChatGenerationConfig,
+) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
 	payload := GeminiChatRequest{
-		Contents: chatContents,
-		GenerationConfig: &GeminiChatGenerationConfig{
-			ResponseMimeType: "application/json",
-			ResponseSchema: &GeminiChatResponseSchema{
-				Type: "OBJECT",
-				Properties: &GeminiChatAppSchema{
-					AnswerDirectly: &GeminiChaPropertySchema{
-						Type: "BOOLEAN",
-					},
-				},
-				Required: []string{
-					"answer_directly",
-				},
-			},
-		},
+		Contents:         g.toContents(chatHistories),
+		GenerationConfig: generationConfig,
 	}
 	payloadJson, err := json.Marshal(payload)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		"POST",
-		"https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash-exp:generateContent",
+		fmt.Sprintf("%s/models/%s:generateContent", g.baseUrl, g.model),
 		bytes.NewBuffer(payloadJson),
 	)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
-	req.Header.Set("x-goog-api-key", apiKey)
+	req.Header.Set("x-goog-api-key", g.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := g.client.Do(req)
 	if err != nil {
-		return false, err
+		return "", err
 	}
+	defer res.Body.Close()
 
 	resBody, err := io.ReadAll(res.Body)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return false, fmt.Errorf(
+		return "", fmt.Errorf(
 			"status error, got status %d. with response body %s",
 			res.StatusCode,
 			string(resBody),
@@ -195,14 +187,46 @@ func DecideToUseRAG(
 	var geminiRes GeminiChatResponse
 	err = json.Unmarshal(resBody, &geminiRes)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
-	var appSchema GeminiResponseAppSchema
-	err = json.Unmarshal([]byte(geminiRes.Candidates[0].Content.Parts[0].Text), &appSchema)
-	if err != nil {
-		return false, err
+	if len(geminiRes.Candidates) == 0 || geminiRes.Candidates[0].Content == nil || len(geminiRes.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("empty response from gemini, body %s", string(resBody))
+	}
+
+	return geminiRes.Candidates[0].Content.Parts[0].Text, nil
+}
+
+func (g *geminiChatModel) toContents(chatHistories []*ChatHistory) []*GeminiChatContent {
+	chatContents := make([]*GeminiChatContent, 0)
+	for _, chatHistory := range chatHistories {
+		chatContents = append(chatContents, &GeminiChatContent{
+			Parts: []*GeminiChatParts{
+				{
+					Text: chatHistory.Chat,
+				},
+			},
+			Role: chatHistory.Role,
+		})
 	}
 
-	return !appSchema.AnswerDirectly, nil
+	return chatContents
+}
+
+func NewGeminiChatModel(baseUrl string, apiKey string, model string, timeout time.Duration) ChatModel {
+	if baseUrl == "" {
+		baseUrl = defaultGeminiBaseUrl
+	}
+	if model == "" {
+		model = defaultGeminiChatModel
+	}
+
+	timeout = providerTimeout(timeout)
+	return &geminiChatModel{
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		timeout: timeout,
+		client:  newHTTPClient(timeout),
+	}
 }
@@ -0,0 +1,117 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestGeminiChatModel(baseUrl string) ChatModel {
+	return NewGeminiChatModel(baseUrl+"/", "secret", "", 0)
+}
+
+func geminiReply(text string) string {
+	res, _ := json.Marshal(GeminiChatResponse{
+		Candidates: []*GeminiChatCandidate{{Content: &GeminiChatContent{Parts: []*GeminiChatParts{{Text: text}}, Role: "model"}}},
+	})
+
+	return string(res)
+}
+
+func TestGeminiGenerateStructured(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/"+defaultGeminiChatModel+":generateContent" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("x-goog-api-key"); got != "secret" {
+			t.Errorf("got api key %q", got)
+		}
+
+		var payload GeminiChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		config := payload.GenerationConfig
+		if config == nil || config.ResponseMimeType != "application/json" || config.ResponseSchema.Type != SchemaTypeObject {
+			t.Errorf("unexpected generation config %+v", config)
+		}
+		if len(payload.Contents) != 1 || payload.Contents[0].Role != "user" || payload.Contents[0].Parts[0].Text != "hi" {
+			t.Errorf("unexpected contents %+v", payload.Contents)
+		}
+
+		w.Write([]byte(geminiReply(`{"answer_directly":false,"keywords":["notes"]}`)))
+	}))
+	defer server.Close()
+
+	var result testStructuredResult
+	err := newTestGeminiChatModel(server.URL).GenerateStructured(
+		context.Background(),
+		[]*ChatHistory{{Chat: "hi", Role: "user"}},
+		testSchema,
+		&result,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.AnswerDirectly || len(result.Keywords) != 1 || result.Keywords[0] != "notes" {
+		t.Fatalf("got %+v", result)
+	}
+}
+
+func TestGeminiGenerateStructuredErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "error status", status: http.StatusForbidden, body: `{"error":"denied"}`, wantErr: "status 403"},
+		{name: "no candidates", status: http.StatusOK, body: `{"candidates":[]}`, wantErr: "empty response"},
+		{name: "reply is not json", status: http.StatusOK, body: geminiReply("```json"), wantErr: "invalid character"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			var result testStructuredResult
+			err := newTestGeminiChatModel(server.URL).GenerateStructured(context.Background(), nil, testSchema, &result)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGeminiGenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/"+defaultGeminiChatModel+":streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected url %s", r.URL)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: " + geminiReply("Hello") + "\r\n\r\n"))
+		w.Write([]byte("data: " + geminiReply("") + "\r\n\r\n"))
+		w.Write([]byte("data: " + geminiReply(" there") + "\r\n\r\n"))
+	}))
+	defer server.Close()
+
+	deltas := make([]string, 0)
+	reply, err := newTestGeminiChatModel(server.URL).GenerateStream(context.Background(), nil, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reply != "Hello there" || len(deltas) != 2 {
+		t.Fatalf("got reply %q and deltas %q", reply, deltas)
+	}
+}
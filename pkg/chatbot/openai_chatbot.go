@@ -0,0 +1,265 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultOpenAIBaseUrl   = "https://api.openai.com/v1"
+	defaultOpenAIChatModel = "gpt-4o-mini"
+)
+
+type OpenAIChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type OpenAIChatJsonSchema struct {
+	Name   string  `json:"name"`
+	Schema *Schema `json:"schema"`
+}
+
+type OpenAIChatResponseFormat struct {
+	Type       string                `json:"type"`
+	JsonSchema *OpenAIChatJsonSchema `json:"json_schema,omitempty"`
+}
+
+type OpenAIChatRequest struct {
+	Model          string                    `json:"model"`
+	Messages       []*OpenAIChatMessage      `json:"messages"`
+	ResponseFormat *OpenAIChatResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                      `json:"stream,omitempty"`
+}
+
+type OpenAIChatChoice struct {
+	Message *OpenAIChatMessage `json:"message"`
+	Delta   *OpenAIChatMessage `json:"delta"`
+}
+
+type OpenAIChatResponse struct {
+	Choices []*OpenAIChatChoice `json:"choices"`
+}
+
+type openAIChatModel struct {
+	baseUrl string
+	apiKey  string
+	model   string
+	timeout time.Duration
+	client  *http.Client
+}
+
+func (o *openAIChatModel) Generate(ctx context.Context, chatHistories []*ChatHistory) (string, error) {
+	return o.chatCompletion(ctx, chatHistories, nil)
+}
+
+func (o *openAIChatModel) GenerateStructured(ctx context.Context, chatHistories []*ChatHistory, schema *Schema, result any) error {
+	reply, err := o.chatCompletion(ctx, chatHistories, &OpenAIChatResponseFormat{
+		Type: "json_schema",
+		JsonSchema: &OpenAIChatJsonSchema{
+			Name:   "response",
+			Schema: toOpenAISchema(schema),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(reply), result)
+}
+
+func (o *openAIChatModel) GenerateStream(ctx context.Context, chatHistories []*ChatHistory, onDelta func(delta string) error) (string, error) {
+	payload := OpenAIChatRequest{
+		Model:    o.model,
+		Messages: toOpenAIMessages(chatHistories),
+		Stream:   true,
+	}
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		o.baseUrl+"/chat/completions",
+		bytes.NewBuffer(payloadJson),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		resBody, _ := io.ReadAll(res.Body)
+		return "", fmt.Errorf(
+			"status error, got status %d. with response body %s",
+			res.StatusCode,
+			string(resBody),
+		)
+	}
+
+	reply := strings.Builder{}
+	err = readServerSentEvents(res.Body, func(data string) error {
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var openAIRes OpenAIChatResponse
+		err := json.Unmarshal([]byte(data), &openAIRes)
+		if err != nil {
+			return err
+		}
+
+		if len(openAIRes.Choices) == 0 || openAIRes.Choices[0].Delta == nil || openAIRes.Choices[0].Delta.Content == "" {
+			return nil
+		}
+		delta := openAIRes.Choices[0].Delta.Content
+		reply.WriteString(delta)
+
+		return onDelta(delta)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return reply.String(), nil
+}
+
+func (o *openAIChatModel) chatCompletion(
+	ctx context.Context,
+	chatHistories []*ChatHistory,
+	responseFormat *OpenAIChatResponseFormat,
+) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	payload := OpenAIChatRequest{
+		Model:          o.model,
+		Messages:       toOpenAIMessages(chatHistories),
+		ResponseFormat: responseFormat,
+	}
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		o.baseUrl+"/chat/completions",
+		bytes.NewBuffer(payloadJson),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf(
+			"status error, got status %d. with response body %s",
+			res.StatusCode,
+			string(resBody),
+		)
+	}
+
+	var openAIRes OpenAIChatResponse
+	err = json.Unmarshal(resBody, &openAIRes)
+	if err != nil {
+		return "", err
+	}
+
+	if len(openAIRes.Choices) == 0 || openAIRes.Choices[0].Message == nil {
+		return "", fmt.Errorf("empty response from chat completion, body %s", string(resBody))
+	}
+
+	return openAIRes.Choices[0].Message.Content, nil
+}
+
+func toOpenAIMessages(chatHistories []*ChatHistory) []*OpenAIChatMessage {
+	messages := make([]*OpenAIChatMessage, 0)
+	for _, chatHistory := range chatHistories {
+		role := chatHistory.Role
+		if role == "model" {
+			role = "assistant"
+		}
+
+		messages = append(messages, &OpenAIChatMessage{
+			Role:    role,
+			Content: chatHistory.Chat,
+		})
+	}
+
+	return messages
+}
+
+// toOpenAISchema converts the Gemini flavoured schema, which uses upper case
+// type names, into the lower case JSON Schema types OpenAI expects.
+func toOpenAISchema(schema *Schema) *Schema {
+	if schema == nil {
+		return nil
+	}
+
+	result := Schema{
+		Type:     strings.ToLower(schema.Type),
+		Items:    toOpenAISchema(schema.Items),
+		Required: schema.Required,
+	}
+	if schema.Properties != nil {
+		result.Properties = make(map[string]*Schema)
+		for name, property := range schema.Properties {
+			result.Properties[name] = toOpenAISchema(property)
+		}
+	}
+
+	return &result
+}
+
+func NewOpenAIChatModel(baseUrl string, apiKey string, model string, timeout time.Duration) ChatModel {
+	if baseUrl == "" {
+		baseUrl = defaultOpenAIBaseUrl
+	}
+	if model == "" {
+		model = defaultOpenAIChatModel
+	}
+
+	timeout = providerTimeout(timeout)
+	return &openAIChatModel{
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		timeout: timeout,
+		client:  newHTTPClient(timeout),
+	}
+}
@@ -0,0 +1,175 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSchema = &Schema{
+	Type: SchemaTypeObject,
+	Properties: map[string]*Schema{
+		"answer_directly": {Type: SchemaTypeBoolean},
+		"keywords":        {Type: SchemaTypeArray, Items: &Schema{Type: SchemaTypeString}},
+	},
+	Required: []string{"answer_directly"},
+}
+
+type testStructuredResult struct {
+	AnswerDirectly bool     `json:"answer_directly"`
+	Keywords       []string `json:"keywords"`
+}
+
+func openAIReply(content string) string {
+	res, _ := json.Marshal(OpenAIChatResponse{
+		Choices: []*OpenAIChatChoice{{Message: &OpenAIChatMessage{Role: "assistant", Content: content}}},
+	})
+
+	return string(res)
+}
+
+func TestOpenAIGenerateStructured(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("got authorization %q", got)
+		}
+
+		var payload OpenAIChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		if len(payload.Messages) != 2 || payload.Messages[1].Role != "assistant" {
+			t.Errorf("model role not mapped to assistant: %+v", payload.Messages)
+		}
+		format := payload.ResponseFormat
+		if format == nil || format.Type != "json_schema" || format.JsonSchema == nil {
+			t.Fatalf("missing json schema response format: %+v", format)
+		}
+		schema := format.JsonSchema.Schema
+		if schema.Type != "object" ||
+			schema.Properties["answer_directly"].Type != "boolean" ||
+			schema.Properties["keywords"].Items.Type != "string" {
+			t.Errorf("schema types not converted to lower case: %+v", schema)
+		}
+
+		w.Write([]byte(openAIReply(`{"answer_directly":true,"keywords":["go","sql"]}`)))
+	}))
+	defer server.Close()
+
+	chatModel := NewOpenAIChatModel(server.URL, "secret", "", 0)
+	var result testStructuredResult
+	err := chatModel.GenerateStructured(
+		context.Background(),
+		[]*ChatHistory{{Chat: "hi", Role: "user"}, {Chat: "hello", Role: "model"}},
+		testSchema,
+		&result,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.AnswerDirectly || len(result.Keywords) != 2 || result.Keywords[1] != "sql" {
+		t.Fatalf("got %+v", result)
+	}
+}
+
+func TestOpenAIGenerateStructuredErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "error status", status: http.StatusBadRequest, body: `{"error":"bad schema"}`, wantErr: "status 400"},
+		{name: "no choices", status: http.StatusOK, body: `{"choices":[]}`, wantErr: "empty response"},
+		{name: "reply is not json", status: http.StatusOK, body: openAIReply("Sure! Here it is"), wantErr: "invalid character"},
+		{name: "reply does not match the result", status: http.StatusOK, body: openAIReply(`{"answer_directly":"yes"}`), wantErr: "cannot unmarshal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			var result testStructuredResult
+			err := NewOpenAIChatModel(server.URL, "", "", 0).GenerateStructured(context.Background(), nil, testSchema, &result)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenAIGenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload OpenAIChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || !payload.Stream {
+			t.Errorf("stream not requested: %+v, %v", payload, err)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(": connected\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{"role":"assistant"}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"Hello"}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":", world"}}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	deltas := make([]string, 0)
+	reply, err := NewOpenAIChatModel(server.URL, "", "", 0).GenerateStream(context.Background(), nil, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reply != "Hello, world" || len(deltas) != 2 {
+		t.Fatalf("got reply %q and deltas %q", reply, deltas)
+	}
+}
+
+func TestOpenAITimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload OpenAIChatRequest
+		json.NewDecoder(r.Body).Decode(&payload)
+		if !payload.Stream {
+			<-r.Context().Done()
+			return
+		}
+
+		// A stream that outlasts the timeout is fine once it has started.
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, word := range []string{"slow", " but", " steady"} {
+			w.Write([]byte(`data: {"choices":[{"delta":{"content":"` + word + `"}}]}` + "\n\n"))
+			w.(http.Flusher).Flush()
+			time.Sleep(40 * time.Millisecond)
+		}
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	chatModel := NewOpenAIChatModel(server.URL, "", "", 50*time.Millisecond)
+	_, err := chatModel.Generate(context.Background(), nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want the deadline to be exceeded", err)
+	}
+
+	reply, err := chatModel.GenerateStream(context.Background(), nil, func(delta string) error { return nil })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reply != "slow but steady" {
+		t.Fatalf("got reply %q", reply)
+	}
+}
@@ -0,0 +1,98 @@
+package chatbot
+
+import (
+	"context"
+	"strings"
+)
+
+type decideUseRAGResult struct {
+	AnswerDirectly bool `json:"answer_directly"`
+}
+
+type condenseQuestionResult struct {
+	StandaloneQuestion string `json:"standalone_question"`
+}
+
+func DecideToUseRAG(
+	ctx context.Context,
+	chatModel ChatModel,
+	chatHistories []*ChatHistory,
+) (bool, error) {
+	schema := Schema{
+		Type: SchemaTypeObject,
+		Properties: map[string]*Schema{
+			"answer_directly": {
+				Type: SchemaTypeBoolean,
+			},
+		},
+		Required: []string{
+			"answer_directly",
+		},
+	}
+
+	var result decideUseRAGResult
+	err := chatModel.GenerateStructured(ctx, chatHistories, &schema, &result)
+	if err != nil {
+		return false, err
+	}
+
+	return !result.AnswerDirectly, nil
+}
+
+// CondenseQuestion rewrites a follow-up question into a question that can be
+// understood without chatHistories, e.g. "what about the second one?" into
+// the thing the second one refers to. It is used as the retrieval query.
+func CondenseQuestion(
+	ctx context.Context,
+	chatModel ChatModel,
+	chatHistories []*ChatHistory,
+	question string,
+) (string, error) {
+	if len(chatHistories) == 0 {
+		return question, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("Rewrite the follow-up question of the user into a standalone question that can be understood without the conversation, ")
+	b.WriteString("replacing pronouns and references to earlier messages with what they refer to. Keep the language of the question. ")
+	b.WriteString("If the question is already standalone, return it unchanged.\n\n")
+	b.WriteString("Conversation:\n")
+	writeTranscript(&b, chatHistories)
+	b.WriteString("Follow-up question: ")
+	b.WriteString(question)
+
+	schema := Schema{
+		Type: SchemaTypeObject,
+		Properties: map[string]*Schema{
+			"standalone_question": {
+				Type: SchemaTypeString,
+			},
+		},
+		Required: []string{
+			"standalone_question",
+		},
+	}
+
+	var result condenseQuestionResult
+	err := chatModel.GenerateStructured(
+		ctx,
+		[]*ChatHistory{
+			{
+				Chat: b.String(),
+				Role: "user",
+			},
+		},
+		&schema,
+		&result,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	standaloneQuestion := strings.TrimSpace(result.StandaloneQuestion)
+	if standaloneQuestion == "" {
+		return question, nil
+	}
+
+	return standaloneQuestion, nil
+}
@@ -0,0 +1,103 @@
+package chatbot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecideToUseRAG(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  bool
+	}{
+		{name: "answer directly", reply: `{"answer_directly":true}`, want: false},
+		{name: "use references", reply: `{"answer_directly":false}`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRAG, err := DecideToUseRAG(context.Background(), NewScriptedChatModel(tt.reply), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if useRAG != tt.want {
+				t.Fatalf("got %v, want %v", useRAG, tt.want)
+			}
+		})
+	}
+
+	_, err := DecideToUseRAG(context.Background(), NewScriptedChatModel("maybe"), nil)
+	if err == nil {
+		t.Fatal("expected an error for a reply that is not json")
+	}
+}
+
+func TestCondenseQuestion(t *testing.T) {
+	histories := []*ChatHistory{
+		{Chat: "Which notes mention Postgres?", Role: "user"},
+		{Chat: "Two: Indexing and Backups.", Role: "model"},
+	}
+
+	tests := []struct {
+		name      string
+		histories []*ChatHistory
+		replies   []string
+		want      string
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "without history the question is kept",
+			histories: nil,
+			want:      "what about the second one?",
+			wantCalls: 0,
+		},
+		{
+			name:      "rewritten question",
+			histories: histories,
+			replies:   []string{`{"standalone_question":"  What does the Backups note say?  "}`},
+			want:      "What does the Backups note say?",
+			wantCalls: 1,
+		},
+		{
+			name:      "empty rewrite falls back to the question",
+			histories: histories,
+			replies:   []string{`{"standalone_question":""}`},
+			want:      "what about the second one?",
+			wantCalls: 1,
+		},
+		{
+			name:      "model error",
+			histories: histories,
+			replies:   nil,
+			wantCalls: 1,
+			wantErr:   ErrScriptExhausted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatModel := NewScriptedChatModel(tt.replies...)
+			got, err := CondenseQuestion(context.Background(), chatModel, tt.histories, "what about the second one?")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			if len(chatModel.Calls) != tt.wantCalls {
+				t.Fatalf("model called %d times, want %d", len(chatModel.Calls), tt.wantCalls)
+			}
+			if tt.wantCalls > 0 {
+				prompt := chatModel.Calls[0][0].Chat
+				if !strings.Contains(prompt, "Assistant: Two: Indexing and Backups.") ||
+					!strings.Contains(prompt, "Follow-up question: what about the second one?") {
+					t.Fatalf("prompt misses the conversation or the question:\n%s", prompt)
+				}
+			}
+		})
+	}
+}
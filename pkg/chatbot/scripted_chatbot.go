@@ -0,0 +1,74 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+)
+
+var ErrScriptExhausted = errors.New("scripted chat model has no more replies")
+
+// ScriptedChatModel replays a fixed list of replies in order and records
+// every conversation it receives. It is meant to stand in for a real model
+// in tests.
+type ScriptedChatModel struct {
+	mu      sync.Mutex
+	replies []string
+	Calls   [][]*ChatHistory
+}
+
+func (s *ScriptedChatModel) Generate(ctx context.Context, chatHistories []*ChatHistory) (string, error) {
+	return s.next(chatHistories)
+}
+
+func (s *ScriptedChatModel) GenerateStructured(ctx context.Context, chatHistories []*ChatHistory, schema *Schema, result any) error {
+	reply, err := s.next(chatHistories)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(reply), result)
+}
+
+// GenerateStream emits the scripted reply word by word.
+func (s *ScriptedChatModel) GenerateStream(ctx context.Context, chatHistories []*ChatHistory, onDelta func(delta string) error) (string, error) {
+	reply, err := s.next(chatHistories)
+	if err != nil {
+		return "", err
+	}
+
+	for _, delta := range strings.SplitAfter(reply, " ") {
+		if delta == "" {
+			continue
+		}
+		err = onDelta(delta)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return reply, nil
+}
+
+func (s *ScriptedChatModel) next(chatHistories []*ChatHistory) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Calls = append(s.Calls, chatHistories)
+	if len(s.replies) == 0 {
+		return "", ErrScriptExhausted
+	}
+
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+
+	return reply, nil
+}
+
+func NewScriptedChatModel(replies ...string) *ScriptedChatModel {
+	return &ScriptedChatModel{
+		replies: replies,
+	}
+}
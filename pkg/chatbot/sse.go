@@ -0,0 +1,44 @@
+package chatbot
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+const maxServerSentEventSize = 1024 * 1024
+
+// readServerSentEvents calls onData with the data field of every event in
+// body until the stream ends.
+func readServerSentEvents(body io.Reader, onData func(data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxServerSentEventSize)
+
+	dataLines := make([]string, 0)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(dataLines) > 0 {
+				err := onData(strings.Join(dataLines, "\n"))
+				if err != nil {
+					return err
+				}
+				dataLines = dataLines[:0]
+			}
+			continue
+		}
+
+		if data, ok := strings.CutPrefix(line, "data:"); ok {
+			dataLines = append(dataLines, strings.TrimPrefix(data, " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(dataLines) > 0 {
+		return onData(strings.Join(dataLines, "\n"))
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package chatbot
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadServerSentEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []string
+	}{
+		{
+			name:   "single line events",
+			stream: "data: one\n\ndata: two\n\n",
+			want:   []string{"one", "two"},
+		},
+		{
+			name:   "multi-line data is joined with newlines",
+			stream: "data: first\ndata: second\ndata:third\n\n",
+			want:   []string{"first\nsecond\nthird"},
+		},
+		{
+			name:   "comments and other fields are ignored",
+			stream: ": keep-alive\nevent: message\nid: 1\ndata: payload\nretry: 100\n\n: ping\n\n",
+			want:   []string{"payload"},
+		},
+		{
+			name:   "only one leading space is removed",
+			stream: "data:  indented\n\n",
+			want:   []string{" indented"},
+		},
+		{
+			name:   "crlf line endings",
+			stream: "data: one\r\n\r\ndata: two\r\n\r\n",
+			want:   []string{"one", "two"},
+		},
+		{
+			name:   "last event without a trailing blank line",
+			stream: "data: one\n\ndata: partial",
+			want:   []string{"one", "partial"},
+		},
+		{
+			name:   "extra blank lines do not create events",
+			stream: "\n\n\ndata: one\n\n\n\n",
+			want:   []string{"one"},
+		},
+		{
+			name:   "empty stream",
+			stream: "",
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			// One byte per read splits every frame across reads.
+			err := readServerSentEvents(iotest.OneByteReader(strings.NewReader(tt.stream)), func(data string) error {
+				got = append(got, data)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadServerSentEventsStopsOnError(t *testing.T) {
+	errStop := errors.New("stop")
+	calls := 0
+	err := readServerSentEvents(strings.NewReader("data: one\n\ndata: two\n\n"), func(data string) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("got error %v, want %v", err, errStop)
+	}
+	if calls != 1 {
+		t.Fatalf("onData called %d times after returning an error", calls)
+	}
+}
+
+func TestReadServerSentEventsReadError(t *testing.T) {
+	errRead := errors.New("connection reset")
+	err := readServerSentEvents(iotest.ErrReader(errRead), func(data string) error {
+		return nil
+	})
+	if !errors.Is(err, errRead) {
+		t.Fatalf("got error %v, want %v", err, errRead)
+	}
+}
+
+func TestReadServerSentEventsTooLarge(t *testing.T) {
+	stream := "data: " + strings.Repeat("a", maxServerSentEventSize) + "\n\n"
+	err := readServerSentEvents(strings.NewReader(stream), func(data string) error {
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected an error for a line over the size limit")
+	}
+}
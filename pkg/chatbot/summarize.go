@@ -0,0 +1,67 @@
+package chatbot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SummaryMaxWords is the length the model is asked to keep summaries under,
+// so the rolling summary does not grow with the conversation.
+const SummaryMaxWords = 300
+
+// EstimateTokens approximates the number of tokens in text at four
+// characters per token, which is close enough for budgeting with both the
+// Gemini and OpenAI tokenizers.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Summarize folds chatHistories into previousSummary and returns the new
+// summary of the whole conversation.
+func Summarize(
+	ctx context.Context,
+	chatModel ChatModel,
+	previousSummary string,
+	chatHistories []*ChatHistory,
+) (string, error) {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Summarize the conversation below between a user and an assistant in at most %d words. ", SummaryMaxWords))
+	b.WriteString("Keep the facts, names, decisions and open questions needed to continue the conversation, and write the summary in the language of the conversation. Reply with the summary only.\n\n")
+	if previousSummary != "" {
+		b.WriteString("Summary of the conversation before these messages:\n")
+		b.WriteString(previousSummary)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("Messages:\n")
+	writeTranscript(&b, chatHistories)
+
+	reply, err := chatModel.Generate(ctx, []*ChatHistory{
+		{
+			Chat: b.String(),
+			Role: "user",
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(reply), nil
+}
+
+// writeTranscript writes the conversation as "User:" and "Assistant:" lines,
+// for prompts that ask the model about a conversation instead of continuing
+// it.
+func writeTranscript(b *strings.Builder, chatHistories []*ChatHistory) {
+	for _, chatHistory := range chatHistories {
+		speaker := "User"
+		if chatHistory.Role != "user" {
+			speaker = "Assistant"
+		}
+		b.WriteString(speaker)
+		b.WriteString(": ")
+		b.WriteString(chatHistory.Chat)
+		b.WriteString("\n\n")
+	}
+}
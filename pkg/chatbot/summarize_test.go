@@ -0,0 +1,56 @@
+package chatbot
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "", want: 0},
+		{text: "abc", want: 1},
+		{text: "abcd", want: 1},
+		{text: "abcde", want: 2},
+		{text: "ééééé", want: 2},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	chatModel := NewScriptedChatModel("  The user asked about backups.\n")
+	summary, err := Summarize(
+		context.Background(),
+		chatModel,
+		"The user keeps notes on Postgres.",
+		[]*ChatHistory{
+			{Chat: "How do I back up?", Role: "user"},
+			{Chat: "Use pg_dump.", Role: "model"},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary != "The user asked about backups." {
+		t.Fatalf("got %q", summary)
+	}
+
+	prompt := chatModel.Calls[0][0].Chat
+	for _, want := range []string{
+		"The user keeps notes on Postgres.",
+		"User: How do I back up?",
+		"Assistant: Use pg_dump.",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt misses %q:\n%s", want, prompt)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package chunking
+
+import (
+	"fmt"
+	"unicode"
+)
+
+const (
+	StrategyMarkdown    = "markdown"
+	StrategyTokenWindow = "token"
+
+	defaultChunkSize = 256
+)
+
+type Chunk struct {
+	Index int
+	Text  string
+	// StartOffset and EndOffset are character (rune) offsets into the
+	// original text, EndOffset being exclusive.
+	StartOffset int
+	EndOffset   int
+}
+
+type Chunker interface {
+	Chunk(text string) []*Chunk
+}
+
+type Config struct {
+	Strategy string
+	Size     int
+	Overlap  int
+}
+
+func NewChunker(config Config) (Chunker, error) {
+	switch config.Strategy {
+	case "", StrategyMarkdown:
+		return NewMarkdownChunker(config.Size, config.Overlap), nil
+	case StrategyTokenWindow:
+		return NewTokenWindowChunker(config.Size, config.Overlap), nil
+	default:
+		return nil, fmt.Errorf("unknown chunking strategy %q", config.Strategy)
+	}
+}
+
+type token struct {
+	start int
+	end   int
+}
+
+// tokenize splits runes into whitespace separated words. A word is used as
+// an approximation of a model token.
+func tokenize(runes []rune, start int, end int) []token {
+	tokens := make([]token, 0)
+	tokenStart := -1
+	for i := start; i < end; i++ {
+		if unicode.IsSpace(runes[i]) {
+			if tokenStart >= 0 {
+				tokens = append(tokens, token{start: tokenStart, end: i})
+				tokenStart = -1
+			}
+			continue
+		}
+		if tokenStart < 0 {
+			tokenStart = i
+		}
+	}
+	if tokenStart >= 0 {
+		tokens = append(tokens, token{start: tokenStart, end: end})
+	}
+
+	return tokens
+}
+
+func normalizeWindow(size int, overlap int) (int, int) {
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	return size, overlap
+}
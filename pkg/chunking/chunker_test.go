@@ -0,0 +1,256 @@
+package chunking
+
+import (
+	"fmt"
+	"testing"
+)
+
+// checkChunks compares the chunk texts with want and checks that the rune
+// offsets of every chunk point back at its text.
+func checkChunks(t *testing.T, text string, chunks []*Chunk, want []string) {
+	t.Helper()
+	runes := []rune(text)
+	if len(chunks) != len(want) {
+		texts := make([]string, 0)
+		for _, chunk := range chunks {
+			texts = append(texts, chunk.Text)
+		}
+		t.Fatalf("got chunks %q, want %q", texts, want)
+	}
+	for i, chunk := range chunks {
+		if chunk.Index != i {
+			t.Errorf("chunk %d has index %d", i, chunk.Index)
+		}
+		if chunk.Text != want[i] {
+			t.Errorf("chunk %d is %q, want %q", i, chunk.Text, want[i])
+		}
+		if chunk.StartOffset < 0 || chunk.EndOffset > len(runes) || chunk.StartOffset > chunk.EndOffset {
+			t.Fatalf("chunk %d has offsets %d-%d out of %d runes", i, chunk.StartOffset, chunk.EndOffset, len(runes))
+		}
+		if got := string(runes[chunk.StartOffset:chunk.EndOffset]); got != chunk.Text {
+			t.Errorf("chunk %d offsets %d-%d point at %q, not %q", i, chunk.StartOffset, chunk.EndOffset, got, chunk.Text)
+		}
+	}
+}
+
+func TestMarkdownChunker(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		overlap int
+		text    string
+		want    []string
+	}{
+		{
+			name: "empty",
+			size: 4,
+			text: "",
+			want: []string{},
+		},
+		{
+			name: "whitespace only",
+			size: 4,
+			text: " \n\n\t \n",
+			want: []string{},
+		},
+		{
+			name: "empty heading",
+			size: 4,
+			text: "  \n\t\n# \n ",
+			want: []string{"#"},
+		},
+		{
+			name: "small sections are merged",
+			size: 10,
+			text: "# A\nalpha beta\n\n# B\ngamma\n",
+			want: []string{"# A\nalpha beta\n\n# B\ngamma"},
+		},
+		{
+			name: "sections are split at headings",
+			size: 4,
+			text: "# A\nalpha beta\n## B\ngamma delta",
+			want: []string{"# A\nalpha beta", "## B\ngamma delta"},
+		},
+		{
+			name: "hash without space is not a heading",
+			size: 3,
+			text: "#tag one\n#tag two",
+			want: []string{"#tag one\n#tag", "two"},
+		},
+		{
+			name:    "large section falls back to the window",
+			size:    3,
+			overlap: 1,
+			text:    "# T\none two three four",
+			want:    []string{"# T\none", "one two three", "three four"},
+		},
+		{
+			name: "small sections around a large one",
+			size: 3,
+			text: "intro\n# Big\na b c d e\n# S\nz",
+			want: []string{"intro", "# Big\na", "b c d", "e", "# S\nz"},
+		},
+		{
+			name: "multibyte text",
+			size: 4,
+			text: "# Grüße\nnaïve café 日本語 テキスト\n# 二\n😀 emoji",
+			want: []string{"# Grüße\nnaïve café", "日本語 テキスト", "# 二\n😀 emoji"},
+		},
+		{
+			name:    "overlap as large as the size is dropped",
+			size:    2,
+			overlap: 2,
+			text:    "a b c d e",
+			want:    []string{"a b", "c d", "e"},
+		},
+		{
+			name: "zero size uses the default",
+			text: "a b c d e",
+			want: []string{"a b c d e"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := NewMarkdownChunker(tt.size, tt.overlap).Chunk(tt.text)
+			checkChunks(t, tt.text, chunks, tt.want)
+		})
+	}
+}
+
+func TestMarkdownSectionsSkipCodeFences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "backtick fence",
+			text: "# A\n```sh\n# not a heading\n```\n# B\nx",
+			want: []string{"# A\n```sh\n# not a heading\n```", "# B\nx"},
+		},
+		{
+			name: "tilde fence",
+			text: "# A\n~~~\n# comment\n~~~\n# B\nx",
+			want: []string{"# A\n~~~\n# comment\n~~~", "# B\nx"},
+		},
+		{
+			name: "unclosed fence",
+			text: "# A\n```\n# B\nx",
+			want: []string{"# A\n```\n# B\nx"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMarkdownChunker(100, 0).(*markdownChunker)
+			runes := []rune(tt.text)
+			sections := m.splitSections(runes)
+			got := make([]string, 0)
+			for _, s := range sections {
+				got = append(got, string(runes[s.start:s.end]))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("got sections %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenWindowChunker(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		overlap int
+		text    string
+		want    []string
+	}{
+		{
+			name: "empty",
+			size: 3,
+			text: "",
+			want: []string{},
+		},
+		{
+			name: "whitespace only",
+			size: 3,
+			text: " \n\t ",
+			want: []string{},
+		},
+		{
+			name:    "windows overlap",
+			size:    3,
+			overlap: 1,
+			text:    "one two  three\nfour five",
+			want:    []string{"one two  three", "three\nfour five"},
+		},
+		{
+			name: "headings are not special",
+			size: 2,
+			text: "# A\nb",
+			want: []string{"# A", "b"},
+		},
+		{
+			name: "multibyte text",
+			size: 2,
+			text: " héllo wörld\n😀 ok 日本",
+			want: []string{"héllo wörld", "😀 ok", "日本"},
+		},
+		{
+			name:    "overlap larger than the size is dropped",
+			size:    2,
+			overlap: 5,
+			text:    "a b c",
+			want:    []string{"a b", "c"},
+		},
+		{
+			name:    "negative overlap is dropped",
+			size:    2,
+			overlap: -1,
+			text:    "a b c",
+			want:    []string{"a b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := NewTokenWindowChunker(tt.size, tt.overlap).Chunk(tt.text)
+			checkChunks(t, tt.text, chunks, tt.want)
+		})
+	}
+}
+
+func TestChunkOffsetsAreRunes(t *testing.T) {
+	chunks := NewTokenWindowChunker(1, 0).Chunk("ünïcödé 😀x")
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+	if chunks[1].StartOffset != 8 || chunks[1].EndOffset != 10 {
+		t.Fatalf("got offsets %d-%d, want 8-10", chunks[1].StartOffset, chunks[1].EndOffset)
+	}
+}
+
+func TestNewChunker(t *testing.T) {
+	tests := []struct {
+		strategy string
+		wantType string
+		wantErr  bool
+	}{
+		{strategy: "", wantType: "*chunking.markdownChunker"},
+		{strategy: StrategyMarkdown, wantType: "*chunking.markdownChunker"},
+		{strategy: StrategyTokenWindow, wantType: "*chunking.tokenWindowChunker"},
+		{strategy: "sentence", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			chunker, err := NewChunker(Config{Strategy: tt.strategy})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got := fmt.Sprintf("%T", chunker); !tt.wantErr && got != tt.wantType {
+				t.Fatalf("got %s, want %s", got, tt.wantType)
+			}
+		})
+	}
+}
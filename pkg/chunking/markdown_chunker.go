@@ -0,0 +1,132 @@
+package chunking
+
+import (
+	"strings"
+)
+
+type markdownChunker struct {
+	size    int
+	overlap int
+}
+
+type section struct {
+	start      int
+	end        int
+	tokenCount int
+}
+
+// Chunk splits text at markdown headings, merges consecutive small sections
+// while they fit in one window and falls back to a token window for sections
+// that are larger than the window.
+func (m *markdownChunker) Chunk(text string) []*Chunk {
+	runes := []rune(text)
+	sections := m.splitSections(runes)
+
+	chunks := make([]*Chunk, 0)
+	var pending *section
+	flush := func() {
+		if pending == nil {
+			return
+		}
+		chunks = append(chunks, m.toChunk(runes, pending.start, pending.end))
+		pending = nil
+	}
+
+	for _, s := range sections {
+		if s.tokenCount == 0 {
+			continue
+		}
+
+		if s.tokenCount > m.size {
+			flush()
+			chunks = append(chunks, windowChunks(runes, s.start, s.end, m.size, m.overlap)...)
+			continue
+		}
+
+		if pending != nil && pending.tokenCount+s.tokenCount > m.size {
+			flush()
+		}
+		if pending == nil {
+			current := s
+			pending = &current
+			continue
+		}
+		pending.end = s.end
+		pending.tokenCount += s.tokenCount
+	}
+	flush()
+
+	for i, chunk := range chunks {
+		chunk.Index = i
+	}
+
+	return chunks
+}
+
+func (m *markdownChunker) splitSections(runes []rune) []section {
+	sections := make([]section, 0)
+	sectionStart := 0
+	inCodeFence := false
+
+	lineStart := 0
+	for lineStart < len(runes) {
+		lineEnd := lineStart
+		for lineEnd < len(runes) && runes[lineEnd] != '\n' {
+			lineEnd++
+		}
+		line := strings.TrimSpace(string(runes[lineStart:lineEnd]))
+
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inCodeFence = !inCodeFence
+		} else if !inCodeFence && isHeading(line) && lineStart > sectionStart {
+			sections = append(sections, m.newSection(runes, sectionStart, lineStart))
+			sectionStart = lineStart
+		}
+
+		lineStart = lineEnd + 1
+	}
+	if sectionStart < len(runes) {
+		sections = append(sections, m.newSection(runes, sectionStart, len(runes)))
+	}
+
+	return sections
+}
+
+func (m *markdownChunker) newSection(runes []rune, start int, end int) section {
+	tokens := tokenize(runes, start, end)
+	if len(tokens) == 0 {
+		return section{start: start, end: end}
+	}
+
+	return section{
+		start:      tokens[0].start,
+		end:        tokens[len(tokens)-1].end,
+		tokenCount: len(tokens),
+	}
+}
+
+func (m *markdownChunker) toChunk(runes []rune, start int, end int) *Chunk {
+	return &Chunk{
+		Text:        string(runes[start:end]),
+		StartOffset: start,
+		EndOffset:   end,
+	}
+}
+
+func isHeading(line string) bool {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+
+	return level > 0 && level <= 6 && (level == len(line) || line[level] == ' ')
+}
+
+func NewMarkdownChunker(size int, overlap int) Chunker {
+	size, overlap = normalizeWindow(size, overlap)
+
+	return &markdownChunker{
+		size:    size,
+		overlap: overlap,
+	}
+}
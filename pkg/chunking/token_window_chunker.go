@@ -0,0 +1,57 @@
+package chunking
+
+type tokenWindowChunker struct {
+	size    int
+	overlap int
+}
+
+func (t *tokenWindowChunker) Chunk(text string) []*Chunk {
+	runes := []rune(text)
+	chunks := windowChunks(runes, 0, len(runes), t.size, t.overlap)
+	for i, chunk := range chunks {
+		chunk.Index = i
+	}
+
+	return chunks
+}
+
+// windowChunks slides a window of size tokens over runes[start:end],
+// advancing size-overlap tokens each step.
+func windowChunks(runes []rune, start int, end int, size int, overlap int) []*Chunk {
+	tokens := tokenize(runes, start, end)
+	chunks := make([]*Chunk, 0)
+	if len(tokens) == 0 {
+		return chunks
+	}
+
+	step := size - overlap
+	for i := 0; i < len(tokens); i += step {
+		last := i + size
+		if last > len(tokens) {
+			last = len(tokens)
+		}
+
+		chunkStart := tokens[i].start
+		chunkEnd := tokens[last-1].end
+		chunks = append(chunks, &Chunk{
+			Text:        string(runes[chunkStart:chunkEnd]),
+			StartOffset: chunkStart,
+			EndOffset:   chunkEnd,
+		})
+
+		if last == len(tokens) {
+			break
+		}
+	}
+
+	return chunks
+}
+
+func NewTokenWindowChunker(size int, overlap int) Chunker {
+	size, overlap = normalizeWindow(size, overlap)
+
+	return &tokenWindowChunker{
+		size:    size,
+		overlap: overlap,
+	}
+}
@@ -0,0 +1,280 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationLockId is an arbitrary key for pg_advisory_lock so that only one
+// instance runs migrations at a time.
+const migrationLockId = 7243901
+
+var (
+	migrationFileRegex     = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+	migrationVariableRegex = regexp.MustCompile(`\$\{(\w+)\}`)
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []*Migration
+}
+
+// NewMigrator loads migrations named <version>_<name>.up.sql and
+// <version>_<name>.down.sql from the root of fsys. Every ${NAME} in a script
+// is replaced by vars[NAME], for settings the schema depends on.
+func NewMigrator(db *pgxpool.Pool, fsys fs.FS, vars map[string]string) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	migrationsByVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		matches := migrationFileRegex.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		script, err := expandMigrationVariables(string(content), vars)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := migrationsByVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			migrationsByVersion[version] = migration
+		}
+		if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			migration.Up = script
+		} else {
+			migration.Down = script
+		}
+	}
+
+	migrations := make([]*Migration, 0)
+	for _, migration := range migrationsByVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies every pending migration in order and returns the applied ones.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	applied := make([]*Migration, 0)
+	err := m.withLock(ctx, func() error {
+		appliedAt, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := appliedAt[migration.Version]; ok {
+				continue
+			}
+
+			err = m.run(ctx, migration.Up, `INSERT INTO schema_migration (version, name, applied_at) VALUES ($1, $2, $3)`, migration.Version, migration.Name, time.Now())
+			if err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// Down reverts the latest steps applied migrations and returns the reverted
+// ones.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	reverted := make([]*Migration, 0)
+	err := m.withLock(ctx, func() error {
+		appliedAt, err := m.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := appliedAt[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+			}
+
+			err = m.run(ctx, migration.Down, `DELETE FROM schema_migration WHERE version = $1`, migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reverted, nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	err := m.ensureTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedAt, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*MigrationStatus, 0)
+	for _, migration := range m.migrations {
+		status := MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		result = append(result, &status)
+	}
+
+	return result, nil
+}
+
+// run executes a migration script and its bookkeeping statement in a single
+// transaction.
+func (m *Migrator) run(ctx context.Context, script string, bookkeeping string, args ...any) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, script)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, bookkeeping, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func expandMigrationVariables(script string, vars map[string]string) (string, error) {
+	var err error
+	script = migrationVariableRegex.ReplaceAllStringFunc(script, func(match string) string {
+		name := migrationVariableRegex.FindStringSubmatch(match)[1]
+		value, ok := vars[name]
+		if !ok && err == nil {
+			err = fmt.Errorf("variable %s is not set", name)
+		}
+		return value
+	})
+
+	return script, err
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockId)
+	if err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockId)
+
+	err = m.ensureTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	return fn()
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.Exec(
+		ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migration (version BIGINT PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMPTZ NOT NULL)`,
+	)
+
+	return err
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := m.db.Query(ctx, `SELECT version, applied_at FROM schema_migration`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		result[version] = appliedAt
+	}
+
+	return result, rows.Err()
+}
@@ -12,3 +12,9 @@ type DatabaseQueryer interface {
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
+
+// DatabaseBeginner is implemented by both the pool and a transaction, where
+// Begin starts a savepoint.
+type DatabaseBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
@@ -0,0 +1,86 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	TaskTypeRetrievalDocument = "RETRIEVAL_DOCUMENT"
+	TaskTypeRetrievalQuery    = "RETRIEVAL_QUERY"
+
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+	ProviderHash   = "hash"
+
+	defaultTimeout = time.Minute
+)
+
+type Embedder interface {
+	Embed(ctx context.Context, text string, taskType string) ([]float32, error)
+}
+
+type Config struct {
+	Provider  string
+	Model     string
+	BaseUrl   string
+	ApiKey    string
+	Dimension int
+	// Timeout bounds each call to the provider, 0 uses a minute.
+	Timeout time.Duration
+}
+
+func NewEmbedder(config Config) (Embedder, error) {
+	switch config.Provider {
+	case "", ProviderGemini:
+		return NewGeminiEmbedder(config.BaseUrl, config.ApiKey, config.Model, config.Dimension, config.Timeout), nil
+	case ProviderOpenAI:
+		return NewOpenAIEmbedder(config.BaseUrl, config.ApiKey, config.Model, config.Dimension, config.Timeout), nil
+	case ProviderOllama:
+		return NewOllamaEmbedder(config.BaseUrl, config.Model, config.Timeout), nil
+	case ProviderHash:
+		return NewHashEmbedder(config.Dimension), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", config.Provider)
+	}
+}
+
+// newHTTPClient gives up on a provider that does not answer within timeout,
+// so that a hung call cannot hold a job past its lease.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return &http.Client{Timeout: timeout}
+}
+
+type dimensionCheckedEmbedder struct {
+	embedder  Embedder
+	dimension int
+}
+
+func (d *dimensionCheckedEmbedder) Embed(ctx context.Context, text string, taskType string) ([]float32, error) {
+	values, err := d.embedder.Embed(ctx, text, taskType)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != d.dimension {
+		return nil, fmt.Errorf("embedding has %d dimensions, expected %d", len(values), d.dimension)
+	}
+
+	return values, nil
+}
+
+// WithDimension fails every embedding of embedder that does not have exactly
+// dimension values, so a model that does not match the vector column is
+// reported as such instead of by the database.
+func WithDimension(embedder Embedder, dimension int) Embedder {
+	return &dimensionCheckedEmbedder{
+		embedder:  embedder,
+		dimension: dimension,
+	}
+}
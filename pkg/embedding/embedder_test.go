@@ -0,0 +1,86 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewEmbedder(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		wantType string
+		wantErr  bool
+	}{
+		{name: "default is gemini", config: Config{}, wantType: "*embedding.geminiEmbedder"},
+		{name: "gemini", config: Config{Provider: ProviderGemini}, wantType: "*embedding.geminiEmbedder"},
+		{name: "openai", config: Config{Provider: ProviderOpenAI}, wantType: "*embedding.openAIEmbedder"},
+		{name: "ollama", config: Config{Provider: ProviderOllama}, wantType: "*embedding.ollamaEmbedder"},
+		{name: "hash", config: Config{Provider: ProviderHash}, wantType: "*embedding.hashEmbedder"},
+		{name: "unknown", config: Config{Provider: "word2vec"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embedder, err := NewEmbedder(tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %T", embedder)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := fmt.Sprintf("%T", embedder); got != tt.wantType {
+				t.Fatalf("got %s, want %s", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestWithDimension(t *testing.T) {
+	embedder := WithDimension(NewHashEmbedder(8), 8)
+	values, err := embedder.Embed(context.Background(), "hello", TaskTypeRetrievalDocument)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(values) != 8 {
+		t.Fatalf("got %d values, want 8", len(values))
+	}
+
+	_, err = WithDimension(NewHashEmbedder(8), 16).Embed(context.Background(), "hello", TaskTypeRetrievalDocument)
+	if err == nil || !strings.Contains(err.Error(), "has 8 dimensions, expected 16") {
+		t.Fatalf("got error %v, want a dimension mismatch", err)
+	}
+}
+
+func TestEmbedderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	for _, provider := range []string{ProviderGemini, ProviderOpenAI, ProviderOllama} {
+		t.Run(provider, func(t *testing.T) {
+			embedder, err := NewEmbedder(Config{Provider: provider, BaseUrl: server.URL, Timeout: 50 * time.Millisecond})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = embedder.Embed(context.Background(), "hello", TaskTypeRetrievalQuery)
+			var netErr interface{ Timeout() bool }
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				t.Fatalf("got error %v, want a timeout", err)
+			}
+		})
+	}
+}
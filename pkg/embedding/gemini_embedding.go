@@ -2,10 +2,18 @@ package embedding
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGeminiEmbeddingModel = "gemini-embedding-exp-03-07"
+	defaultGeminiBaseUrl        = "https://generativelanguage.googleapis.com/v1beta"
 )
 
 type EmbeddingRequestContentPart struct {
@@ -17,9 +25,10 @@ type EmbeddingRequestContent struct {
 }
 
 type EmbeddingRequest struct {
-	Model    string                  `json:"model"`
-	Content  EmbeddingRequestContent `json:"content"`
-	TaskType string                  `json:"task_type"`
+	Model                string                  `json:"model"`
+	Content              EmbeddingRequestContent `json:"content"`
+	TaskType             string                  `json:"task_type"`
+	OutputDimensionality int                     `json:"outputDimensionality,omitempty"`
 }
 
 type EmbeddingResponseEmbedding struct {
@@ -30,13 +39,17 @@ type EmbeddingResponse struct {
 	Embedding EmbeddingResponseEmbedding `json:"embedding"`
 }
 
-func GetGeminiEmbedding(
-	apiKey string,
-	text string,
-	taskType string,
-) (*EmbeddingResponse, error) {
+type geminiEmbedder struct {
+	baseUrl   string
+	apiKey    string
+	model     string
+	dimension int
+	client    *http.Client
+}
+
+func (g *geminiEmbedder) Embed(ctx context.Context, text string, taskType string) ([]float32, error) {
 	geminiReq := EmbeddingRequest{
-		Model: "models/gemini-embedding-exp-03-07",
+		Model: "models/" + g.model,
 		Content: EmbeddingRequestContent{
 			Parts: []EmbeddingRequestContentPart{
 				{
@@ -44,29 +57,31 @@ func GetGeminiEmbedding(
 				},
 			},
 		},
-		TaskType: taskType,
+		TaskType:             taskType,
+		OutputDimensionality: g.dimension,
 	}
 	geminiReqJson, err := json.Marshal(geminiReq)
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		"POST",
-		"https://generativelanguage.googleapis.com/v1beta/models/gemini-embedding-exp-03-07:embedContent",
+		fmt.Sprintf("%s/models/%s:embedContent", g.baseUrl, g.model),
 		bytes.NewBuffer(geminiReqJson),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("X-goog-api-key", apiKey)
+	req.Header.Set("X-goog-api-key", g.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := g.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer res.Body.Close()
 
 	resByte, err := io.ReadAll(res.Body)
 	if err != nil {
@@ -83,5 +98,24 @@ func GetGeminiEmbedding(
 		return nil, err
 	}
 
-	return &resEmbedding, nil
+	return resEmbedding.Embedding.Values, nil
+}
+
+// NewGeminiEmbedder returns an embedder for the Gemini API. A dimension of 0
+// keeps the default of the model.
+func NewGeminiEmbedder(baseUrl string, apiKey string, model string, dimension int, timeout time.Duration) Embedder {
+	if baseUrl == "" {
+		baseUrl = defaultGeminiBaseUrl
+	}
+	if model == "" {
+		model = defaultGeminiEmbeddingModel
+	}
+
+	return &geminiEmbedder{
+		baseUrl:   strings.TrimSuffix(baseUrl, "/"),
+		apiKey:    apiKey,
+		model:     model,
+		dimension: dimension,
+		client:    newHTTPClient(timeout),
+	}
 }
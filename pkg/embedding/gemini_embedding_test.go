@@ -0,0 +1,61 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestGeminiEmbedder(baseUrl string) Embedder {
+	return NewGeminiEmbedder(baseUrl+"/", "secret", "", 0, 0)
+}
+
+func TestGeminiEmbedderEmbed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/"+defaultGeminiEmbeddingModel+":embedContent" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("X-goog-api-key"); got != "secret" {
+			t.Errorf("got api key %q", got)
+		}
+
+		var payload EmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		if payload.Model != "models/"+defaultGeminiEmbeddingModel ||
+			payload.TaskType != TaskTypeRetrievalQuery ||
+			payload.OutputDimensionality != 0 ||
+			len(payload.Content.Parts) != 1 ||
+			payload.Content.Parts[0].Text != "hello" {
+			t.Errorf("unexpected request %+v", payload)
+		}
+
+		w.Write([]byte(`{"embedding":{"values":[0.5,-0.5]}}`))
+	}))
+	defer server.Close()
+
+	values, err := newTestGeminiEmbedder(server.URL).Embed(context.Background(), "hello", TaskTypeRetrievalQuery)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(values) != 2 || values[0] != 0.5 || values[1] != -0.5 {
+		t.Fatalf("got %v", values)
+	}
+}
+
+func TestGeminiEmbedderErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"status":"RESOURCE_EXHAUSTED"}}`))
+	}))
+	defer server.Close()
+
+	_, err := newTestGeminiEmbedder(server.URL).Embed(context.Background(), "hello", TaskTypeRetrievalQuery)
+	if err == nil || !strings.Contains(err.Error(), "code 429") || !strings.Contains(err.Error(), "RESOURCE_EXHAUSTED") {
+		t.Fatalf("got error %v, want the status and body", err)
+	}
+}
@@ -0,0 +1,63 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const defaultHashDimension = 3072
+
+type hashEmbedder struct {
+	dimension int
+}
+
+// Embed builds a bag-of-words vector using the hashing trick. It is
+// deterministic and needs no network, which makes it usable for offline
+// development and tests, but it has no semantic understanding.
+func (h *hashEmbedder) Embed(ctx context.Context, text string, taskType string) ([]float32, error) {
+	values := make([]float32, h.dimension)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		hasher := fnv.New64a()
+		hasher.Write([]byte(word))
+		sum := hasher.Sum64()
+
+		index := int(sum % uint64(h.dimension))
+		if sum&(1<<63) != 0 {
+			values[index] -= 1
+		} else {
+			values[index] += 1
+		}
+	}
+
+	var norm float64
+	for _, value := range values {
+		norm += float64(value) * float64(value)
+	}
+	if norm == 0 {
+		return values, nil
+	}
+
+	norm = math.Sqrt(norm)
+	for i := range values {
+		values[i] = float32(float64(values[i]) / norm)
+	}
+
+	return values, nil
+}
+
+func NewHashEmbedder(dimension int) Embedder {
+	if dimension <= 0 {
+		dimension = defaultHashDimension
+	}
+
+	return &hashEmbedder{
+		dimension: dimension,
+	}
+}
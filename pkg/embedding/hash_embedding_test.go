@@ -0,0 +1,66 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestHashEmbedderDimension(t *testing.T) {
+	tests := []struct {
+		name      string
+		dimension int
+		want      int
+	}{
+		{name: "configured", dimension: 8, want: 8},
+		{name: "zero uses default", dimension: 0, want: defaultHashDimension},
+		{name: "negative uses default", dimension: -1, want: defaultHashDimension},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := NewHashEmbedder(tt.dimension).Embed(context.Background(), "hello world", TaskTypeRetrievalDocument)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(values) != tt.want {
+				t.Fatalf("got %d values, want %d", len(values), tt.want)
+			}
+		})
+	}
+}
+
+func TestHashEmbedderEmbed(t *testing.T) {
+	embedder := NewHashEmbedder(64)
+	ctx := context.Background()
+
+	a, err := embedder.Embed(ctx, "The quick brown fox", TaskTypeRetrievalDocument)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := embedder.Embed(ctx, "the QUICK, brown fox!", TaskTypeRetrievalQuery)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var norm float64
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("value %d differs for texts with the same words: %v != %v", i, a[i], b[i])
+		}
+		norm += float64(a[i]) * float64(a[i])
+	}
+	if math.Abs(norm-1) > 1e-6 {
+		t.Fatalf("vector is not normalized, squared norm %v", norm)
+	}
+
+	empty, err := embedder.Embed(ctx, "  ...  ", TaskTypeRetrievalDocument)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, value := range empty {
+		if value != 0 {
+			t.Fatalf("value %d of a text without words is %v, want 0", i, value)
+		}
+	}
+}
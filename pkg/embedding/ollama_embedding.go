@@ -0,0 +1,90 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultOllamaBaseUrl = "http://localhost:11434"
+
+type OllamaEmbeddingRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+}
+
+type OllamaEmbeddingResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+type ollamaEmbedder struct {
+	baseUrl string
+	model   string
+	client  *http.Client
+}
+
+func (o *ollamaEmbedder) Embed(ctx context.Context, text string, taskType string) ([]float32, error) {
+	payload := OllamaEmbeddingRequest{
+		Model: o.model,
+		Input: text,
+	}
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		o.baseUrl+"/api/embed",
+		bytes.NewBuffer(payloadJson),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resByte, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error from response, code %d, body %s", res.StatusCode, string(resByte))
+	}
+
+	var resEmbedding OllamaEmbeddingResponse
+	err = json.Unmarshal(resByte, &resEmbedding)
+	if err != nil {
+		return nil, err
+	}
+	if len(resEmbedding.Embeddings) == 0 {
+		return nil, fmt.Errorf("empty embedding response")
+	}
+
+	return resEmbedding.Embeddings[0], nil
+}
+
+func NewOllamaEmbedder(baseUrl string, model string, timeout time.Duration) Embedder {
+	if baseUrl == "" {
+		baseUrl = defaultOllamaBaseUrl
+	}
+
+	return &ollamaEmbedder{
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		model:   model,
+		client:  newHTTPClient(timeout),
+	}
+}
@@ -0,0 +1,64 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOllamaEmbedderEmbed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/embed" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var payload OllamaEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		if payload.Model != "nomic-embed-text" || payload.Input != "hello" {
+			t.Errorf("unexpected request %+v", payload)
+		}
+
+		w.Write([]byte(`{"embeddings":[[1,2],[3,4]]}`))
+	}))
+	defer server.Close()
+
+	values, err := NewOllamaEmbedder(server.URL+"/", "nomic-embed-text", 0).Embed(context.Background(), "hello", TaskTypeRetrievalDocument)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(values) != 2 || values[0] != 1 || values[1] != 2 {
+		t.Fatalf("got %v, want the first embedding", values)
+	}
+}
+
+func TestOllamaEmbedderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "error status", status: http.StatusNotFound, body: `{"error":"model not found"}`, wantErr: "code 404"},
+		{name: "no embeddings", status: http.StatusOK, body: `{"embeddings":[]}`, wantErr: "empty embedding response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := NewOllamaEmbedder(server.URL, "model", 0).Embed(context.Background(), "hello", TaskTypeRetrievalDocument)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
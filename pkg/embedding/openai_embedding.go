@@ -0,0 +1,106 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultOpenAIBaseUrl = "https://api.openai.com/v1"
+
+type OpenAIEmbeddingRequest struct {
+	Model      string `json:"model"`
+	Input      string `json:"input"`
+	Dimensions int    `json:"dimensions,omitempty"`
+}
+
+type OpenAIEmbeddingResponseData struct {
+	Embedding []float32 `json:"embedding"`
+}
+
+type OpenAIEmbeddingResponse struct {
+	Data []OpenAIEmbeddingResponseData `json:"data"`
+}
+
+type openAIEmbedder struct {
+	baseUrl   string
+	apiKey    string
+	model     string
+	dimension int
+	client    *http.Client
+}
+
+func (o *openAIEmbedder) Embed(ctx context.Context, text string, taskType string) ([]float32, error) {
+	payload := OpenAIEmbeddingRequest{
+		Model:      o.model,
+		Input:      text,
+		Dimensions: o.dimension,
+	}
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		o.baseUrl+"/embeddings",
+		bytes.NewBuffer(payloadJson),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resByte, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error from response, code %d, body %s", res.StatusCode, string(resByte))
+	}
+
+	var resEmbedding OpenAIEmbeddingResponse
+	err = json.Unmarshal(resByte, &resEmbedding)
+	if err != nil {
+		return nil, err
+	}
+	if len(resEmbedding.Data) == 0 {
+		return nil, fmt.Errorf("empty embedding response")
+	}
+
+	return resEmbedding.Data[0].Embedding, nil
+}
+
+// NewOpenAIEmbedder works with any server implementing the OpenAI
+// /embeddings endpoint. The task type is ignored since the API has no
+// equivalent of it.
+func NewOpenAIEmbedder(baseUrl string, apiKey string, model string, dimension int, timeout time.Duration) Embedder {
+	if baseUrl == "" {
+		baseUrl = defaultOpenAIBaseUrl
+	}
+
+	return &openAIEmbedder{
+		baseUrl:   strings.TrimSuffix(baseUrl, "/"),
+		apiKey:    apiKey,
+		model:     model,
+		dimension: dimension,
+		client:    newHTTPClient(timeout),
+	}
+}
@@ -0,0 +1,93 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIEmbedderEmbed(t *testing.T) {
+	tests := []struct {
+		name           string
+		apiKey         string
+		dimension      int
+		wantAuth       string
+		wantDimensions bool
+	}{
+		{name: "with api key and dimension", apiKey: "secret", dimension: 256, wantAuth: "Bearer secret", wantDimensions: true},
+		{name: "without api key and dimension", wantAuth: "", wantDimensions: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/v1/embeddings" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != tt.wantAuth {
+					t.Errorf("got authorization %q, want %q", got, tt.wantAuth)
+				}
+
+				body, _ := io.ReadAll(r.Body)
+				var payload map[string]any
+				if err := json.Unmarshal(body, &payload); err != nil {
+					t.Errorf("invalid request body %s: %v", body, err)
+				}
+				if payload["model"] != "text-embedding-3-small" || payload["input"] != "hello" {
+					t.Errorf("unexpected request body %s", body)
+				}
+				dimensions, ok := payload["dimensions"]
+				if ok != tt.wantDimensions {
+					t.Errorf("dimensions sent %v, want %v", ok, tt.wantDimensions)
+				}
+				if ok && dimensions != float64(tt.dimension) {
+					t.Errorf("got dimensions %v, want %d", dimensions, tt.dimension)
+				}
+
+				w.Write([]byte(`{"data":[{"embedding":[0.1,0.2,0.3]}]}`))
+			}))
+			defer server.Close()
+
+			embedder := NewOpenAIEmbedder(server.URL+"/v1/", tt.apiKey, "text-embedding-3-small", tt.dimension, 0)
+			values, err := embedder.Embed(context.Background(), "hello", TaskTypeRetrievalQuery)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(values) != 3 || values[0] != 0.1 || values[2] != 0.3 {
+				t.Fatalf("got %v", values)
+			}
+		})
+	}
+}
+
+func TestOpenAIEmbedderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "error status", status: http.StatusUnauthorized, body: `{"error":"invalid key"}`, wantErr: "code 401"},
+		{name: "empty data", status: http.StatusOK, body: `{"data":[]}`, wantErr: "empty embedding response"},
+		{name: "invalid json", status: http.StatusOK, body: `not json`, wantErr: "invalid character"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := NewOpenAIEmbedder(server.URL, "", "model", 0, 0).Embed(context.Background(), "hello", TaskTypeRetrievalQuery)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package jwt
+
+import (
+	"errors"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token is expired")
+)
+
+type Claims struct {
+	Subject   string
+	SessionId string
+	IssuedAt  int64
+	ExpiresAt int64
+}
+
+type tokenClaims struct {
+	SessionId string `json:"sid,omitempty"`
+	gojwt.RegisteredClaims
+}
+
+// Sign encodes claims as a compact JWT signed with HMAC SHA-256.
+func Sign(claims *Claims, secret []byte) (string, error) {
+	token := gojwt.NewWithClaims(gojwt.SigningMethodHS256, tokenClaims{
+		SessionId: claims.SessionId,
+		RegisteredClaims: gojwt.RegisteredClaims{
+			Subject:   claims.Subject,
+			IssuedAt:  gojwt.NewNumericDate(time.Unix(claims.IssuedAt, 0)),
+			ExpiresAt: gojwt.NewNumericDate(time.Unix(claims.ExpiresAt, 0)),
+		},
+	})
+
+	return token.SignedString(secret)
+}
+
+// Parse verifies the signature and expiry of token and returns its claims.
+// Only HS256 is accepted, whatever algorithm the token header names.
+func Parse(token string, secret []byte) (*Claims, error) {
+	var parsed tokenClaims
+	_, err := gojwt.ParseWithClaims(
+		token,
+		&parsed,
+		func(*gojwt.Token) (any, error) {
+			return secret, nil
+		},
+		gojwt.WithValidMethods([]string{gojwt.SigningMethodHS256.Alg()}),
+		gojwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		if errors.Is(err, gojwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	claims := Claims{
+		Subject:   parsed.Subject,
+		SessionId: parsed.SessionId,
+		ExpiresAt: parsed.ExpiresAt.Unix(),
+	}
+	if parsed.IssuedAt != nil {
+		claims.IssuedAt = parsed.IssuedAt.Unix()
+	}
+
+	return &claims, nil
+}
@@ -0,0 +1,99 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+var testSecret = []byte("test-secret")
+
+func TestSignAndParse(t *testing.T) {
+	now := time.Now()
+	token, err := Sign(&Claims{
+		Subject:   "user",
+		SessionId: "session",
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Minute).Unix(),
+	}, testSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	claims, err := Parse(token, testSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.Subject != "user" || claims.SessionId != "session" || claims.IssuedAt != now.Unix() || claims.ExpiresAt != now.Add(time.Minute).Unix() {
+		t.Fatalf("got %+v", claims)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	now := time.Now()
+	registered := gojwt.RegisteredClaims{
+		Subject:   "user",
+		ExpiresAt: gojwt.NewNumericDate(now.Add(time.Minute)),
+	}
+
+	sign := func(method gojwt.SigningMethod, key any, claims gojwt.Claims) string {
+		token, err := gojwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("signing test token: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:    "expired",
+			token:   sign(gojwt.SigningMethodHS256, testSecret, tokenClaims{RegisteredClaims: gojwt.RegisteredClaims{Subject: "user", ExpiresAt: gojwt.NewNumericDate(now.Add(-time.Minute))}}),
+			wantErr: ErrExpiredToken,
+		},
+		{
+			name:    "without expiry",
+			token:   sign(gojwt.SigningMethodHS256, testSecret, tokenClaims{RegisteredClaims: gojwt.RegisteredClaims{Subject: "user"}}),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "other secret",
+			token:   sign(gojwt.SigningMethodHS256, []byte("other"), tokenClaims{RegisteredClaims: registered}),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "other hmac algorithm",
+			token:   sign(gojwt.SigningMethodHS512, testSecret, tokenClaims{RegisteredClaims: registered}),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "alg none",
+			token:   sign(gojwt.SigningMethodNone, gojwt.UnsafeAllowNoneSignatureType, tokenClaims{RegisteredClaims: registered}),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "malformed",
+			token:   "not.a.token",
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "empty",
+			token:   "",
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := Parse(tt.token, testSecret)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got claims %+v and error %v, want %v", claims, err, tt.wantErr)
+			}
+		})
+	}
+}
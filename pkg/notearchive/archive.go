@@ -0,0 +1,86 @@
+package notearchive
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ManifestFileName = "manifest.json"
+	ManifestVersion  = 1
+	NoteExtension    = ".md"
+)
+
+type Notebook struct {
+	Id       uuid.UUID
+	ParentId *uuid.UUID
+	Name     string
+}
+
+type Note struct {
+	Id         uuid.UUID
+	NotebookId uuid.UUID
+	Title      string
+	Content    string
+	CreatedAt  time.Time
+	UpdatedAt  *time.Time
+}
+
+type Manifest struct {
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Notebooks  []ManifestNotebook `json:"notebooks"`
+	Notes      []ManifestNote     `json:"notes"`
+}
+
+type ManifestNotebook struct {
+	Id       uuid.UUID  `json:"id"`
+	ParentId *uuid.UUID `json:"parent_id"`
+	Name     string     `json:"name"`
+	Path     string     `json:"path"`
+}
+
+type ManifestNote struct {
+	Id         uuid.UUID  `json:"id"`
+	NotebookId uuid.UUID  `json:"notebook_id"`
+	Title      string     `json:"title"`
+	Path       string     `json:"path"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+}
+
+// SanitizeName turns a notebook name or note title into a single path
+// segment that is valid on common file systems.
+func SanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if name == "" {
+		return "Untitled"
+	}
+	if len(name) > 120 {
+		name = strings.TrimSpace(string([]rune(name)[:100]))
+	}
+
+	return name
+}
+
+// uniquePath joins dir and name+ext, adding " (2)", " (3)"... to name when
+// the result has already been used.
+func uniquePath(used map[string]bool, dir string, name string, ext string) string {
+	candidate := path.Join(dir, name+ext)
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		candidate = path.Join(dir, fmt.Sprintf("%s (%d)%s", name, i, ext))
+	}
+	used[strings.ToLower(candidate)] = true
+
+	return candidate
+}
@@ -0,0 +1,155 @@
+package notearchive
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FrontMatter holds the keys of a note's YAML front matter this package
+// understands. Every other key is kept verbatim in Extra.
+type FrontMatter struct {
+	Title     string
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+	Extra     []string
+}
+
+var (
+	frontMatterKeyRegex = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.*)$`)
+	// notionIdRegex matches the id Notion appends to exported page names.
+	notionIdRegex = regexp.MustCompile(`\s+[0-9a-f]{32}$`)
+
+	frontMatterTimeLayouts = []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+)
+
+// ParseNote splits a Markdown document into its front matter and body. Only
+// simple "key: value" front matter is interpreted, which covers notes written
+// by FormatNote, Obsidian and most static site generators.
+func ParseNote(text string) (FrontMatter, string) {
+	var frontMatter FrontMatter
+
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return frontMatter, text
+	}
+
+	lines := strings.Split(text, "\n")
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if lines[i] == "---" || lines[i] == "..." {
+			end = i
+			break
+		}
+	}
+	if end == -1 {
+		return frontMatter, text
+	}
+
+	keepLine := false
+	for _, line := range lines[1:end] {
+		match := frontMatterKeyRegex.FindStringSubmatch(line)
+		if match == nil {
+			// Continuation of the previous key, e.g. a list item.
+			if keepLine {
+				frontMatter.Extra = append(frontMatter.Extra, line)
+			}
+			continue
+		}
+
+		keepLine = false
+		value := unquoteFrontMatterValue(match[2])
+		switch strings.ToLower(match[1]) {
+		case "id":
+		case "title":
+			frontMatter.Title = value
+		case "created_at", "created":
+			frontMatter.CreatedAt = parseFrontMatterTime(value)
+		case "updated_at", "updated", "modified":
+			frontMatter.UpdatedAt = parseFrontMatterTime(value)
+		default:
+			keepLine = true
+			frontMatter.Extra = append(frontMatter.Extra, line)
+		}
+	}
+
+	body := strings.TrimLeft(strings.Join(lines[end+1:], "\n"), "\n")
+	return frontMatter, body
+}
+
+// FormatExtra renders the front matter keys ParseNote did not interpret back
+// in front of body, so metadata such as tags or aliases survives an import.
+func FormatExtra(frontMatter FrontMatter, body string) string {
+	if len(frontMatter.Extra) == 0 {
+		return body
+	}
+
+	return "---\n" + strings.Join(frontMatter.Extra, "\n") + "\n---\n\n" + body
+}
+
+// NameFromPath returns the file or folder name of an archive path without
+// the Markdown extension and without the id Notion appends to page names.
+func NameFromPath(p string) string {
+	name := path.Base(p)
+	for _, ext := range []string{NoteExtension, ".markdown"} {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			name = name[:len(name)-len(ext)]
+			break
+		}
+	}
+
+	return strings.TrimSpace(notionIdRegex.ReplaceAllString(name, ""))
+}
+
+// IsNotePath reports whether the archive path holds a Markdown note.
+func IsNotePath(p string) bool {
+	lower := strings.ToLower(p)
+	return strings.HasSuffix(lower, NoteExtension) || strings.HasSuffix(lower, ".markdown")
+}
+
+// IsHiddenPath reports whether the path is inside a folder, or is a file,
+// that tools keep for themselves, such as .obsidian or __MACOSX.
+func IsHiddenPath(p string) bool {
+	for _, segment := range strings.Split(p, "/") {
+		if strings.HasPrefix(segment, ".") || segment == "__MACOSX" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func unquoteFrontMatterValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		var unquoted string
+		if json.Unmarshal([]byte(value), &unquoted) == nil {
+			return unquoted
+		}
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+
+	return value
+}
+
+func parseFrontMatterTime(value string) *time.Time {
+	for _, layout := range frontMatterTimeLayouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return &t
+		}
+	}
+
+	return nil
+}
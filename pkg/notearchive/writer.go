@@ -0,0 +1,147 @@
+package notearchive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Writer streams notebooks and notes into a ZIP archive, with a folder per
+// notebook and a Markdown file per note. Only the manifest is kept in memory.
+type Writer struct {
+	zipWriter *zip.Writer
+	manifest  Manifest
+	dirs      map[uuid.UUID]string
+	usedPaths map[string]bool
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		zipWriter: zip.NewWriter(w),
+		manifest: Manifest{
+			Version:    ManifestVersion,
+			ExportedAt: time.Now(),
+			Notebooks:  make([]ManifestNotebook, 0),
+			Notes:      make([]ManifestNote, 0),
+		},
+		dirs:      make(map[uuid.UUID]string),
+		usedPaths: map[string]bool{ManifestFileName: true},
+	}
+}
+
+// AddNotebook creates the notebook's folder inside its parent's folder, or
+// at the archive root when the parent has not been added.
+func (w *Writer) AddNotebook(notebook Notebook) error {
+	parentDir := ""
+	if notebook.ParentId != nil {
+		parentDir = w.dirs[*notebook.ParentId]
+	}
+
+	dir := uniquePath(w.usedPaths, parentDir, SanitizeName(notebook.Name), "")
+	_, err := w.zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     dir + "/",
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	w.dirs[notebook.Id] = dir
+	w.manifest.Notebooks = append(w.manifest.Notebooks, ManifestNotebook{
+		Id:       notebook.Id,
+		ParentId: notebook.ParentId,
+		Name:     notebook.Name,
+		Path:     dir,
+	})
+
+	return nil
+}
+
+// AddNote writes the note into its notebook's folder, which must have been
+// added before.
+func (w *Writer) AddNote(note Note) error {
+	dir, ok := w.dirs[note.NotebookId]
+	if !ok {
+		return fmt.Errorf("notebook %s of note %s has not been added", note.NotebookId, note.Id)
+	}
+
+	modified := note.CreatedAt
+	if note.UpdatedAt != nil {
+		modified = *note.UpdatedAt
+	}
+
+	notePath := uniquePath(w.usedPaths, dir, SanitizeName(note.Title), NoteExtension)
+	file, err := w.zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     notePath,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(file, FormatNote(note))
+	if err != nil {
+		return err
+	}
+
+	w.manifest.Notes = append(w.manifest.Notes, ManifestNote{
+		Id:         note.Id,
+		NotebookId: note.NotebookId,
+		Title:      note.Title,
+		Path:       notePath,
+		CreatedAt:  note.CreatedAt,
+		UpdatedAt:  note.UpdatedAt,
+	})
+
+	return nil
+}
+
+// Close writes the manifest and finishes the archive. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	file, err := w.zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     ManifestFileName,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(w.manifest)
+	if err != nil {
+		return err
+	}
+
+	return w.zipWriter.Close()
+}
+
+// FormatNote renders the note as Markdown with a YAML front matter holding
+// its id, title and timestamps.
+func FormatNote(note Note) string {
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	fmt.Fprintf(&sb, "id: %s\n", note.Id)
+	// A JSON string is a valid YAML double quoted scalar.
+	title, _ := json.Marshal(note.Title)
+	fmt.Fprintf(&sb, "title: %s\n", title)
+	fmt.Fprintf(&sb, "created_at: %s\n", note.CreatedAt.Format(time.RFC3339))
+	if note.UpdatedAt != nil {
+		fmt.Fprintf(&sb, "updated_at: %s\n", note.UpdatedAt.Format(time.RFC3339))
+	}
+	sb.WriteString("---\n\n")
+	sb.WriteString(note.Content)
+	if !strings.HasSuffix(note.Content, "\n") {
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,121 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCohereBaseUrl = "https://api.cohere.com/v1"
+	defaultCohereModel   = "rerank-v3.5"
+)
+
+type CohereRerankRequest struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+}
+
+type CohereRerankResponseResult struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+}
+
+type CohereRerankResponse struct {
+	Results []CohereRerankResponseResult `json:"results"`
+}
+
+type cohereReranker struct {
+	baseUrl string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func (c *cohereReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	scores := make([]float64, len(documents))
+	if len(documents) == 0 {
+		return scores, nil
+	}
+
+	payload := CohereRerankRequest{
+		Model:     c.model,
+		Query:     query,
+		Documents: documents,
+	}
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		c.baseUrl+"/rerank",
+		bytes.NewBuffer(payloadJson),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resByte, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error from response, code %d, body %s", res.StatusCode, string(resByte))
+	}
+
+	var resRerank CohereRerankResponse
+	err = json.Unmarshal(resByte, &resRerank)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range resRerank.Results {
+		if result.Index < 0 || result.Index >= len(scores) {
+			return nil, fmt.Errorf("rerank result index %d out of range", result.Index)
+		}
+		scores[result.Index] = result.RelevanceScore
+	}
+
+	return scores, nil
+}
+
+// NewCohereReranker works with the Cohere /rerank endpoint and the servers
+// copying it, such as Jina AI and Hugging Face text-embeddings-inference.
+func NewCohereReranker(baseUrl string, apiKey string, model string, timeout time.Duration) Reranker {
+	if baseUrl == "" {
+		baseUrl = defaultCohereBaseUrl
+	}
+	if model == "" {
+		model = defaultCohereModel
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return &cohereReranker{
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
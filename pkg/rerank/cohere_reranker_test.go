@@ -0,0 +1,81 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCohereRerank(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rerank" {
+			t.Errorf("got path %s, want /rerank", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("got authorization %q", got)
+		}
+		var req CohereRerankRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if req.Query != "question" || len(req.Documents) != 3 {
+			t.Errorf("got request %+v", req)
+		}
+		fmt.Fprint(w, `{"results":[{"index":2,"relevance_score":0.9},{"index":0,"relevance_score":0.4}]}`)
+	}))
+	defer server.Close()
+
+	scores, err := NewCohereReranker(server.URL+"/", "key", "", 0).Rerank(context.Background(), "question", []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(scores) != fmt.Sprint([]float64{0.4, 0, 0.9}) {
+		t.Fatalf("got scores %v", scores)
+	}
+}
+
+func TestCohereRerankErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "index past the end", status: http.StatusOK, body: `{"results":[{"index":2,"relevance_score":0.9}]}`, wantErr: "index 2 out of range"},
+		{name: "negative index", status: http.StatusOK, body: `{"results":[{"index":-1,"relevance_score":0.9}]}`, wantErr: "index -1 out of range"},
+		{name: "error status", status: http.StatusTooManyRequests, body: `{"message":"slow down"}`, wantErr: "code 429"},
+		{name: "not json", status: http.StatusOK, body: `results`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			_, err := NewCohereReranker(server.URL, "", "", 0).Rerank(context.Background(), "question", []string{"a", "b"})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCohereRerankNoDocuments(t *testing.T) {
+	scores, err := NewCohereReranker("http://127.0.0.1:0", "", "", 0).Rerank(context.Background(), "question", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(scores) != 0 {
+		t.Fatalf("got scores %v", scores)
+	}
+}
@@ -0,0 +1,101 @@
+package rerank
+
+import (
+	"ai-notetaking-be/pkg/chatbot"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// llmGradeMax is the top of the scale the chat model grades documents on.
+const llmGradeMax = 10
+
+type llmRerankResult struct {
+	Grades []llmRerankGrade `json:"grades"`
+}
+
+type llmRerankGrade struct {
+	Document int     `json:"document"`
+	Grade    float64 `json:"grade"`
+}
+
+type llmReranker struct {
+	chatModel chatbot.ChatModel
+}
+
+// Rerank grades every document in a single structured call. Documents the
+// model leaves out get a score of zero.
+func (l *llmReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	scores := make([]float64, len(documents))
+	if len(documents) == 0 {
+		return scores, nil
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Grade from 0 to %d how useful each document is to answer the question, where 0 is unrelated and %d answers it fully.\n\n", llmGradeMax, llmGradeMax))
+	b.WriteString("Question: ")
+	b.WriteString(query)
+	b.WriteString("\n\n")
+	for i, document := range documents {
+		b.WriteString(fmt.Sprintf("Document %d\n%s\n\n", i+1, strings.TrimSpace(document)))
+	}
+
+	schema := chatbot.Schema{
+		Type: chatbot.SchemaTypeObject,
+		Properties: map[string]*chatbot.Schema{
+			"grades": {
+				Type: chatbot.SchemaTypeArray,
+				Items: &chatbot.Schema{
+					Type: chatbot.SchemaTypeObject,
+					Properties: map[string]*chatbot.Schema{
+						"document": {
+							Type: chatbot.SchemaTypeInteger,
+						},
+						"grade": {
+							Type: chatbot.SchemaTypeNumber,
+						},
+					},
+					Required: []string{
+						"document",
+						"grade",
+					},
+				},
+			},
+		},
+		Required: []string{
+			"grades",
+		},
+	}
+
+	var result llmRerankResult
+	err := l.chatModel.GenerateStructured(
+		ctx,
+		[]*chatbot.ChatHistory{
+			{
+				Chat: b.String(),
+				Role: "user",
+			},
+		},
+		&schema,
+		&result,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, grade := range result.Grades {
+		i := grade.Document - 1
+		if i < 0 || i >= len(scores) {
+			continue
+		}
+		scores[i] = min(max(grade.Grade, 0), llmGradeMax) / llmGradeMax
+	}
+
+	return scores, nil
+}
+
+func NewLLMReranker(chatModel chatbot.ChatModel) Reranker {
+	return &llmReranker{
+		chatModel: chatModel,
+	}
+}
@@ -0,0 +1,68 @@
+package rerank
+
+import (
+	"ai-notetaking-be/pkg/chatbot"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLLMRerank(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  []float64
+	}{
+		{
+			name:  "grades are scaled",
+			reply: `{"grades":[{"document":1,"grade":5},{"document":3,"grade":10}]}`,
+			want:  []float64{0.5, 0, 1},
+		},
+		{
+			name:  "grades are clamped",
+			reply: `{"grades":[{"document":1,"grade":-3},{"document":2,"grade":42}]}`,
+			want:  []float64{0, 1, 0},
+		},
+		{
+			name:  "out of range documents are ignored",
+			reply: `{"grades":[{"document":0,"grade":9},{"document":4,"grade":9},{"document":-2,"grade":9},{"document":2,"grade":2}]}`,
+			want:  []float64{0, 0.2, 0},
+		},
+		{
+			name:  "no grades",
+			reply: `{"grades":[]}`,
+			want:  []float64{0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatModel := chatbot.NewScriptedChatModel(tt.reply)
+			scores, err := NewLLMReranker(chatModel).Rerank(context.Background(), "question", []string{"a", " b ", "c"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fmt.Sprint(scores) != fmt.Sprint(tt.want) {
+				t.Fatalf("got scores %v, want %v", scores, tt.want)
+			}
+			prompt := chatModel.Calls[0][0].Chat
+			if !strings.Contains(prompt, "Question: question") || !strings.Contains(prompt, "Document 3\nc") {
+				t.Fatalf("got prompt %q", prompt)
+			}
+		})
+	}
+}
+
+func TestLLMRerankErrors(t *testing.T) {
+	_, err := NewLLMReranker(chatbot.NewScriptedChatModel("not json")).Rerank(context.Background(), "question", []string{"a"})
+	if err == nil {
+		t.Fatal("expected an error for a reply that is not json")
+	}
+
+	chatModel := chatbot.NewScriptedChatModel()
+	scores, err := NewLLMReranker(chatModel).Rerank(context.Background(), "question", nil)
+	if err != nil || len(scores) != 0 || len(chatModel.Calls) != 0 {
+		t.Fatalf("got scores %v, error %v and %d calls for no documents", scores, err, len(chatModel.Calls))
+	}
+}
@@ -0,0 +1,79 @@
+package rerank
+
+import "math"
+
+// MMR picks up to k items by maximal marginal relevance: each pick is the
+// item with the best lambda * relevance - (1 - lambda) * similarity to the
+// items already picked, so near duplicates of a pick lose to other relevant
+// items. A lambda of 1 ranks by relevance only. It returns the indexes of the
+// picked items in pick order. A NaN relevance ranks below every other
+// item, and an item without a vector is not similar to any pick.
+func MMR(relevance []float64, vectors [][]float32, lambda float64, k int) []int {
+	picked := make([]int, 0, max(min(k, len(relevance)), 0))
+	// maxSimilarity holds the highest similarity of every item to the picked
+	// items.
+	maxSimilarity := make([]float64, len(relevance))
+	used := make([]bool, len(relevance))
+
+	for len(picked) < k && len(picked) < len(relevance) {
+		best := -1
+		bestScore := math.Inf(-1)
+		for i := range relevance {
+			if used[i] {
+				continue
+			}
+
+			score := lambda * relevance[i]
+			if len(picked) > 0 {
+				score -= (1 - lambda) * maxSimilarity[i]
+			}
+			if math.IsNaN(score) {
+				score = math.Inf(-1)
+			}
+			// The first unused item is the pick when every score is -Inf.
+			if best < 0 || score > bestScore {
+				best = i
+				bestScore = score
+			}
+		}
+
+		picked = append(picked, best)
+		used[best] = true
+		for i := range relevance {
+			if used[i] {
+				continue
+			}
+			if similarity := CosineSimilarity(vectorAt(vectors, i), vectorAt(vectors, best)); similarity > maxSimilarity[i] {
+				maxSimilarity[i] = similarity
+			}
+		}
+	}
+
+	return picked
+}
+
+func vectorAt(vectors [][]float32, i int) []float32 {
+	if i >= len(vectors) {
+		return nil
+	}
+
+	return vectors[i]
+}
+
+func CosineSimilarity(a []float32, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,144 @@
+package rerank
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestMMR(t *testing.T) {
+	nan := math.NaN()
+	inf := math.Inf(-1)
+	tests := []struct {
+		name      string
+		relevance []float64
+		vectors   [][]float32
+		lambda    float64
+		k         int
+		want      []int
+	}{
+		{
+			name:      "lambda 1 ranks by relevance",
+			relevance: []float64{0.2, 0.9, 0.5},
+			vectors:   [][]float32{{1, 0}, {1, 0}, {1, 0}},
+			lambda:    1,
+			k:         3,
+			want:      []int{1, 2, 0},
+		},
+		{
+			name:      "lambda 0 ranks by dissimilarity after the first pick",
+			relevance: []float64{0.9, 0.8, 0.1},
+			vectors:   [][]float32{{1, 0}, {1, 0.1}, {0, 1}},
+			lambda:    0,
+			k:         3,
+			want:      []int{0, 2, 1},
+		},
+		{
+			name:      "duplicate vectors lose to other relevant items",
+			relevance: []float64{0.9, 0.85, 0.6},
+			vectors:   [][]float32{{1, 0}, {1, 0}, {0, 1}},
+			lambda:    0.5,
+			k:         2,
+			want:      []int{0, 2},
+		},
+		{
+			name:      "k larger than the items",
+			relevance: []float64{0.1, 0.3},
+			vectors:   [][]float32{{1, 0}, {0, 1}},
+			lambda:    1,
+			k:         5,
+			want:      []int{1, 0},
+		},
+		{
+			name:      "zero k",
+			relevance: []float64{0.1, 0.3},
+			vectors:   [][]float32{{1, 0}, {0, 1}},
+			lambda:    1,
+			k:         0,
+			want:      []int{},
+		},
+		{
+			name:      "negative k",
+			relevance: []float64{0.1},
+			vectors:   [][]float32{{1, 0}},
+			lambda:    1,
+			k:         -1,
+			want:      []int{},
+		},
+		{
+			name:      "no items",
+			relevance: []float64{},
+			vectors:   [][]float32{},
+			lambda:    0.5,
+			k:         3,
+			want:      []int{},
+		},
+		{
+			name:      "NaN relevance ranks last",
+			relevance: []float64{nan, 0.2, nan, 0.4},
+			vectors:   [][]float32{{1, 0}, {0, 1}, {1, 1}, {1, -1}},
+			lambda:    1,
+			k:         4,
+			want:      []int{3, 1, 0, 2},
+		},
+		{
+			name:      "every relevance NaN",
+			relevance: []float64{nan, nan},
+			vectors:   [][]float32{{1, 0}, {0, 1}},
+			lambda:    0.7,
+			k:         2,
+			want:      []int{0, 1},
+		},
+		{
+			name:      "every relevance -Inf",
+			relevance: []float64{inf, inf, inf},
+			vectors:   [][]float32{{1, 0}, {0, 1}, {1, 1}},
+			lambda:    0.7,
+			k:         3,
+			want:      []int{0, 1, 2},
+		},
+		{
+			name:      "missing vectors",
+			relevance: []float64{0.5, 0.9},
+			vectors:   [][]float32{{1, 0}},
+			lambda:    0.5,
+			k:         2,
+			want:      []int{1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MMR(tt.relevance, tt.vectors, tt.lambda, tt.k)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []float32
+		b    []float32
+		want float64
+	}{
+		{name: "same direction", a: []float32{1, 2}, b: []float32{2, 4}, want: 1},
+		{name: "opposite", a: []float32{1, 0}, b: []float32{-3, 0}, want: -1},
+		{name: "orthogonal", a: []float32{1, 0}, b: []float32{0, 5}, want: 0},
+		{name: "zero vector", a: []float32{0, 0}, b: []float32{1, 1}, want: 0},
+		{name: "both zero", a: []float32{0, 0}, b: []float32{0, 0}, want: 0},
+		{name: "mismatched lengths", a: []float32{1, 0, 0}, b: []float32{1, 0}, want: 0},
+		{name: "empty", a: []float32{}, b: nil, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CosineSimilarity(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package rerank
+
+import (
+	"ai-notetaking-be/pkg/chatbot"
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	ProviderNone   = "none"
+	ProviderCohere = "cohere"
+	ProviderLLM    = "llm"
+
+	defaultTimeout = time.Minute
+)
+
+// Reranker scores how relevant each document is to query. Scores are
+// returned in the order of documents, higher is more relevant.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, documents []string) ([]float64, error)
+}
+
+type Config struct {
+	Provider string
+	Model    string
+	BaseUrl  string
+	ApiKey   string
+	// Timeout bounds each call to the provider, 0 uses a minute. The llm
+	// provider uses the timeout of the chat model.
+	Timeout time.Duration
+}
+
+// NewReranker returns nil for the none provider, re-ranking is optional.
+// The llm provider asks chatModel to grade the documents.
+func NewReranker(config Config, chatModel chatbot.ChatModel) (Reranker, error) {
+	switch config.Provider {
+	case "", ProviderNone:
+		return nil, nil
+	case ProviderCohere:
+		return NewCohereReranker(config.BaseUrl, config.ApiKey, config.Model, config.Timeout), nil
+	case ProviderLLM:
+		return NewLLMReranker(chatModel), nil
+	default:
+		return nil, fmt.Errorf("unknown rerank provider %q", config.Provider)
+	}
+}
@@ -0,0 +1,263 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type editKind int
+
+const (
+	editEqual editKind = iota
+	editDelete
+	editInsert
+)
+
+type edit struct {
+	kind editKind
+	line string
+}
+
+// Unified returns a line-level unified diff between from and to with
+// contextLines of unchanged lines around each hunk. It returns an empty
+// string when both texts are equal.
+func Unified(fromLabel string, toLabel string, from string, to string, contextLines int) string {
+	edits := lineEdits(splitLines(from), splitLines(to))
+
+	changed := false
+	for _, e := range edits {
+		if e.kind != editEqual {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromLabel, toLabel)
+
+	// fromLine and toLine hold the number of lines of each side consumed
+	// before every edit, which is what hunk headers are built from.
+	fromLine := make([]int, len(edits)+1)
+	toLine := make([]int, len(edits)+1)
+	for i, e := range edits {
+		fromLine[i+1] = fromLine[i]
+		toLine[i+1] = toLine[i]
+		if e.kind != editInsert {
+			fromLine[i+1]++
+		}
+		if e.kind != editDelete {
+			toLine[i+1]++
+		}
+	}
+
+	i := 0
+	for i < len(edits) {
+		if edits[i].kind == editEqual {
+			i++
+			continue
+		}
+
+		start := max(0, i-contextLines)
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].kind == editEqual {
+				continue
+			}
+			if j-end > 2*contextLines {
+				break
+			}
+			end = j + 1
+		}
+		end = min(len(edits), end+contextLines)
+
+		fromCount := fromLine[end] - fromLine[start]
+		toCount := toLine[end] - toLine[start]
+		fmt.Fprintf(
+			&sb,
+			"@@ -%d,%d +%d,%d @@\n",
+			hunkStart(fromLine[start], fromCount),
+			fromCount,
+			hunkStart(toLine[start], toCount),
+			toCount,
+		)
+		for _, e := range edits[start:end] {
+			switch e.kind {
+			case editEqual:
+				sb.WriteString(" ")
+			case editDelete:
+				sb.WriteString("-")
+			case editInsert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(e.line)
+			sb.WriteString("\n")
+		}
+
+		i = end
+	}
+
+	return sb.String()
+}
+
+func hunkStart(consumed int, count int) int {
+	if count == 0 {
+		return consumed
+	}
+	return consumed + 1
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// lineEdits computes a shortest edit script with the linear space variant of
+// Myers' algorithm: the texts are split where the forward and backward
+// searches for the edit path meet and both halves are diffed recursively, so
+// memory stays proportional to the length of the texts.
+func lineEdits(a []string, b []string) []edit {
+	edits := make([]edit, 0, len(a)+len(b))
+	return appendLineEdits(edits, a, b)
+}
+
+func appendLineEdits(edits []edit, a []string, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{kind: editEqual, line: line})
+	}
+
+	middleA := a[prefix : len(a)-suffix]
+	middleB := b[prefix : len(b)-suffix]
+	switch {
+	case len(middleA) == 0:
+		for _, line := range middleB {
+			edits = append(edits, edit{kind: editInsert, line: line})
+		}
+	case len(middleB) == 0:
+		for _, line := range middleA {
+			edits = append(edits, edit{kind: editDelete, line: line})
+		}
+	default:
+		x, y, ok := middleSnake(middleA, middleB)
+		if ok {
+			edits = appendLineEdits(edits, middleA[:x], middleB[:y])
+			edits = appendLineEdits(edits, middleA[x:], middleB[y:])
+		} else {
+			// Nothing in common.
+			for _, line := range middleA {
+				edits = append(edits, edit{kind: editDelete, line: line})
+			}
+			for _, line := range middleB {
+				edits = append(edits, edit{kind: editInsert, line: line})
+			}
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{kind: editEqual, line: line})
+	}
+
+	return edits
+}
+
+// middleSnake runs the forward and backward searches of Myers' algorithm
+// until they overlap and returns the point where the edit path crosses, ok is
+// false when a and b have no line in common.
+func middleSnake(a []string, b []string) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	// forward[offset+k] is the furthest x reached from the start on diagonal
+	// k = x - y, backward[offset+k] the same from the end, -1 when unreached.
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+
+	delta := n - m
+	// With an odd delta the paths can only meet during a forward step,
+	// otherwise during a backward step.
+	checkForward := delta%2 != 0
+	forwardStart, forwardEnd := 0, 0
+	backwardStart, backwardEnd := 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case checkForward:
+				backwardK := offset + delta - k
+				if backwardK >= 0 && backwardK < len(backward) && backward[backwardK] != -1 {
+					if x >= n-backward[backwardK] {
+						return x, y, true
+					}
+				}
+			}
+		}
+
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			switch {
+			case x > n:
+				backwardEnd += 2
+			case y > m:
+				backwardStart += 2
+			case !checkForward:
+				forwardK := offset + delta - k
+				if forwardK >= 0 && forwardK < len(forward) && forward[forwardK] != -1 {
+					forwardX := forward[forwardK]
+					if forwardX >= n-x {
+						return forwardX, forwardX - (forwardK - offset), true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
@@ -0,0 +1,148 @@
+package textdiff
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "equal",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "both empty",
+			from: "",
+			to:   "",
+			want: "",
+		},
+		{
+			name: "from empty",
+			from: "",
+			to:   "a\nb\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "to empty",
+			from: "a\nb\n",
+			to:   "",
+			want: "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "changed line with context",
+			from: "1\n2\n3\n4\n5\n6\n7\n",
+			to:   "1\n2\n3\nfour\n5\n6\n7\n",
+			want: "--- old\n+++ new\n@@ -2,5 +2,5 @@\n 2\n 3\n-4\n+four\n 5\n 6\n",
+		},
+		{
+			name: "nearby changes share a hunk",
+			from: "1\n2\n3\n4\n5\n6\n",
+			to:   "one\n2\n3\n4\n5\nsix\n",
+			want: "--- old\n+++ new\n@@ -1,6 +1,6 @@\n-1\n+one\n 2\n 3\n 4\n 5\n-6\n+six\n",
+		},
+		{
+			name: "distant changes get their own hunk",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			to:   "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			want: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n-1\n+one\n 2\n 3\n@@ -8,3 +8,3 @@\n 8\n 9\n-10\n+ten\n",
+		},
+		{
+			name: "missing trailing newline",
+			from: "a\nb",
+			to:   "a\nc",
+			want: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified("old", "new", tt.from, tt.to, 2)
+			if got != tt.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineEditsIsShortest(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	alphabet := []string{"a", "b", "c", "d"}
+	randomLines := func() []string {
+		lines := make([]string, random.Intn(30))
+		for i := range lines {
+			lines[i] = alphabet[random.Intn(len(alphabet))]
+		}
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := randomLines(), randomLines()
+		edits := lineEdits(a, b)
+
+		var gotA, gotB []string
+		changes := 0
+		for _, e := range edits {
+			if e.kind != editInsert {
+				gotA = append(gotA, e.line)
+			}
+			if e.kind != editDelete {
+				gotB = append(gotB, e.line)
+			}
+			if e.kind != editEqual {
+				changes++
+			}
+		}
+		if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("edits of %q -> %q do not rebuild both sides", a, b)
+		}
+		if want := len(a) + len(b) - 2*longestCommonSubsequence(a, b); changes != want {
+			t.Fatalf("edits of %q -> %q have %d changes, want %d", a, b, changes, want)
+		}
+	}
+}
+
+func TestLineEditsLargeInput(t *testing.T) {
+	a := make([]string, 100000)
+	b := make([]string, 100000)
+	for i := range a {
+		a[i] = strconv.Itoa(i)
+		b[i] = a[i]
+		if i%1000 == 0 {
+			b[i] = "changed"
+		}
+	}
+
+	edits := lineEdits(a, b)
+	// Every changed line is one deletion and one insertion.
+	if want := len(a) + 100; len(edits) != want {
+		t.Fatalf("got %d edits, want %d", len(edits), want)
+	}
+}
+
+func longestCommonSubsequence(a []string, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	return lengths[0][0]
+}
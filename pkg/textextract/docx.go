@@ -0,0 +1,88 @@
+package textextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+const docxDocumentPath = "word/document.xml"
+
+// extractDOCX reads the text runs of the main document part. Paragraphs and
+// breaks become newlines, table cells are separated by tabs.
+func extractDOCX(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+
+	var document *zip.File
+	for _, file := range archive.File {
+		if file.Name == docxDocumentPath {
+			document = file
+			break
+		}
+	}
+	if document == nil {
+		return "", errors.New("docx has no " + docxDocumentPath)
+	}
+
+	rc, err := document.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	// The size in the archive directory cannot be trusted, the part is read
+	// up to the limit instead.
+	documentXml, err := io.ReadAll(io.LimitReader(rc, MaxDecodedSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(documentXml) > MaxDecodedSize {
+		return "", ErrTooLarge
+	}
+
+	var b strings.Builder
+	decoder := xml.NewDecoder(bytes.NewReader(documentXml))
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteString("\t")
+			case "br", "cr":
+				b.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.WriteString("\n")
+			case "tc":
+				b.WriteString("\t")
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,110 @@
+package textextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const docxContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// buildDOCX zips files keyed by their path in the archive.
+func buildDOCX(t *testing.T, files map[string]io.Reader) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("creating %s: %v", name, err)
+		}
+		if _, err := io.Copy(f, content); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing archive: %v", err)
+	}
+
+	return b.Bytes()
+}
+
+// zeroReader yields n zero bytes.
+type zeroReader struct {
+	n int64
+}
+
+func (z *zeroReader) Read(p []byte) (int, error) {
+	if z.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > z.n {
+		p = p[:z.n]
+	}
+	clear(p)
+	z.n -= int64(len(p))
+
+	return len(p), nil
+}
+
+func TestExtractDOCX(t *testing.T) {
+	document := `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>First</w:t></w:r><w:r><w:tab/><w:t xml:space="preserve">paragraph </w:t></w:r></w:p>
+<w:p><w:r><w:t>Line</w:t><w:br/><w:t>break</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>A</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>B</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+</w:body></w:document>`
+	data := buildDOCX(t, map[string]io.Reader{
+		"[Content_Types].xml": strings.NewReader("<Types/>"),
+		docxDocumentPath:      strings.NewReader(document),
+	})
+
+	got, err := Extract(docxContentType, "notes.docx", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "First\tparagraph \nLine\nbreak\nA\n\tB"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestExtractDOCXErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    func(t *testing.T) []byte
+		wantErr error
+	}{
+		{
+			name: "not a zip",
+			data: func(t *testing.T) []byte { return []byte("plain text") },
+		},
+		{
+			name: "missing document part",
+			data: func(t *testing.T) []byte {
+				return buildDOCX(t, map[string]io.Reader{"word/styles.xml": strings.NewReader("<w:styles/>")})
+			},
+		},
+		{
+			name: "decompression bomb",
+			data: func(t *testing.T) []byte {
+				return buildDOCX(t, map[string]io.Reader{docxDocumentPath: &zeroReader{n: MaxDecodedSize + 1}})
+			},
+			wantErr: ErrTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Extract(docxContentType, "notes.docx", tt.data(t))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package textextract
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	FormatPlain = "plain"
+	FormatHTML  = "html"
+	FormatDOCX  = "docx"
+	FormatPDF   = "pdf"
+
+	// MaxDecodedSize caps the bytes decompressed from a single document, so
+	// that a small compressed file cannot exhaust memory.
+	MaxDecodedSize = 64 * 1024 * 1024
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported document type")
+	ErrTooLarge    = errors.New("document is too large once decompressed")
+)
+
+var blankLinesPattern = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+\n`)
+
+// Format picks the extractor for a file from its content type, falling back
+// to its extension. It returns "" for unsupported files.
+func Format(contentType string, fileName string) string {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch contentType {
+	case "application/pdf":
+		return FormatPDF
+	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
+		return FormatDOCX
+	case "text/html", "application/xhtml+xml":
+		return FormatHTML
+	}
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".pdf":
+		return FormatPDF
+	case ".docx":
+		return FormatDOCX
+	case ".html", ".htm", ".xhtml":
+		return FormatHTML
+	case ".txt", ".md", ".markdown", ".csv", ".tsv", ".json", ".log":
+		return FormatPlain
+	}
+
+	if strings.HasPrefix(contentType, "text/") {
+		return FormatPlain
+	}
+
+	return ""
+}
+
+// Extract returns the plain text of a document with runs of blank lines
+// collapsed.
+func Extract(contentType string, fileName string, data []byte) (string, error) {
+	var text string
+	var err error
+	switch Format(contentType, fileName) {
+	case FormatPlain:
+		text = string(data)
+	case FormatHTML:
+		text, err = extractHTML(data)
+	case FormatDOCX:
+		text, err = extractDOCX(data)
+	case FormatPDF:
+		text, err = extractPDF(data)
+	default:
+		return "", ErrUnsupported
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !utf8.ValidString(text) {
+		text = strings.ToValidUTF8(text, "�")
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\x00", "")
+	text = blankLinesPattern.ReplaceAllString(text, "\n\n")
+
+	return strings.TrimSpace(text), nil
+}
@@ -0,0 +1,97 @@
+package textextract
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlBlockTags end a line of text.
+var htmlBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true,
+	"main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true,
+	"table": true, "tr": true, "ul": true,
+}
+
+// htmlSkippedTags never contain readable text.
+var htmlSkippedTags = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true, "svg": true,
+}
+
+func extractHTML(data []byte) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	// space is a separator owed before the next text, dropped at the end of
+	// a cell or line.
+	space := false
+	var walk func(n *html.Node, pre bool)
+	walk = func(n *html.Node, pre bool) {
+		if n.Type == html.ElementNode {
+			if htmlSkippedTags[n.Data] {
+				return
+			}
+			pre = pre || n.Data == "pre"
+			if n.Data != "br" && htmlBlockTags[n.Data] && !atLineOrCellStart(b.String()) {
+				b.WriteString("\n")
+				space = false
+			}
+		}
+		if n.Type == html.TextNode {
+			if pre {
+				b.WriteString(n.Data)
+				space = false
+			} else if text := strings.Join(strings.Fields(n.Data), " "); text != "" {
+				if space || (startsWithSpace(n.Data) && !atLineOrCellStart(b.String())) {
+					b.WriteString(" ")
+				}
+				b.WriteString(text)
+				space = endsWithSpace(n.Data)
+			} else if n.Data != "" && !atLineOrCellStart(b.String()) {
+				space = true
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, pre)
+		}
+
+		if n.Type == html.ElementNode {
+			if n.Data == "td" || n.Data == "th" {
+				b.WriteString("\t")
+				space = false
+			} else if n.Data == "br" || (htmlBlockTags[n.Data] && b.Len() > 0 && !strings.HasSuffix(b.String(), "\n")) {
+				// Blocks start and end a single line, only <br> adds blank ones.
+				b.WriteString("\n")
+				space = false
+			}
+		}
+	}
+	walk(doc, false)
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.TrimLeft(s, " \t\r\n") != s
+}
+
+func endsWithSpace(s string) bool {
+	return s != "" && strings.TrimRight(s, " \t\r\n") != s
+}
+
+func atLineOrCellStart(s string) bool {
+	return s == "" || strings.HasSuffix(s, "\n") || strings.HasSuffix(s, "\t")
+}
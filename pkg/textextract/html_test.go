@@ -0,0 +1,82 @@
+package textextract
+
+import "testing"
+
+func TestExtractHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "plain text",
+			html: "plain",
+			want: "plain",
+		},
+		{
+			name: "skipped tags",
+			html: `<html><head><title>Title</title><style>p { color: red }</style></head>` +
+				`<body><script>var x = 1</script><p>Hello <b>bold</b> world</p>` +
+				`<noscript>enable js</noscript><template><p>later</p></template><svg><text>chart</text></svg></body></html>`,
+			want: "Hello bold world",
+		},
+		{
+			name: "whitespace is collapsed outside pre",
+			html: "<p>  one\n\n   two\t three  </p><p>four<i> five </i>six</p>",
+			want: "one two three\nfour five six",
+		},
+		{
+			name: "pre keeps whitespace",
+			html: "<p>before</p><pre>func main() {\n    fmt.Println(\"hi\")\n\n}</pre><p>after</p>",
+			want: "before\nfunc main() {\n    fmt.Println(\"hi\")\n\n}\nafter",
+		},
+		{
+			name: "nested pre",
+			html: "<pre><code>a  b\n  c</code></pre>",
+			want: "a  b\n  c",
+		},
+		{
+			name: "block tags end lines",
+			html: "<h1>Title</h1><div>one</div><section><div>two</div></section><blockquote>three</blockquote>text<hr>end",
+			want: "Title\none\ntwo\nthree\ntext\nend",
+		},
+		{
+			name: "nested lists end a single line",
+			html: "<ul><li>a</li><li>b<ul><li>c</li></ul></li></ul>after",
+			want: "a\nb\nc\nafter",
+		},
+		{
+			name: "br adds blank lines",
+			html: "one<br>two<br><br>three",
+			want: "one\ntwo\n\nthree",
+		},
+		{
+			name: "inline tags do not break lines",
+			html: "<p><span>in</span><a href=\"#\">line</a> <em>text</em></p>",
+			want: "inline text",
+		},
+		{
+			name: "table cells",
+			html: "<table><thead><tr><th>Name</th><th> Qty </th></tr></thead>" +
+				"<tbody><tr><td> apple </td><td>3</td></tr><tr><td>pear</td><td></td></tr></tbody></table>",
+			want: "Name\tQty\napple\t3\npear",
+		},
+		{
+			name: "entities",
+			html: "<p>a &amp; b &lt;c&gt;&nbsp;d</p>",
+			want: "a & b <c> d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract("text/html", "page.html", []byte(tt.html))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
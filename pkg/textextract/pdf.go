@@ -0,0 +1,572 @@
+package textextract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// pdfMaxFormDepth limits how deep form XObjects drawing other forms are
+	// followed.
+	pdfMaxFormDepth = 5
+	// pdfWordSpacing is the TJ adjustment, in thousandths of an em, past
+	// which a gap is read as a space between words.
+	pdfWordSpacing = 200
+)
+
+var pdfObjectPattern = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+var errPDFEncrypted = errors.New("encrypted pdf")
+
+type pdfObject struct {
+	value  any
+	stream []byte
+}
+
+type pdfDocument struct {
+	objects map[int]*pdfObject
+	fonts   map[*pdfObject]*pdfFont
+	// decodedBudget is what is left of MaxDecodedSize for this document,
+	// err is set once a stream exceeds it.
+	decodedBudget int64
+	err           error
+}
+
+// extractPDF reads the text shown on each page in page order. It handles
+// Flate compressed content, object streams, ToUnicode maps and simple font
+// encodings, which covers text based PDFs; scanned pages yield nothing.
+func extractPDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\f\r "), []byte("%PDF")) {
+		return "", errors.New("not a pdf file")
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", errPDFEncrypted
+	}
+
+	doc := pdfDocument{
+		objects:       make(map[int]*pdfObject),
+		fonts:         make(map[*pdfObject]*pdfFont),
+		decodedBudget: MaxDecodedSize,
+	}
+	doc.load(data)
+	if doc.err != nil {
+		return "", doc.err
+	}
+
+	var b strings.Builder
+	for _, page := range doc.pages() {
+		w := pdfTextWriter{doc: &doc}
+		w.run(doc.pageContent(page.dict), page.resources, 0)
+		if doc.err != nil {
+			return "", doc.err
+		}
+		text := strings.TrimSpace(w.b.String())
+		if text != "" {
+			b.WriteString(text)
+			b.WriteString("\n\n")
+		}
+	}
+
+	return b.String(), nil
+}
+
+func (d *pdfDocument) load(data []byte) {
+	for _, match := range pdfObjectPattern.FindAllSubmatchIndex(data, -1) {
+		num := atoi(data[match[2]:match[3]])
+		l := pdfLexer{data: data, pos: match[1]}
+		obj := pdfObject{value: l.object(true)}
+
+		save := l.pos
+		if l.token() == pdfKeyword("stream") {
+			obj.stream = streamData(data, l.pos, obj.value)
+		} else {
+			l.pos = save
+		}
+		d.objects[num] = &obj
+	}
+
+	// Objects packed in object streams, unless a plain object of the same
+	// number exists.
+	for _, obj := range d.objects {
+		dict, ok := obj.value.(pdfDict)
+		if !ok || dict["Type"] != pdfName("ObjStm") || obj.stream == nil {
+			continue
+		}
+		decoded, err := d.decodeStream(obj)
+		if err != nil {
+			continue
+		}
+
+		n, _ := d.resolve(dict["N"]).(float64)
+		first, _ := d.resolve(dict["First"]).(float64)
+		header := pdfLexer{data: decoded}
+		for i := 0; i < int(n); i++ {
+			num, ok1 := header.token().(float64)
+			offset, ok2 := header.token().(float64)
+			if !ok1 || !ok2 {
+				break
+			}
+			if _, exists := d.objects[int(num)]; exists {
+				continue
+			}
+			at := int(first) + int(offset)
+			if at < 0 || at >= len(decoded) {
+				continue
+			}
+			l := pdfLexer{data: decoded, pos: at}
+			d.objects[int(num)] = &pdfObject{value: l.object(true)}
+		}
+	}
+}
+
+// streamData returns the raw bytes after the stream keyword, using /Length
+// when it is direct and looking for endstream otherwise.
+func streamData(data []byte, pos int, value any) []byte {
+	if pos < len(data) && data[pos] == '\r' {
+		pos++
+	}
+	if pos < len(data) && data[pos] == '\n' {
+		pos++
+	}
+
+	if dict, ok := value.(pdfDict); ok {
+		if length, ok := dict["Length"].(float64); ok {
+			end := pos + int(length)
+			if end <= len(data) && bytes.HasPrefix(bytes.TrimLeft(data[end:], "\r\n \t"), []byte("endstream")) {
+				return data[pos:end]
+			}
+		}
+	}
+
+	end := bytes.Index(data[pos:], []byte("endstream"))
+	if end < 0 {
+		return data[pos:]
+	}
+
+	return bytes.TrimRight(data[pos:pos+end], "\r\n")
+}
+
+func (d *pdfDocument) resolve(v any) any {
+	for i := 0; i < 16; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		obj, ok := d.objects[ref.num]
+		if !ok {
+			return nil
+		}
+		v = obj.value
+	}
+
+	return nil
+}
+
+func (d *pdfDocument) object(v any) *pdfObject {
+	ref, ok := v.(pdfRef)
+	if !ok {
+		return nil
+	}
+
+	return d.objects[ref.num]
+}
+
+func (d *pdfDocument) dict(v any) pdfDict {
+	dict, _ := d.resolve(v).(pdfDict)
+
+	return dict
+}
+
+func (d *pdfDocument) decodeStream(obj *pdfObject) ([]byte, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	dict, _ := obj.value.(pdfDict)
+	data := obj.stream
+
+	filters := make([]any, 0)
+	switch f := d.resolve(dict["Filter"]).(type) {
+	case pdfName:
+		filters = append(filters, f)
+	case pdfArray:
+		for _, item := range f {
+			filters = append(filters, d.resolve(item))
+		}
+	}
+	params := make([]pdfDict, len(filters))
+	switch p := d.resolve(dict["DecodeParms"]).(type) {
+	case pdfDict:
+		if len(params) > 0 {
+			params[0] = p
+		}
+	case pdfArray:
+		for i := range params {
+			if i < len(p) {
+				params[i] = d.dict(p[i])
+			}
+		}
+	}
+
+	for i, filter := range filters {
+		switch filter {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			decoded, err := inflate(data, d.decodedBudget)
+			if err != nil {
+				if errors.Is(err, ErrTooLarge) {
+					d.err = err
+				}
+				return nil, err
+			}
+			d.decodedBudget -= int64(len(decoded))
+			data = d.unpredict(decoded, params[i])
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			l := pdfLexer{data: data}
+			data = l.hexString()
+		default:
+			return nil, ErrUnsupported
+		}
+	}
+
+	return data, nil
+}
+
+// inflate decompresses zlib data, keeping what was read from a truncated or
+// damaged stream. It fails with ErrTooLarge past limit bytes.
+func inflate(data []byte, limit int64) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	decoded, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if int64(len(decoded)) > limit {
+		return nil, ErrTooLarge
+	}
+	if err != nil && len(decoded) == 0 {
+		return nil, err
+	}
+
+	return decoded, nil
+}
+
+// unpredict reverses the PNG predictors of /DecodeParms, used by object
+// streams written with them.
+func (d *pdfDocument) unpredict(data []byte, params pdfDict) []byte {
+	predictor, _ := d.resolve(params["Predictor"]).(float64)
+	if predictor < 10 {
+		return data
+	}
+	columns := 1
+	if c, ok := d.resolve(params["Columns"]).(float64); ok && c > 0 {
+		columns = int(c)
+	}
+
+	rowSize := columns + 1
+	out := make([]byte, 0, len(data))
+	prev := make([]byte, columns)
+	for start := 0; start+rowSize <= len(data); start += rowSize {
+		filter := data[start]
+		row := append([]byte(nil), data[start+1:start+rowSize]...)
+		for i := range row {
+			var left, upLeft byte
+			if i > 0 {
+				left = row[i-1]
+				upLeft = prev[i-1]
+			}
+			up := prev[i]
+			switch filter {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+
+	return out
+}
+
+func paeth(a byte, b byte, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+
+	return c
+}
+
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages walks the page tree from the catalog, passing inherited resources
+// down. Files without a usable catalog fall back to every page object.
+func (d *pdfDocument) pages() []pdfPage {
+	pages := make([]pdfPage, 0)
+	visited := make(map[int]bool)
+
+	var walk func(v any, resources pdfDict)
+	walk = func(v any, resources pdfDict) {
+		if ref, ok := v.(pdfRef); ok {
+			if visited[ref.num] {
+				return
+			}
+			visited[ref.num] = true
+		}
+		node := d.dict(v)
+		if node == nil {
+			return
+		}
+		if r := d.dict(node["Resources"]); r != nil {
+			resources = r
+		}
+
+		if node["Type"] == pdfName("Pages") || node["Kids"] != nil {
+			kids, _ := d.resolve(node["Kids"]).(pdfArray)
+			for _, kid := range kids {
+				walk(kid, resources)
+			}
+			return
+		}
+		pages = append(pages, pdfPage{dict: node, resources: resources})
+	}
+
+	nums := make([]int, 0)
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	for i := len(nums) - 1; i >= 0; i-- {
+		dict, ok := d.objects[nums[i]].value.(pdfDict)
+		if ok && dict["Type"] == pdfName("Catalog") && dict["Pages"] != nil {
+			walk(dict["Pages"], nil)
+			if len(pages) > 0 {
+				return pages
+			}
+		}
+	}
+
+	for _, num := range nums {
+		dict, ok := d.objects[num].value.(pdfDict)
+		if ok && dict["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: dict, resources: d.dict(dict["Resources"])})
+		}
+	}
+
+	return pages
+}
+
+func (d *pdfDocument) pageContent(page pdfDict) []byte {
+	refs := make([]any, 0)
+	switch contents := page["Contents"].(type) {
+	case pdfRef:
+		if array, ok := d.resolve(contents).(pdfArray); ok {
+			refs = append(refs, array...)
+		} else {
+			refs = append(refs, contents)
+		}
+	case pdfArray:
+		refs = append(refs, contents...)
+	}
+
+	// Content may be split between streams at any token, so the parts are
+	// joined before being read.
+	var joined []byte
+	for _, ref := range refs {
+		obj := d.object(ref)
+		if obj == nil || obj.stream == nil {
+			continue
+		}
+		decoded, err := d.decodeStream(obj)
+		if err != nil {
+			continue
+		}
+		joined = append(joined, decoded...)
+		joined = append(joined, '\n')
+	}
+
+	return joined
+}
+
+// pdfTextWriter runs a content stream, writing the strings it shows and
+// guessing line and word breaks from the text positioning operators.
+type pdfTextWriter struct {
+	doc   *pdfDocument
+	b     strings.Builder
+	font  *pdfFont
+	lastY float64
+}
+
+func (w *pdfTextWriter) run(content []byte, resources pdfDict, depth int) {
+	l := pdfLexer{data: content}
+	operands := make([]any, 0)
+	for {
+		token := l.token()
+		if token == nil {
+			return
+		}
+
+		op, ok := token.(pdfKeyword)
+		if !ok {
+			operands = append(operands, l.objectFrom(token, false, 0))
+			continue
+		}
+
+		switch op {
+		case "true", "false", "null":
+			operands = append(operands, l.objectFrom(token, false, 0))
+			continue
+		case "BI":
+			for {
+				t := l.token()
+				if t == nil || t == pdfKeyword("ID") {
+					break
+				}
+			}
+			l.skipInlineImage()
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					w.font = w.doc.font(resources, name)
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				tx, _ := operands[len(operands)-2].(float64)
+				ty, _ := operands[len(operands)-1].(float64)
+				if ty != 0 {
+					w.newLine()
+				} else if tx > 0 {
+					w.space()
+				}
+				w.lastY += ty
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				y, _ := operands[len(operands)-1].(float64)
+				if math.Abs(y-w.lastY) > 0.5 {
+					w.newLine()
+				} else {
+					w.space()
+				}
+				w.lastY = y
+			}
+		case "T*":
+			w.newLine()
+		case "Tj":
+			if len(operands) >= 1 {
+				w.show(operands[len(operands)-1])
+			}
+		case "'", "\"":
+			w.newLine()
+			if len(operands) >= 1 {
+				w.show(operands[len(operands)-1])
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				array, _ := operands[len(operands)-1].(pdfArray)
+				for _, item := range array {
+					if n, ok := item.(float64); ok {
+						if n < -pdfWordSpacing {
+							w.space()
+						}
+						continue
+					}
+					w.show(item)
+				}
+			}
+		case "Do":
+			if depth < pdfMaxFormDepth && len(operands) >= 1 {
+				if name, ok := operands[len(operands)-1].(pdfName); ok {
+					w.form(resources, name, depth)
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func (w *pdfTextWriter) form(resources pdfDict, name pdfName, depth int) {
+	xobjects := w.doc.dict(resources["XObject"])
+	obj := w.doc.object(xobjects[name])
+	if obj == nil || obj.stream == nil {
+		return
+	}
+	dict, _ := obj.value.(pdfDict)
+	if dict["Subtype"] != pdfName("Form") {
+		return
+	}
+
+	content, err := w.doc.decodeStream(obj)
+	if err != nil {
+		return
+	}
+	if r := w.doc.dict(dict["Resources"]); r != nil {
+		resources = r
+	}
+
+	font := w.font
+	w.run(content, resources, depth+1)
+	w.font = font
+}
+
+func (w *pdfTextWriter) show(v any) {
+	s, ok := v.(pdfString)
+	if !ok {
+		return
+	}
+
+	w.b.WriteString(w.font.decode(s))
+}
+
+func (w *pdfTextWriter) newLine() {
+	if w.b.Len() > 0 && !strings.HasSuffix(w.b.String(), "\n") {
+		w.b.WriteString("\n")
+	}
+}
+
+func (w *pdfTextWriter) space() {
+	if w.b.Len() == 0 {
+		return
+	}
+	s := w.b.String()
+	if !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		w.b.WriteString(" ")
+	}
+}
+
+func atoi(b []byte) int {
+	n := 0
+	for _, c := range b {
+		n = n*10 + int(c-'0')
+	}
+
+	return n
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,288 @@
+package textextract
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+type pdfFont struct {
+	// toUnicode maps character codes of codeLength bytes to text, and
+	// toUnicodeRanges the bfrange entries that increment their text, which
+	// are kept as ranges since identity maps span every two byte code.
+	toUnicode       map[uint32]string
+	toUnicodeRanges []pdfCodeRange
+	codeLength      int
+	// differences overrides single byte codes from the font's /Encoding.
+	differences map[byte]rune
+}
+
+type pdfCodeRange struct {
+	start uint32
+	end   uint32
+	base  []rune
+}
+
+// winAnsiHigh holds the WinAnsiEncoding characters of 0x80 to 0x9F, the rest
+// of the encoding matches Latin-1.
+var winAnsiHigh = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// glyphNames covers the glyph names commonly found in /Differences arrays
+// whose character is not obvious from the name.
+var glyphNames = map[string]string{
+	"space": " ", "quoteright": "’", "quoteleft": "‘", "quotedblleft": "“",
+	"quotedblright": "”", "quotesingle": "'", "quotedbl": "\"", "endash": "–",
+	"emdash": "—", "bullet": "•", "ellipsis": "…", "hyphen": "-", "minus": "−",
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl", "period": ".",
+	"comma": ",", "colon": ":", "semicolon": ";", "exclam": "!", "question": "?",
+	"parenleft": "(", "parenright": ")", "slash": "/", "ampersand": "&",
+	"percent": "%", "dollar": "$", "numbersign": "#", "at": "@", "plus": "+",
+	"equal": "=", "underscore": "_", "asterisk": "*", "zero": "0", "one": "1",
+	"two": "2", "three": "3", "four": "4", "five": "5", "six": "6", "seven": "7",
+	"eight": "8", "nine": "9", "degree": "°", "copyright": "©", "registered": "®",
+	"trademark": "™", "section": "§", "paragraph": "¶", "dagger": "†",
+}
+
+// font loads the font named in the resources, caching it per object.
+func (d *pdfDocument) font(resources pdfDict, name pdfName) *pdfFont {
+	fonts := d.dict(resources["Font"])
+	ref := fonts[name]
+	obj := d.object(ref)
+	if obj != nil {
+		if font, ok := d.fonts[obj]; ok {
+			return font
+		}
+	}
+
+	dict := d.dict(ref)
+	if dict == nil {
+		return nil
+	}
+
+	font := pdfFont{codeLength: 1}
+	if dict["Subtype"] == pdfName("Type0") {
+		font.codeLength = 2
+	}
+
+	if cmap := d.object(dict["ToUnicode"]); cmap != nil && cmap.stream != nil {
+		if data, err := d.decodeStream(cmap); err == nil {
+			font.parseToUnicode(data)
+		}
+	}
+
+	if encoding := d.dict(dict["Encoding"]); encoding != nil {
+		differences, _ := d.resolve(encoding["Differences"]).(pdfArray)
+		font.differences = make(map[byte]rune)
+		code := 0
+		for _, item := range differences {
+			switch v := d.resolve(item).(type) {
+			case float64:
+				code = int(v)
+			case pdfName:
+				if text := glyphText(string(v)); text != "" && code < 256 {
+					font.differences[byte(code)] = []rune(text)[0]
+				}
+				code++
+			}
+		}
+	}
+
+	if obj != nil {
+		d.fonts[obj] = &font
+	}
+
+	return &font
+}
+
+func (f *pdfFont) parseToUnicode(data []byte) {
+	f.toUnicode = make(map[uint32]string)
+	l := pdfLexer{data: data}
+	mode := ""
+	operands := make([]any, 0)
+	for {
+		token := l.token()
+		if token == nil {
+			return
+		}
+
+		keyword, ok := token.(pdfKeyword)
+		if !ok {
+			operands = append(operands, l.objectFrom(token, false, 0))
+			continue
+		}
+
+		switch keyword {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			mode = string(keyword)
+			operands = operands[:0]
+			continue
+		case "endcodespacerange":
+			if len(operands) >= 1 {
+				if lo, ok := operands[0].(pdfString); ok && len(lo) > 0 {
+					f.codeLength = len(lo)
+				}
+			}
+			mode = ""
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok := operands[i].(pdfString)
+				if !ok {
+					continue
+				}
+				f.toUnicode[codeValue(src)] = cmapText(operands[i+1])
+			}
+			mode = ""
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				start, end := codeValue(lo), codeValue(hi)
+				if end < start || end-start > 0xFFFF {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					base := []rune(utf16Text(dst))
+					if len(base) > 0 {
+						f.toUnicodeRanges = append(f.toUnicodeRanges, pdfCodeRange{start: start, end: end, base: base})
+					}
+				case pdfArray:
+					for j, item := range dst {
+						if start+uint32(j) > end {
+							break
+						}
+						f.toUnicode[start+uint32(j)] = cmapText(item)
+					}
+				}
+			}
+			mode = ""
+		}
+
+		if mode == "" {
+			operands = operands[:0]
+		}
+	}
+}
+
+// decode turns the bytes of a shown string into text. A nil font decodes
+// bytes as WinAnsiEncoding.
+func (f *pdfFont) decode(s pdfString) string {
+	var b strings.Builder
+	codeLength := 1
+	if f != nil && f.codeLength > 1 {
+		codeLength = f.codeLength
+	}
+
+	for i := 0; i < len(s); i += codeLength {
+		end := i + codeLength
+		if end > len(s) {
+			end = len(s)
+		}
+		code := codeValue(s[i:end])
+
+		if f != nil {
+			if text, ok := f.unicodeText(code); ok {
+				b.WriteString(text)
+				continue
+			}
+		}
+		if codeLength > 1 {
+			// Multi-byte codes without a mapping are glyph ids, which do not
+			// say anything about the character.
+			continue
+		}
+		if f != nil {
+			if r, ok := f.differences[byte(code)]; ok {
+				b.WriteRune(r)
+				continue
+			}
+		}
+		if r := winAnsiRune(byte(code)); r != 0 {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+func (f *pdfFont) unicodeText(code uint32) (string, bool) {
+	if text, ok := f.toUnicode[code]; ok {
+		return text, true
+	}
+	// Later ranges win, like later entries of the map.
+	for i := len(f.toUnicodeRanges) - 1; i >= 0; i-- {
+		r := f.toUnicodeRanges[i]
+		if code < r.start || code > r.end {
+			continue
+		}
+		text := append([]rune(nil), r.base...)
+		text[len(text)-1] += rune(code - r.start)
+		return string(text), true
+	}
+
+	return "", false
+}
+
+func winAnsiRune(c byte) rune {
+	switch {
+	case c == '\t' || c == '\n' || c == '\r':
+		return rune(c)
+	case c < 0x20:
+		return 0
+	case c >= 0x80 && c <= 0x9F:
+		return winAnsiHigh[c-0x80]
+	default:
+		return rune(c)
+	}
+}
+
+func glyphText(name string) string {
+	if text, ok := glyphNames[name]; ok {
+		return text
+	}
+	if strings.HasPrefix(name, "uni") && len(name) >= 7 {
+		if v, err := strconv.ParseUint(name[3:7], 16, 32); err == nil {
+			return string(rune(v))
+		}
+	}
+	if len([]rune(name)) == 1 {
+		return name
+	}
+
+	return ""
+}
+
+func cmapText(v any) string {
+	switch dst := v.(type) {
+	case pdfString:
+		return utf16Text(dst)
+	case pdfName:
+		return glyphText(string(dst))
+	}
+
+	return ""
+}
+
+func utf16Text(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+
+	return string(utf16.Decode(units))
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+
+	return v
+}
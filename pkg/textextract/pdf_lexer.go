@@ -0,0 +1,289 @@
+package textextract
+
+import (
+	"bytes"
+	"strconv"
+)
+
+type pdfName string
+
+type pdfString []byte
+
+// pdfKeyword is a bare word: true, false, null, R and the operators of
+// content streams.
+type pdfKeyword string
+
+type pdfRef struct {
+	num int
+	gen int
+}
+
+type pdfDict map[pdfName]any
+
+type pdfArray []any
+
+type pdfDelimiter byte
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFWhitespace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return c == '(' || c == ')' || c == '<' || c == '>' || c == '[' || c == ']' || c == '{' || c == '}' || c == '/' || c == '%'
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFWhitespace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// token returns the next token, or nil at the end of the data. Tokens are
+// pdfName, pdfString, float64, pdfKeyword and pdfDelimiter for the [ ] <<
+// and >> punctuation, where << is '<' and >> is '>'.
+func (l *pdfLexer) token() any {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		return l.name()
+	case c == '(':
+		l.pos++
+		return l.literalString()
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfDelimiter('<')
+		}
+		l.pos++
+		return l.hexString()
+	case c == '>':
+		l.pos++
+		if l.pos < len(l.data) && l.data[l.pos] == '>' {
+			l.pos++
+		}
+		return pdfDelimiter('>')
+	case c == '[' || c == ']' || c == '{' || c == '}' || c == ')':
+		l.pos++
+		return pdfDelimiter(c)
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		start := l.pos
+		l.pos++
+		for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		n, err := strconv.ParseFloat(string(l.data[start:l.pos]), 64)
+		if err != nil {
+			return pdfKeyword(l.data[start:l.pos])
+		}
+		return n
+	default:
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		return pdfKeyword(l.data[start:l.pos])
+	}
+}
+
+func (l *pdfLexer) name() pdfName {
+	var b []byte
+	for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		l.pos++
+	}
+
+	return pdfName(b)
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+			b = append(b, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return b
+			}
+			b = append(b, c)
+		case '\\':
+			if l.pos >= len(l.data) {
+				return b
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				b = append(b, '\n')
+			case 'r':
+				b = append(b, '\r')
+			case 't':
+				b = append(b, '\t')
+			case 'b':
+				b = append(b, '\b')
+			case 'f':
+				b = append(b, '\f')
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					b = append(b, byte(v))
+				} else {
+					b = append(b, e)
+				}
+			}
+		default:
+			b = append(b, c)
+		}
+	}
+
+	return b
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		c := l.data[l.pos]
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	b := make([]byte, len(digits)/2)
+	for i := range b {
+		v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		b[i] = byte(v)
+	}
+
+	return b
+}
+
+// object parses a complete value. With refs set, "n g R" is read as a
+// reference, which only happens outside content streams.
+func (l *pdfLexer) object(refs bool) any {
+	return l.objectFrom(l.token(), refs, 0)
+}
+
+func (l *pdfLexer) objectFrom(token any, refs bool, depth int) any {
+	if depth > 64 {
+		return nil
+	}
+
+	switch t := token.(type) {
+	case pdfDelimiter:
+		switch t {
+		case '[':
+			array := make(pdfArray, 0)
+			for {
+				next := l.token()
+				if next == nil || next == pdfDelimiter(']') {
+					return array
+				}
+				array = append(array, l.objectFrom(next, refs, depth+1))
+			}
+		case '<':
+			dict := make(pdfDict)
+			for {
+				next := l.token()
+				if next == nil || next == pdfDelimiter('>') {
+					return dict
+				}
+				key, ok := next.(pdfName)
+				if !ok {
+					continue
+				}
+				dict[key] = l.objectFrom(l.token(), refs, depth+1)
+			}
+		}
+		return nil
+	case float64:
+		if !refs || t != float64(int(t)) {
+			return t
+		}
+		save := l.pos
+		gen, ok := l.token().(float64)
+		if ok && gen == float64(int(gen)) && l.token() == pdfKeyword("R") {
+			return pdfRef{num: int(t), gen: int(gen)}
+		}
+		l.pos = save
+		return t
+	case pdfKeyword:
+		switch t {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return t
+	default:
+		return t
+	}
+}
+
+// skipInlineImage moves past the binary data of an inline image, which
+// starts after the ID operator and ends at an EI operator.
+func (l *pdfLexer) skipInlineImage() {
+	l.pos++
+	for l.pos < len(l.data) {
+		i := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if i < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		at := l.pos + i
+		l.pos = at + 2
+		if (at == 0 || isPDFWhitespace(l.data[at-1])) && (l.pos >= len(l.data) || isPDFWhitespace(l.data[l.pos])) {
+			return
+		}
+	}
+}
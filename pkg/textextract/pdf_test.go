@@ -0,0 +1,427 @@
+package textextract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+// The files in testdata were written by go-pdf/fpdf: simple.pdf uses a
+// standard font, unicode.pdf an embedded TrueType font with a ToUnicode map,
+// and objstm.pdf is simple.pdf rewritten by pdfcpu with object streams and a
+// cross reference stream.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+
+	return data
+}
+
+func TestExtractPDFFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    string
+	}{
+		{fixture: "simple.pdf", want: "First page heading\nSecond line of the first page\n\nSecond page text"},
+		{fixture: "objstm.pdf", want: "First page heading\nSecond line of the first page\n\nSecond page text"},
+		{fixture: "unicode.pdf", want: "Grüße aus Köln\nΕλληνικά και Кириллица"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got, err := Extract("application/pdf", tt.fixture, readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// testPDFObject is an indirect object of buildPDF, dict is written as is and
+// gets a /Length entry when stream is set. An empty dict leaves the object
+// number free.
+type testPDFObject struct {
+	dict   string
+	stream []byte
+}
+
+// buildPDF numbers objects from 1, the first one being the catalog, and
+// writes a cross reference table for them.
+func buildPDF(objects ...testPDFObject) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	offsets := make([]int, 0)
+	for i, obj := range objects {
+		if obj.dict == "" {
+			offsets = append(offsets, -1)
+			continue
+		}
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		if obj.stream == nil {
+			fmt.Fprintf(&b, "%s\nendobj\n", obj.dict)
+			continue
+		}
+		dict := strings.TrimSuffix(obj.dict, ">>")
+		fmt.Fprintf(&b, "%s/Length %d>>\nstream\n", dict, len(obj.stream))
+		b.Write(obj.stream)
+		b.WriteString("\nendstream\nendobj\n")
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		if offset < 0 {
+			b.WriteString("0000000000 00001 f \n")
+			continue
+		}
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<</Size %d/Root 1 0 R>>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return b.Bytes()
+}
+
+const helveticaFont = "<</Type/Font/Subtype/Type1/BaseFont/Helvetica>>"
+
+// singlePagePDF draws content on one page with font as /F1, extra objects
+// are numbered from 6.
+func singlePagePDF(font string, content testPDFObject, extra ...testPDFObject) []byte {
+	objects := []testPDFObject{
+		{dict: "<</Type/Catalog/Pages 2 0 R>>"},
+		{dict: "<</Type/Pages/Kids[3 0 R]/Count 1>>"},
+		{dict: "<</Type/Page/Parent 2 0 R/Resources<</Font<</F1 5 0 R>>>>/Contents 4 0 R>>"},
+		content,
+		{dict: font},
+	}
+
+	return buildPDF(append(objects, extra...)...)
+}
+
+func flate(data []byte) []byte {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write(data)
+	w.Close()
+
+	return b.Bytes()
+}
+
+// pngUp encodes data with the PNG Up predictor in rows of columns bytes,
+// padding the last row with spaces.
+func pngUp(data []byte, columns int) []byte {
+	for len(data)%columns != 0 {
+		data = append(data, ' ')
+	}
+
+	out := make([]byte, 0)
+	prev := make([]byte, columns)
+	for start := 0; start < len(data); start += columns {
+		row := data[start : start+columns]
+		out = append(out, 2)
+		for i := range row {
+			out = append(out, row[i]-prev[i])
+		}
+		prev = row
+	}
+
+	return out
+}
+
+const testContent = "BT /F1 12 Tf 72 720 Td (Hello) Tj 0 -14 Td [(Wor) -50 (ld) -300 (again)] TJ T* (Bye) Tj ET"
+
+func TestExtractPDF(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{
+			name: "uncompressed content",
+			data: singlePagePDF(helveticaFont, testPDFObject{dict: "<<>>", stream: []byte(testContent)}),
+			want: "Hello\nWorld again\nBye",
+		},
+		{
+			name: "flate content",
+			data: singlePagePDF(helveticaFont, testPDFObject{
+				dict:   "<</Filter/FlateDecode>>",
+				stream: flate([]byte(testContent)),
+			}),
+			want: "Hello\nWorld again\nBye",
+		},
+		{
+			name: "flate content with png predictor",
+			data: singlePagePDF(helveticaFont, testPDFObject{
+				dict:   "<</Filter/FlateDecode/DecodeParms<</Predictor 12/Columns 8>>>>",
+				stream: flate(pngUp([]byte(testContent), 8)),
+			}),
+			want: "Hello\nWorld again\nBye",
+		},
+		{
+			name: "ascii hex content",
+			data: singlePagePDF(helveticaFont, testPDFObject{
+				dict:   "<</Filter/ASCIIHexDecode>>",
+				stream: []byte(fmt.Sprintf("%X>", "BT /F1 12 Tf (Hex encoded) Tj ET")),
+			}),
+			want: "Hex encoded",
+		},
+		{
+			name: "filter chain",
+			data: singlePagePDF(helveticaFont, testPDFObject{
+				dict:   "<</Filter[/ASCIIHexDecode/FlateDecode]>>",
+				stream: []byte(fmt.Sprintf("%X>", flate([]byte("BT /F1 12 Tf (Chained) Tj ET")))),
+			}),
+			want: "Chained",
+		},
+		{
+			name: "unsupported filter",
+			data: singlePagePDF(helveticaFont, testPDFObject{
+				dict:   "<</Filter/DCTDecode>>",
+				stream: []byte("not text"),
+			}),
+			want: "",
+		},
+		{
+			name: "content array",
+			data: buildPDF(
+				testPDFObject{dict: "<</Type/Catalog/Pages 2 0 R>>"},
+				testPDFObject{dict: "<</Type/Pages/Kids[3 0 R]/Count 1>>"},
+				testPDFObject{dict: "<</Type/Page/Parent 2 0 R/Contents[4 0 R 5 0 R]>>"},
+				// The split falls between an operand and its operator.
+				testPDFObject{dict: "<<>>", stream: []byte("BT (Split across streams)")},
+				testPDFObject{dict: "<<>>", stream: []byte("Tj ET")},
+			),
+			want: "Split across streams",
+		},
+		{
+			name: "page order follows the page tree",
+			data: buildPDF(
+				testPDFObject{dict: "<</Type/Catalog/Pages 2 0 R>>"},
+				testPDFObject{dict: "<</Type/Pages/Kids[4 0 R 3 0 R]/Count 2>>"},
+				testPDFObject{dict: "<</Type/Page/Parent 2 0 R/Contents 5 0 R>>"},
+				testPDFObject{dict: "<</Type/Page/Parent 2 0 R/Contents 6 0 R>>"},
+				testPDFObject{dict: "<<>>", stream: []byte("BT (Second) Tj ET")},
+				testPDFObject{dict: "<<>>", stream: []byte("BT (First) Tj ET")},
+			),
+			want: "First\n\nSecond",
+		},
+		{
+			name: "object stream with png predictor",
+			data: func() []byte {
+				packed := []string{
+					"<</Type/Pages/Kids[3 0 R]/Count 1>>",
+					"<</Type/Page/Parent 2 0 R/Contents 4 0 R>>",
+				}
+				header := fmt.Sprintf("2 0 3 %d ", len(packed[0])+1)
+				body := header + packed[0] + " " + packed[1]
+				return buildPDF(
+					testPDFObject{dict: "<</Type/Catalog/Pages 2 0 R>>"},
+					// Objects 2 and 3 are only in the object stream.
+					testPDFObject{},
+					testPDFObject{},
+					testPDFObject{dict: "<<>>", stream: []byte("BT (Packed) Tj ET")},
+					testPDFObject{
+						dict:   fmt.Sprintf("<</Type/ObjStm/N 2/First %d/Filter/FlateDecode/DecodeParms<</Predictor 12/Columns 5>>>>", len(header)),
+						stream: flate(pngUp([]byte(body), 5)),
+					},
+				)
+			}(),
+			want: "Packed",
+		},
+		{
+			name: "tounicode cmap",
+			data: singlePagePDF(
+				"<</Type/Font/Subtype/Type0/BaseFont/Embedded/Encoding/Identity-H/ToUnicode 6 0 R>>",
+				testPDFObject{dict: "<<>>", stream: []byte("BT /F1 12 Tf <0001000200030004000500FF> Tj ET")},
+				testPDFObject{
+					dict: "<<>>",
+					stream: []byte(strings.Join([]string{
+						"/CIDInit /ProcSet findresource begin 12 dict begin begincmap",
+						"1 begincodespacerange <0000> <FFFF> endcodespacerange",
+						"2 beginbfchar <0001> <0048> <0002> <0069> endbfchar",
+						"2 beginbfrange <0003> <0004> <0020> <0005> <0005> [<D83DDE00>] endbfrange",
+						"endcmap CMapName currentdict /CMap defineresource pop end end",
+					}, "\n")),
+				},
+			),
+			// <0004> is the next character after the space of the range and
+			// <00FF> has no mapping.
+			want: "Hi !😀",
+		},
+		{
+			name: "differences encoding",
+			data: singlePagePDF(
+				"<</Type/Font/Subtype/Type1/BaseFont/Embedded/Encoding<</Differences[1/quoteright/uni00E9 65/bullet]>>>>",
+				testPDFObject{dict: "<<>>", stream: []byte(`BT /F1 12 Tf (it\001s caf\002 A) Tj ET`)},
+			),
+			want: "it’s café •",
+		},
+		{
+			name: "form xobject",
+			data: buildPDF(
+				testPDFObject{dict: "<</Type/Catalog/Pages 2 0 R>>"},
+				testPDFObject{dict: "<</Type/Pages/Kids[3 0 R]/Count 1>>"},
+				testPDFObject{dict: "<</Type/Page/Parent 2 0 R/Resources<</XObject<</X1 5 0 R>>>>/Contents 4 0 R>>"},
+				testPDFObject{dict: "<<>>", stream: []byte("BT (Before) Tj ET /X1 Do")},
+				testPDFObject{dict: "<</Type/XObject/Subtype/Form>>", stream: []byte("BT 0 -14 Td (Inside the form) Tj ET")},
+			),
+			want: "Before\nInside the form",
+		},
+		{
+			name: "form drawing itself",
+			data: buildPDF(
+				testPDFObject{dict: "<</Type/Catalog/Pages 2 0 R>>"},
+				testPDFObject{dict: "<</Type/Pages/Kids[3 0 R]/Count 1>>"},
+				testPDFObject{dict: "<</Type/Page/Parent 2 0 R/Resources 5 0 R/Contents 4 0 R>>"},
+				testPDFObject{dict: "<<>>", stream: []byte("/X1 Do")},
+				testPDFObject{dict: "<</XObject<</X1 6 0 R>>>>"},
+				testPDFObject{dict: "<</Type/XObject/Subtype/Form/Resources 5 0 R>>", stream: []byte("BT (Loop) Tj ET /X1 Do")},
+			),
+			want: "LoopLoopLoopLoopLoop",
+		},
+		{
+			name: "page tree cycle",
+			data: buildPDF(
+				testPDFObject{dict: "<</Type/Catalog/Pages 2 0 R>>"},
+				testPDFObject{dict: "<</Type/Pages/Kids[2 0 R 3 0 R]/Count 1>>"},
+				testPDFObject{dict: "<</Type/Page/Parent 2 0 R/Contents 4 0 R>>"},
+				testPDFObject{dict: "<<>>", stream: []byte("BT (Once) Tj ET")},
+			),
+			want: "Once",
+		},
+		{
+			name: "missing catalog",
+			data: buildPDF(
+				testPDFObject{dict: "<</Type/Page/Contents 2 0 R>>"},
+				testPDFObject{dict: "<<>>", stream: []byte("BT (Orphan page) Tj ET")},
+			),
+			want: "Orphan page",
+		},
+		{
+			name: "wrong stream length",
+			data: bytes.Replace(
+				singlePagePDF(helveticaFont, testPDFObject{dict: "<<>>", stream: []byte(testContent)}),
+				[]byte(fmt.Sprintf("/Length %d", len(testContent))),
+				[]byte("/Length 9999"),
+				1,
+			),
+			want: "Hello\nWorld again\nBye",
+		},
+		{
+			name: "truncated flate stream",
+			data: func() []byte {
+				compressed := flate([]byte(testContent + strings.Repeat(" ", 4096)))
+				return singlePagePDF(helveticaFont, testPDFObject{dict: "<</Filter/FlateDecode>>", stream: compressed[:len(compressed)-8]})
+			}(),
+			want: "Hello\nWorld again\nBye",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract("application/pdf", "", tt.data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractPDFErrors(t *testing.T) {
+	zeros := flate(make([]byte, MaxDecodedSize+1))
+	half := flate(make([]byte, MaxDecodedSize/2+1))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{
+			name: "not a pdf",
+			data: []byte("PK\x03\x04 a zip file"),
+		},
+		{
+			name:    "encrypted",
+			data:    []byte("%PDF-1.7\n1 0 obj\n<</Filter/Standard>>\nendobj\ntrailer\n<</Encrypt 1 0 R>>\n"),
+			wantErr: errPDFEncrypted,
+		},
+		{
+			name:    "decompression bomb",
+			data:    singlePagePDF(helveticaFont, testPDFObject{dict: "<</Filter/FlateDecode>>", stream: zeros}),
+			wantErr: ErrTooLarge,
+		},
+		{
+			name: "streams over the budget together",
+			data: buildPDF(
+				testPDFObject{dict: "<</Type/Catalog/Pages 2 0 R>>"},
+				testPDFObject{dict: "<</Type/Pages/Kids[3 0 R]/Count 1>>"},
+				testPDFObject{dict: "<</Type/Page/Parent 2 0 R/Contents[4 0 R 5 0 R]>>"},
+				testPDFObject{dict: "<</Filter/FlateDecode>>", stream: half},
+				testPDFObject{dict: "<</Filter/FlateDecode>>", stream: half},
+			),
+			wantErr: ErrTooLarge,
+		},
+		{
+			name: "bomb in an object stream",
+			data: buildPDF(
+				testPDFObject{dict: "<</Type/Catalog/Pages 2 0 R>>"},
+				testPDFObject{dict: "<</Type/ObjStm/N 1/First 4/Filter/FlateDecode>>", stream: zeros},
+			),
+			wantErr: ErrTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Extract("application/pdf", "", tt.data)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestExtractPDFDamaged checks that truncated and corrupted files do not
+// crash the extractor, whatever text it manages to read.
+func TestExtractPDFDamaged(t *testing.T) {
+	fixtures := [][]byte{
+		readFixture(t, "simple.pdf"),
+		readFixture(t, "objstm.pdf"),
+		readFixture(t, "unicode.pdf"),
+		singlePagePDF(helveticaFont, testPDFObject{
+			dict:   "<</Filter/FlateDecode/DecodeParms<</Predictor 12/Columns 8>>>>",
+			stream: flate(pngUp([]byte(testContent), 8)),
+		}),
+	}
+
+	random := rand.New(rand.NewSource(1))
+	for _, data := range fixtures {
+		for cut := 0; cut < len(data); cut += 1 + len(data)/200 {
+			Extract("application/pdf", "", data[:cut])
+		}
+		for i := 0; i < 200; i++ {
+			damaged := append([]byte(nil), data...)
+			for j := 0; j < 1+random.Intn(8); j++ {
+				damaged[random.Intn(len(damaged))] = byte(random.Intn(256))
+			}
+			Extract("application/pdf", "", damaged)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package wikilink
+
+import (
+	"regexp"
+	"strings"
+)
+
+// linkPattern matches [[Title]], [[Title#Heading]] and [[Title|Alias]].
+var linkPattern = regexp.MustCompile(`\[\[([^\[\]|#\n]+)(#[^\[\]|\n]*)?(\|[^\[\]\n]*)?\]\]`)
+
+type Link struct {
+	Title string
+	// Start and End are the byte offsets of the whole [[...]] in the content.
+	Start int
+	End   int
+}
+
+// Parse returns the links of content in order of appearance. Embeds written
+// as ![[...]] and anything inside a code fence are not links.
+func Parse(content string) []Link {
+	links := make([]Link, 0)
+	for _, match := range findLinks(content) {
+		title := strings.TrimSpace(content[match[2]:match[3]])
+		if title == "" {
+			continue
+		}
+
+		links = append(links, Link{
+			Title: title,
+			Start: match[0],
+			End:   match[1],
+		})
+	}
+
+	return links
+}
+
+// Titles returns the distinct link titles of content, compared
+// case-insensitively, keeping the first spelling.
+func Titles(content string) []string {
+	titles := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, link := range Parse(content) {
+		key := strings.ToLower(link.Title)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		titles = append(titles, link.Title)
+	}
+
+	return titles
+}
+
+// CanLink reports whether title can be written inside [[...]] and parsed
+// back as the same title.
+func CanLink(title string) bool {
+	return strings.TrimSpace(title) != "" && !strings.ContainsAny(title, "[]|#\r\n")
+}
+
+// Rename points every link to oldTitle at newTitle, keeping headings and
+// aliases. The content is returned unchanged when newTitle cannot be linked.
+func Rename(content string, oldTitle string, newTitle string) string {
+	if !CanLink(newTitle) {
+		return content
+	}
+	oldTitle = strings.TrimSpace(oldTitle)
+
+	var b strings.Builder
+	last := 0
+	for _, match := range findLinks(content) {
+		if !strings.EqualFold(strings.TrimSpace(content[match[2]:match[3]]), oldTitle) {
+			continue
+		}
+
+		b.WriteString(content[last:match[2]])
+		b.WriteString(newTitle)
+		last = match[3]
+	}
+	b.WriteString(content[last:])
+
+	return b.String()
+}
+
+// findLinks returns the submatch indexes of the links of content, leaving
+// out embeds and matches inside code fences.
+func findLinks(content string) [][]int {
+	fences := codeFences(content)
+	matches := make([][]int, 0)
+	for _, match := range linkPattern.FindAllStringSubmatchIndex(content, -1) {
+		if match[0] > 0 && content[match[0]-1] == '!' {
+			continue
+		}
+		if inRanges(fences, match[0]) {
+			continue
+		}
+
+		matches = append(matches, match)
+	}
+
+	return matches
+}
+
+// codeFences returns the byte ranges of the fenced code blocks of content,
+// from the opening ``` or ~~~ line to the end of the closing one. An
+// unclosed fence runs to the end of the content.
+func codeFences(content string) [][2]int {
+	fences := make([][2]int, 0)
+	fenceStart := -1
+
+	lineStart := 0
+	for lineStart < len(content) {
+		lineEnd := strings.IndexByte(content[lineStart:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(content)
+		} else {
+			lineEnd += lineStart
+		}
+		line := strings.TrimSpace(content[lineStart:lineEnd])
+
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			if fenceStart < 0 {
+				fenceStart = lineStart
+			} else {
+				fences = append(fences, [2]int{fenceStart, lineEnd})
+				fenceStart = -1
+			}
+		}
+
+		lineStart = lineEnd + 1
+	}
+	if fenceStart >= 0 {
+		fences = append(fences, [2]int{fenceStart, len(content)})
+	}
+
+	return fences
+}
+
+func inRanges(ranges [][2]int, offset int) bool {
+	for _, r := range ranges {
+		if offset >= r[0] && offset < r[1] {
+			return true
+		}
+	}
+
+	return false
+}
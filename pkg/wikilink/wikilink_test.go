@@ -0,0 +1,235 @@
+package wikilink
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Link
+	}{
+		{
+			name:    "plain link",
+			content: "see [[Go]] here",
+			want:    []Link{{Title: "Go", Start: 4, End: 10}},
+		},
+		{
+			name:    "heading and alias",
+			content: "[[Go#Modules|mods]] and [[Rust|  the crab ]]",
+			want:    []Link{{Title: "Go", Start: 0, End: 19}, {Title: "Rust", Start: 24, End: 44}},
+		},
+		{
+			name:    "title is trimmed",
+			content: "[[  Go  #Intro]]",
+			want:    []Link{{Title: "Go", Start: 0, End: 16}},
+		},
+		{
+			name:    "embeds are not links",
+			content: "![[diagram.png]] [[Go]]",
+			want:    []Link{{Title: "Go", Start: 17, End: 23}},
+		},
+		{
+			name:    "empty titles are not links",
+			content: "[[ ]] [[#Heading]] [[|alias]]",
+			want:    []Link{},
+		},
+		{
+			name:    "links do not span lines",
+			content: "[[Go\nlang]]",
+			want:    []Link{},
+		},
+		{
+			name:    "nested brackets",
+			content: "[[[Go]]]",
+			want:    []Link{{Title: "Go", Start: 1, End: 7}},
+		},
+		{
+			name:    "special characters",
+			content: "[[C++ & Go (2024): notes?]] [[Ünïcode 日本]]",
+			want:    []Link{{Title: "C++ & Go (2024): notes?", Start: 0, End: 27}, {Title: "Ünïcode 日本", Start: 28, End: 48}},
+		},
+		{
+			name:    "code fences",
+			content: "[[A]]\n```go\n[[B]]\n```\n~~~\n[[C]]\n~~~\n[[D]]",
+			want:    []Link{{Title: "A", Start: 0, End: 5}, {Title: "D", Start: 36, End: 41}},
+		},
+		{
+			name:    "unclosed code fence",
+			content: "[[A]]\n```\n[[B]]",
+			want:    []Link{{Title: "A", Start: 0, End: 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.content)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTitles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "no links",
+			content: "plain text",
+			want:    []string{},
+		},
+		{
+			name:    "case folding keeps the first spelling",
+			content: "[[Go]] [[go#Intro]] [[GO|golang]] [[Rust]]",
+			want:    []string{"Go", "Rust"},
+		},
+		{
+			name:    "non-ascii case folding",
+			content: "[[Ärger]] [[ärger]]",
+			want:    []string{"Ärger"},
+		},
+		{
+			name:    "embeds and fenced links are left out",
+			content: "![[Go]]\n```\n[[Rust]]\n```\n[[Zig]]",
+			want:    []string{"Zig"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Titles(tt.content)
+			if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRename(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		oldTitle string
+		newTitle string
+		want     string
+	}{
+		{
+			name:     "plain link",
+			content:  "see [[Go]]",
+			oldTitle: "Go",
+			newTitle: "Golang",
+			want:     "see [[Golang]]",
+		},
+		{
+			name:     "headings and aliases are kept",
+			content:  "[[Go#Modules]] [[Go|the language]] [[Go#Intro|intro]]",
+			oldTitle: "Go",
+			newTitle: "Golang",
+			want:     "[[Golang#Modules]] [[Golang|the language]] [[Golang#Intro|intro]]",
+		},
+		{
+			name:     "case folding",
+			content:  "[[go]] [[GO]] [[ Go ]]",
+			oldTitle: " Go",
+			newTitle: "Golang",
+			want:     "[[Golang]] [[Golang]] [[Golang]]",
+		},
+		{
+			name:     "other links are untouched",
+			content:  "[[Go]] [[Gopher]] [[Rust|Go]]",
+			oldTitle: "Go",
+			newTitle: "Golang",
+			want:     "[[Golang]] [[Gopher]] [[Rust|Go]]",
+		},
+		{
+			name:     "embeds are untouched",
+			content:  "![[Go]] [[Go]]",
+			oldTitle: "Go",
+			newTitle: "Golang",
+			want:     "![[Go]] [[Golang]]",
+		},
+		{
+			name:     "code fences are untouched",
+			content:  "[[Go]]\n```\n[[Go]]\n```\n[[Go]]",
+			oldTitle: "Go",
+			newTitle: "Golang",
+			want:     "[[Golang]]\n```\n[[Go]]\n```\n[[Golang]]",
+		},
+		{
+			name:     "special characters",
+			content:  "[[C++ (draft)]] and [[Q&A?]]",
+			oldTitle: "C++ (draft)",
+			newTitle: "C++ & Go: $1 \\n",
+			want:     "[[C++ & Go: $1 \\n]] and [[Q&A?]]",
+		},
+		{
+			name:     "unlinkable title with a bracket",
+			content:  "[[Go]]",
+			oldTitle: "Go",
+			newTitle: "Go]]",
+			want:     "[[Go]]",
+		},
+		{
+			name:     "unlinkable title with a pipe",
+			content:  "[[Go]]",
+			oldTitle: "Go",
+			newTitle: "Go|Rust",
+			want:     "[[Go]]",
+		},
+		{
+			name:     "unlinkable title with a hash",
+			content:  "[[Go]]",
+			oldTitle: "Go",
+			newTitle: "Go #1",
+			want:     "[[Go]]",
+		},
+		{
+			name:     "unlinkable title with a line break",
+			content:  "[[Go]]",
+			oldTitle: "Go",
+			newTitle: "Go\nRust",
+			want:     "[[Go]]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Rename(tt.content, tt.oldTitle, tt.newTitle)
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanLink(t *testing.T) {
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		{title: "Go", want: true},
+		{title: "C++ & Go (2024): notes?", want: true},
+		{title: "日本語", want: true},
+		{title: "", want: false},
+		{title: "  ", want: false},
+		{title: "[draft]", want: false},
+		{title: "a|b", want: false},
+		{title: "Issue #1", want: false},
+		{title: "two\nlines", want: false},
+		{title: "two\r\nlines", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := CanLink(tt.title); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}